@@ -0,0 +1,209 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type AdminHandler struct {
+	userStore    store.UserStore
+	workoutStore store.WorkoutStore
+	logger       *slog.Logger
+}
+
+func NewAdminHandler(userStore store.UserStore, workoutStore store.WorkoutStore, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		userStore:    userStore,
+		workoutStore: workoutStore,
+		logger:       logger,
+	}
+}
+
+func (ah *AdminHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	if !policy.Can(middleware.GetUser(r), policy.ActionListUsers, policy.Resource{}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return
+	}
+
+	limit, err := utils.ReadIntQuery(r, "limit", 50)
+	if err != nil || limit < 1 || limit > 500 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 500"})
+		return
+	}
+	offset, err := utils.ReadIntQuery(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "offset must be a positive number"})
+		return
+	}
+
+	users, err := ah.userStore.ListUsers(limit, offset)
+	if err != nil {
+		ah.logger.Error("ListUsers", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"users": users})
+}
+
+func (ah *AdminHandler) HandleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	ah.setSuspended(w, r, true)
+}
+
+func (ah *AdminHandler) HandleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	ah.setSuspended(w, r, false)
+}
+
+func (ah *AdminHandler) setSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	userID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ah.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid user id"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	if !policy.Can(currentUser, policy.ActionManageUser, policy.Resource{OwnerID: int(userID)}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return
+	}
+
+	if int64(currentUser.ID) == userID {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "cannot change the suspension of your own account"})
+		return
+	}
+
+	err = ah.userStore.SetUserSuspended(userID, suspended)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "user not found"})
+		return
+	} else if err != nil {
+		ah.logger.Error("SetUserSuspended", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	user, err := ah.userStore.GetUserByID(userID)
+	if err != nil {
+		ah.logger.Error("GetUserByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
+
+func (ah *AdminHandler) HandleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ah.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid user id"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	if !policy.Can(currentUser, policy.ActionManageUser, policy.Resource{OwnerID: int(userID)}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ah.logger.Error("DecodingSetUserRole", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if !store.IsValidRole(req.Role) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "role must be user or admin"})
+		return
+	}
+
+	if int64(currentUser.ID) == userID && req.Role != store.RoleAdmin {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "cannot remove your own admin role"})
+		return
+	}
+
+	err = ah.userStore.SetUserRole(userID, req.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "user not found"})
+		return
+	} else if err != nil {
+		ah.logger.Error("SetUserRole", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	user, err := ah.userStore.GetUserByID(userID)
+	if err != nil {
+		ah.logger.Error("GetUserByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
+
+func (ah *AdminHandler) HandleModerateWorkout(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ah.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	workoutOwner, err := ah.workoutStore.GetWorkoutOwner(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+		return
+	} else if err != nil {
+		ah.logger.Error("GetWorkoutOwner", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !policy.Can(middleware.GetUser(r), policy.ActionModerateWorkout, policy.Resource{OwnerID: workoutOwner}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return
+	}
+
+	// moderation doesn't take part in optimistic concurrency, it removes
+	// whatever version is current, and it skips the trash so the owner can't
+	// restore the workout
+	workout, err := ah.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	} else if err != nil {
+		ah.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	err = ah.workoutStore.PurgeWorkoutByID(workoutID, workout.Version)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "workout is being modified, try again"})
+		return
+	} else if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	} else if err != nil {
+		ah.logger.Error("PurgeWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	ah.logger.Info("workout removed by moderator", "workout_id", workoutID, "owner_id", workoutOwner, "moderator_id", middleware.GetUser(r).ID)
+	utils.WriteJSON(w, http.StatusNoContent, utils.Envelope{"message": "workout deleted"})
+}
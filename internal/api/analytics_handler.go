@@ -0,0 +1,216 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type AnalyticsHandler struct {
+	analyticsStore store.AnalyticsStore
+	logger         *slog.Logger
+}
+
+func NewAnalyticsHandler(analyticsStore store.AnalyticsStore, logger *slog.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsStore: analyticsStore,
+		logger:         logger,
+	}
+}
+
+// HandleGetProgression returns the estimated 1RM, top set and volume of one
+// exercise over time, bucketed by day, week or month. Weights are in the
+// unit query parameter, the user's preferred unit by default.
+func (ah *AnalyticsHandler) HandleGetProgression(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	query := r.URL.Query()
+
+	progression := store.ProgressionQuery{
+		UserID:       currentUser.ID,
+		ExerciseName: query.Get("exercise"),
+		Bucket:       query.Get("bucket"),
+	}
+
+	if value := query.Get("exercise_id"); value != "" {
+		exerciseID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid exercise_id query parameter"})
+			return
+		}
+		progression.ExerciseID = &exerciseID
+	} else if progression.ExerciseName == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "exercise_id or exercise is required"})
+		return
+	}
+
+	if progression.Bucket == "" {
+		progression.Bucket = "week"
+	}
+	if !store.IsValidBucket(progression.Bucket) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "bucket must be day, week or month"})
+		return
+	}
+
+	formula, err := strength.ParseFormula(query.Get("formula"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "formula must be epley or brzycki"})
+		return
+	}
+	progression.Formula = formula
+
+	unit, err := readUnit(r, currentUser.PreferredUnit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "unit must be kg or lb"})
+		return
+	}
+
+	location, err := loadTimezone(query.Get("tz"), currentUser.Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	progression.Timezone = location.String()
+
+	progression.From, progression.To, err = utils.ReadDateRange(r, location, 90)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	points, err := ah.analyticsStore.Progression(progression)
+	if err != nil {
+		ah.logger.Error("Progression", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for i := range points {
+		points[i].EstimatedOneRepMax = weightFromKilograms(points[i].EstimatedOneRepMax, unit)
+		points[i].TopSetWeight = units.FromKilograms(points[i].TopSetWeight, unit)
+		points[i].Volume = units.FromKilograms(points[i].Volume, unit)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"bucket":      progression.Bucket,
+		"formula":     progression.Formula,
+		"unit":        unit,
+		"progression": points,
+	})
+}
+
+// statsChange is how a period compares to the previous one, as the
+// difference of each total.
+type statsChange struct {
+	Workouts        int                `json:"workouts"`
+	TrainingDays    int                `json:"training_days"`
+	DurationMinutes int                `json:"duration_minutes"`
+	CaloriesBurned  int                `json:"calories_burned"`
+	Sets            int                `json:"sets"`
+	Tonnage         float64            `json:"tonnage"`
+	MuscleGroupSets map[string]float64 `json:"muscle_group_sets"`
+}
+
+func compareStats(current, previous *store.TrainingStats) statsChange {
+	change := statsChange{
+		Workouts:        current.Workouts - previous.Workouts,
+		TrainingDays:    current.TrainingDays - previous.TrainingDays,
+		DurationMinutes: current.DurationMinutes - previous.DurationMinutes,
+		CaloriesBurned:  current.CaloriesBurned - previous.CaloriesBurned,
+		Sets:            current.Sets - previous.Sets,
+		Tonnage:         current.Tonnage - previous.Tonnage,
+		MuscleGroupSets: map[string]float64{},
+	}
+	for muscle, sets := range current.MuscleGroupSets {
+		change.MuscleGroupSets[muscle] = sets
+	}
+	for muscle, sets := range previous.MuscleGroupSets {
+		change.MuscleGroupSets[muscle] -= sets
+	}
+
+	return change
+}
+
+// statsPeriod returns the bounds of the week, starting on Monday, or month
+// day falls in.
+func statsPeriod(period string, day time.Time) (time.Time, time.Time) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	if period == "month" {
+		from := day.AddDate(0, 0, 1-day.Day())
+		return from, from.AddDate(0, 1, 0)
+	}
+
+	from := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	return from, from.AddDate(0, 0, 7)
+}
+
+// HandleGetStats returns the training totals of the week or month a date
+// falls in, today by default, next to those of the period before it.
+// Tonnage is in the unit query parameter, the user's preferred unit by
+// default.
+func (ah *AnalyticsHandler) HandleGetStats(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	query := r.URL.Query()
+
+	period := query.Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if period != "week" && period != "month" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "period must be week or month"})
+		return
+	}
+
+	location, err := loadTimezone(query.Get("tz"), currentUser.Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	day := time.Now().In(location)
+	if value := query.Get("date"); value != "" {
+		day, err = time.ParseInLocation(time.DateOnly, value, location)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "date must be a date like 2006-01-02"})
+			return
+		}
+	}
+
+	unit, err := readUnit(r, currentUser.PreferredUnit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "unit must be kg or lb"})
+		return
+	}
+
+	from, to := statsPeriod(period, day)
+	previousFrom, previousTo := statsPeriod(period, from.AddDate(0, 0, -1))
+
+	current, err := ah.analyticsStore.TrainingStats(currentUser.ID, from, to, location.String())
+	if err != nil {
+		ah.logger.Error("TrainingStats", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	previous, err := ah.analyticsStore.TrainingStats(currentUser.ID, previousFrom, previousTo, location.String())
+	if err != nil {
+		ah.logger.Error("TrainingStats", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	current.Tonnage = units.FromKilograms(current.Tonnage, unit)
+	previous.Tonnage = units.FromKilograms(previous.Tonnage, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"period":   period,
+		"unit":     unit,
+		"current":  current,
+		"previous": previous,
+		"change":   compareStats(current, previous),
+	})
+}
@@ -0,0 +1,559 @@
+package api
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/calendar"
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/tokens"
+	"github.com/gbuenodev/goProject/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// calendarFeedTokenTTL keeps subscriptions working until the user
+	// rotates or revokes the token.
+	calendarFeedTokenTTL = 5 * 365 * 24 * time.Hour
+	// the feed covers recent history and the plans ahead
+	calendarFeedPast   = 90 * 24 * time.Hour
+	calendarFeedFuture = 365 * 24 * time.Hour
+	maxCalendarDays    = 366
+)
+
+type plannedWorkoutRequest struct {
+	Title           string    `json:"title"`
+	Notes           string    `json:"notes"`
+	TemplateID      *int64    `json:"template_id"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	Timezone        string    `json:"timezone"`
+	DurationMinutes int       `json:"duration_minutes"`
+	RRule           string    `json:"rrule"`
+}
+
+// calendarEvent is an occurrence of a planned workout, completed when a
+// workout is linked to it, or a logged workout that wasn't planned.
+type calendarEvent struct {
+	Type             string    `json:"type"`
+	PlannedWorkoutID *int64    `json:"planned_workout_id,omitempty"`
+	WorkoutID        *int64    `json:"workout_id,omitempty"`
+	Title            string    `json:"title"`
+	Notes            string    `json:"notes,omitempty"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Completed        bool      `json:"completed"`
+}
+
+type CalendarHandler struct {
+	plannedWorkoutStore store.PlannedWorkoutStore
+	workoutStore        store.WorkoutStore
+	templateStore       store.TemplateStore
+	tokenStore          store.TokenStore
+	userStore           store.UserStore
+	logger              *slog.Logger
+}
+
+func NewCalendarHandler(plannedWorkoutStore store.PlannedWorkoutStore, workoutStore store.WorkoutStore, templateStore store.TemplateStore, tokenStore store.TokenStore, userStore store.UserStore, logger *slog.Logger) *CalendarHandler {
+	return &CalendarHandler{
+		plannedWorkoutStore: plannedWorkoutStore,
+		workoutStore:        workoutStore,
+		templateStore:       templateStore,
+		tokenStore:          tokenStore,
+		userStore:           userStore,
+		logger:              logger,
+	}
+}
+
+func (ch *CalendarHandler) HandleListPlannedWorkouts(w http.ResponseWriter, r *http.Request) {
+	plans, err := ch.plannedWorkoutStore.ListPlannedWorkouts(middleware.GetUser(r).ID)
+	if err != nil {
+		ch.logger.Error("ListPlannedWorkouts", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"planned_workouts": plans})
+}
+
+func (ch *CalendarHandler) HandleGetPlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	plan, ok := ch.readPlannedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"planned_workout": plan})
+}
+
+func (ch *CalendarHandler) HandleCreatePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	var req plannedWorkoutRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ch.logger.Error("DecodingCreatePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	plan := &store.PlannedWorkout{UserID: currentUser.ID}
+	if !ch.applyPlannedWorkoutRequest(w, plan, &req, currentUser.Timezone) {
+		return
+	}
+
+	err = ch.plannedWorkoutStore.CreatePlannedWorkout(plan)
+	if err != nil {
+		ch.logger.Error("CreatePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"planned_workout": plan})
+}
+
+func (ch *CalendarHandler) HandleUpdatePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	plan, ok := ch.readPlannedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req plannedWorkoutRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ch.logger.Error("DecodingUpdatePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	if !ch.applyPlannedWorkoutRequest(w, plan, &req, middleware.GetUser(r).Timezone) {
+		return
+	}
+
+	err = ch.plannedWorkoutStore.UpdatePlannedWorkout(plan)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "planned workout not found"})
+		return
+	} else if err != nil {
+		ch.logger.Error("UpdatePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"planned_workout": plan})
+}
+
+func (ch *CalendarHandler) HandleDeletePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	plan, ok := ch.readPlannedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.plannedWorkoutStore.DeletePlannedWorkout(plan.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "planned workout not found"})
+		return
+	} else if err != nil {
+		ch.logger.Error("DeletePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleCompletePlannedWorkout links one of the user's workouts to an
+// occurrence of a plan. The occurrence defaults to the day the workout was
+// performed on, in the timezone of the plan.
+func (ch *CalendarHandler) HandleCompletePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkoutID int64  `json:"workout_id"`
+		Date      string `json:"date"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ch.logger.Error("DecodingCompletePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	plan, ok := ch.readPlannedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	workout, err := ch.workoutStore.GetWorkoutByID(req.WorkoutID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && workout.UserID != plan.UserID) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "workout_id must be one of your workouts"})
+		return
+	} else if err != nil {
+		ch.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	location, rule := planSchedule(plan)
+	date := workout.PerformedAt.In(location)
+	if req.Date != "" {
+		date, err = time.ParseInLocation(time.DateOnly, req.Date, location)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "date must be a date like 2006-01-02"})
+			return
+		}
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+
+	if len(calendar.Expand(rule, plan.ScheduledAt.In(location), date, date.AddDate(0, 0, 1))) == 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": fmt.Sprintf("the plan has no occurrence on %s", date.Format(time.DateOnly))})
+		return
+	}
+
+	err = ch.plannedWorkoutStore.CompletePlannedWorkout(plan.ID, date, int64(workout.ID))
+	if err != nil {
+		ch.logger.Error("CompletePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"completion": store.Completion{Date: date.Format(time.DateOnly), WorkoutID: int64(workout.ID)}})
+}
+
+func (ch *CalendarHandler) HandleUncompletePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	plan, ok := ch.readPlannedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "date must be a date like 2006-01-02"})
+		return
+	}
+
+	err = ch.plannedWorkoutStore.UncompletePlannedWorkout(plan.ID, date)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "occurrence is not completed"})
+		return
+	} else if err != nil {
+		ch.logger.Error("UncompletePlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetCalendar returns the planned and logged workouts between the from
+// and to dates, both inclusive, read in the user's timezone unless tz names
+// another one.
+func (ch *CalendarHandler) HandleGetCalendar(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	location, err := loadTimezone(r.URL.Query().Get("tz"), currentUser.Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	from, to, err := utils.ReadDateRange(r, location, 30)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if to.Sub(from) > maxCalendarDays*24*time.Hour {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": fmt.Sprintf("the range cannot span more than %d days", maxCalendarDays)})
+		return
+	}
+
+	events, err := ch.calendarEvents(currentUser.ID, from, to)
+	if err != nil {
+		ch.logger.Error("calendarEvents", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": events})
+}
+
+// HandleCreateFeedToken issues the secret token of the user's iCalendar
+// feed, revoking the previous one.
+func (ch *CalendarHandler) HandleCreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	err := ch.tokenStore.DeleteAllTokensForUser(currentUser.ID, tokens.ScopeCalendarFeed)
+	if err != nil {
+		ch.logger.Error("DeleteAllTokensForUser", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	token, err := ch.tokenStore.CreateNewToken(currentUser.ID, calendarFeedTokenTTL, tokens.ScopeCalendarFeed)
+	if err != nil {
+		ch.logger.Error("CreateNewToken", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"feed_token": token,
+		"path":       "/calendar/feed/" + token.Plaintext + ".ics",
+	})
+}
+
+func (ch *CalendarHandler) HandleRevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	err := ch.tokenStore.DeleteAllTokensForUser(middleware.GetUser(r).ID, tokens.ScopeCalendarFeed)
+	if err != nil {
+		ch.logger.Error("DeleteAllTokensForUser", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetFeed serves the iCalendar feed of the user the token in the URL
+// belongs to. Calendar apps can't send headers, the token is the only
+// credential.
+func (ch *CalendarHandler) HandleGetFeed(w http.ResponseWriter, r *http.Request) {
+	user, err := ch.userStore.GetUserToken(tokens.ScopeCalendarFeed, chi.URLParam(r, "token"))
+	if err != nil {
+		ch.logger.Error("GetUserToken", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if user == nil || user.IsSuspended() {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "feed not found"})
+		return
+	}
+
+	now := time.Now()
+	events, err := ch.calendarEvents(user.ID, now.Add(-calendarFeedPast), now.Add(calendarFeedFuture))
+	if err != nil {
+		ch.logger.Error("calendarEvents", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	feedEvents := make([]calendar.Event, len(events))
+	for i, event := range events {
+		feedEvents[i] = calendar.Event{
+			Start:       event.Start,
+			End:         event.End,
+			Summary:     event.Title,
+			Description: event.Notes,
+			Status:      "CONFIRMED",
+		}
+		if event.PlannedWorkoutID != nil {
+			feedEvents[i].UID = fmt.Sprintf("planned-%d-%s@goproject", *event.PlannedWorkoutID, event.Start.UTC().Format("20060102"))
+			if !event.Completed {
+				feedEvents[i].Status = "TENTATIVE"
+			}
+		} else {
+			feedEvents[i].UID = fmt.Sprintf("workout-%d@goproject", *event.WorkoutID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	err = calendar.WriteFeed(w, user.Username+"'s workouts", feedEvents, now)
+	if err != nil {
+		ch.logger.Error("WriteFeed", "err", err)
+	}
+}
+
+// calendarWorkoutsPage is how many workouts calendarEvents reads at a time.
+const calendarWorkoutsPage = 500
+
+// listAllWorkouts pages through the workouts of a user performed in
+// [from, to), however many there are.
+func (ch *CalendarHandler) listAllWorkouts(userID int, from, to time.Time) ([]store.Workout, error) {
+	var workouts []store.Workout
+	for offset := 0; ; offset += calendarWorkoutsPage {
+		page, err := ch.workoutStore.ListWorkouts(userID, from, to, calendarWorkoutsPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, page...)
+
+		if len(page) < calendarWorkoutsPage {
+			return workouts, nil
+		}
+	}
+}
+
+// calendarEvents expands the plans of a user over [from, to) and adds the
+// workouts performed then that no occurrence is linked to.
+func (ch *CalendarHandler) calendarEvents(userID int, from, to time.Time) ([]calendarEvent, error) {
+	plans, err := ch.plannedWorkoutStore.ListPlannedWorkouts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []calendarEvent{}
+	linked := map[int64]bool{}
+	for _, plan := range plans {
+		location, rule := planSchedule(&plan)
+
+		completions := map[string]int64{}
+		for _, completion := range plan.Completions {
+			completions[completion.Date] = completion.WorkoutID
+		}
+
+		for _, start := range calendar.Expand(rule, plan.ScheduledAt.In(location), from, to) {
+			event := calendarEvent{
+				Type:             "planned",
+				PlannedWorkoutID: &plan.ID,
+				Title:            plan.Title,
+				Notes:            plan.Notes,
+				Start:            start,
+				End:              start.Add(time.Duration(plan.DurationMinutes) * time.Minute),
+			}
+			if workoutID, ok := completions[start.Format(time.DateOnly)]; ok {
+				event.WorkoutID = &workoutID
+				event.Completed = true
+				linked[workoutID] = true
+			}
+			events = append(events, event)
+		}
+	}
+
+	workouts, err := ch.listAllWorkouts(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, workout := range workouts {
+		workoutID := int64(workout.ID)
+		if linked[workoutID] {
+			continue
+		}
+
+		start := workout.PerformedAt
+		if workout.StartedAt != nil {
+			start = *workout.StartedAt
+		}
+		end := start.Add(time.Duration(max(workout.DurationMinutes, 1)) * time.Minute)
+		if workout.EndedAt != nil {
+			end = *workout.EndedAt
+		}
+
+		events = append(events, calendarEvent{
+			Type:      "workout",
+			WorkoutID: &workoutID,
+			Title:     workout.Title,
+			Notes:     workout.Description,
+			Start:     start,
+			End:       end,
+			Completed: true,
+		})
+	}
+
+	slices.SortStableFunc(events, func(a, b calendarEvent) int { return a.Start.Compare(b.Start) })
+	return events, nil
+}
+
+// planSchedule returns the timezone and recurrence rule of a plan. Both were
+// validated when the plan was saved.
+func planSchedule(plan *store.PlannedWorkout) (*time.Location, *calendar.Rule) {
+	location, err := time.LoadLocation(plan.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	var rule *calendar.Rule
+	if plan.RRule != "" {
+		rule, _ = calendar.ParseRule(plan.RRule, location)
+	}
+	return location, rule
+}
+
+func (ch *CalendarHandler) readPlannedWorkout(w http.ResponseWriter, r *http.Request) (*store.PlannedWorkout, bool) {
+	planID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid planned workout id"})
+		return nil, false
+	}
+
+	plan, err := ch.plannedWorkoutStore.GetPlannedWorkout(planID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "planned workout not found"})
+		return nil, false
+	} else if err != nil {
+		ch.logger.Error("GetPlannedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if !policy.Can(middleware.GetUser(r), policy.ActionManagePlan, policy.Resource{OwnerID: plan.UserID}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return nil, false
+	}
+
+	return plan, true
+}
+
+// applyPlannedWorkoutRequest validates req into plan, writing the error
+// response itself when it is invalid. The title defaults to the template's.
+func (ch *CalendarHandler) applyPlannedWorkoutRequest(w http.ResponseWriter, plan *store.PlannedWorkout, req *plannedWorkoutRequest, defaultTimezone string) bool {
+	title := strings.TrimSpace(req.Title)
+
+	if req.TemplateID != nil {
+		template, err := ch.templateStore.GetTemplateByID(*req.TemplateID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && template.UserID != plan.UserID) {
+			utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "template_id must be one of your templates"})
+			return false
+		} else if err != nil {
+			ch.logger.Error("GetTemplateByID", "err", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return false
+		}
+		title = cmp.Or(title, template.Title)
+	}
+
+	if title == "" || len(title) > 255 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "title must be between 1 and 255 characters"})
+		return false
+	}
+	if req.ScheduledAt.IsZero() {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "scheduled_at is required"})
+		return false
+	}
+
+	timezone := cmp.Or(req.Timezone, defaultTimezone, "UTC")
+	location, err := loadTimezone(timezone, "")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return false
+	}
+
+	durationMinutes := cmp.Or(req.DurationMinutes, 60)
+	if durationMinutes < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "duration_minutes must be positive"})
+		return false
+	}
+
+	rrule := ""
+	if req.RRule != "" {
+		rule, err := calendar.ParseRule(req.RRule, location)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "rrule: " + err.Error()})
+			return false
+		}
+		rrule = rule.String()
+	}
+
+	plan.Title = title
+	plan.Notes = req.Notes
+	plan.TemplateID = req.TemplateID
+	plan.ScheduledAt = req.ScheduledAt
+	plan.Timezone = timezone
+	plan.DurationMinutes = durationMinutes
+	plan.RRule = rrule
+	return true
+}
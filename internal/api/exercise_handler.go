@@ -0,0 +1,256 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type exerciseRequest struct {
+	Name             string   `json:"name"`
+	Aliases          []string `json:"aliases"`
+	PrimaryMuscles   []string `json:"primary_muscles"`
+	SecondaryMuscles []string `json:"secondary_muscles"`
+	Equipment        string   `json:"equipment"`
+	MovementPattern  string   `json:"movement_pattern"`
+	Unilateral       bool     `json:"unilateral"`
+	// BuiltIn adds the exercise to the shared library, admins only.
+	BuiltIn bool `json:"built_in"`
+}
+
+type ExerciseHandler struct {
+	exerciseStore store.ExerciseStore
+	logger        *slog.Logger
+}
+
+func NewExerciseHandler(exerciseStore store.ExerciseStore, logger *slog.Logger) *ExerciseHandler {
+	return &ExerciseHandler{
+		exerciseStore: exerciseStore,
+		logger:        logger,
+	}
+}
+
+func (eh *ExerciseHandler) HandleListExercises(w http.ResponseWriter, r *http.Request) {
+	limit, err := utils.ReadIntQuery(r, "limit", 100)
+	if err != nil || limit < 1 || limit > 500 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 500"})
+		return
+	}
+	offset, err := utils.ReadIntQuery(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "offset must be a positive number"})
+		return
+	}
+
+	filter := store.ExerciseFilter{
+		Search: r.URL.Query().Get("q"),
+		Muscle: r.URL.Query().Get("muscle"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if filter.Muscle != "" && !store.IsValidMuscleGroup(filter.Muscle) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "unknown muscle group"})
+		return
+	}
+
+	exercises, err := eh.exerciseStore.ListExercises(middleware.GetUser(r).ID, filter)
+	if err != nil {
+		eh.logger.Error("ListExercises", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercises": exercises})
+}
+
+func (eh *ExerciseHandler) HandleGetExercise(w http.ResponseWriter, r *http.Request) {
+	exercise, ok := eh.readExercise(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleCreateExercise(w http.ResponseWriter, r *http.Request) {
+	var req exerciseRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		eh.logger.Error("DecodingCreateExercise", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	exercise := &store.Exercise{}
+	if !req.BuiltIn {
+		exercise.OwnerID = &currentUser.ID
+	} else if !policy.Can(currentUser, policy.ActionManageExercise, policy.Resource{}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "only admins can add built-in exercises"})
+		return
+	}
+
+	err = applyExerciseRequest(exercise, &req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = eh.exerciseStore.CreateExercise(exercise)
+	if errors.Is(err, store.ErrDuplicateExercise) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "an exercise with this name already exists"})
+		return
+	} else if err != nil {
+		eh.logger.Error("CreateExercise", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleUpdateExercise(w http.ResponseWriter, r *http.Request) {
+	exercise, ok := eh.readExercise(w, r)
+	if !ok || !eh.authorizeExercise(w, r, exercise) {
+		return
+	}
+
+	var req exerciseRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		eh.logger.Error("DecodingUpdateExercise", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	err = applyExerciseRequest(exercise, &req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = eh.exerciseStore.UpdateExercise(exercise)
+	if errors.Is(err, store.ErrDuplicateExercise) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "an exercise with this name already exists"})
+		return
+	} else if err != nil {
+		eh.logger.Error("UpdateExercise", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleDeleteExercise(w http.ResponseWriter, r *http.Request) {
+	exercise, ok := eh.readExercise(w, r)
+	if !ok || !eh.authorizeExercise(w, r, exercise) {
+		return
+	}
+
+	err := eh.exerciseStore.DeleteExercise(exercise.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "exercise not found"})
+		return
+	} else if err != nil {
+		eh.logger.Error("DeleteExercise", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readExercise loads the exercise named in the URL if the current user can
+// see it. Other users' custom exercises are reported as missing.
+func (eh *ExerciseHandler) readExercise(w http.ResponseWriter, r *http.Request) (*store.Exercise, bool) {
+	exerciseID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid exercise id"})
+		return nil, false
+	}
+
+	exercise, err := eh.exerciseStore.GetExercise(exerciseID, middleware.GetUser(r).ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "exercise not found"})
+		return nil, false
+	} else if err != nil {
+		eh.logger.Error("GetExercise", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	return exercise, true
+}
+
+func (eh *ExerciseHandler) authorizeExercise(w http.ResponseWriter, r *http.Request, exercise *store.Exercise) bool {
+	resource := policy.Resource{}
+	if exercise.OwnerID != nil {
+		resource.OwnerID = *exercise.OwnerID
+	}
+
+	if !policy.Can(middleware.GetUser(r), policy.ActionManageExercise, resource) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return false
+	}
+	return true
+}
+
+func applyExerciseRequest(exercise *store.Exercise, req *exerciseRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 255 {
+		return errors.New("name must be between 1 and 255 characters")
+	}
+
+	if len(req.PrimaryMuscles) == 0 {
+		return errors.New("at least one primary muscle group is required")
+	}
+	for _, muscle := range slices.Concat(req.PrimaryMuscles, req.SecondaryMuscles) {
+		if !store.IsValidMuscleGroup(muscle) {
+			return fmt.Errorf("unknown muscle group %q", muscle)
+		}
+	}
+
+	if req.Equipment == "" {
+		req.Equipment = "other"
+	}
+	if !slices.Contains(store.Equipment, req.Equipment) {
+		return fmt.Errorf("equipment must be one of %s", strings.Join(store.Equipment, ", "))
+	}
+
+	if req.MovementPattern == "" {
+		req.MovementPattern = "other"
+	}
+	if !slices.Contains(store.MovementPatterns, req.MovementPattern) {
+		return fmt.Errorf("movement_pattern must be one of %s", strings.Join(store.MovementPatterns, ", "))
+	}
+
+	aliases := []string{}
+	for _, alias := range req.Aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	exercise.Name = name
+	exercise.Aliases = aliases
+	exercise.PrimaryMuscles = req.PrimaryMuscles
+	exercise.SecondaryMuscles = req.SecondaryMuscles
+	if exercise.SecondaryMuscles == nil {
+		exercise.SecondaryMuscles = []string{}
+	}
+	exercise.Equipment = req.Equipment
+	exercise.MovementPattern = req.MovementPattern
+	exercise.Unilateral = req.Unilateral
+	return nil
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type LoginThrottleConfig struct {
+	// MaxUsernameFailures and MaxIPFailures are the number of consecutive
+	// failures after which the username or the client IP gets locked.
+	MaxUsernameFailures int
+	MaxIPFailures       int
+	// BaseDelay doubles on every consecutive failure, up to MaxDelay.
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	// FailureWindow is how long a failure counts towards the limits.
+	FailureWindow time.Duration
+}
+
+var DefaultLoginThrottleConfig = LoginThrottleConfig{
+	MaxUsernameFailures: 5,
+	MaxIPFailures:       20,
+	BaseDelay:           time.Second,
+	MaxDelay:            30 * time.Second,
+	LockoutDuration:     15 * time.Minute,
+	FailureWindow:       15 * time.Minute,
+}
+
+const (
+	usernameKeyPrefix = "username:"
+	ipKeyPrefix       = "ip:"
+)
+
+func usernameKey(username string) string {
+	return usernameKeyPrefix + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return ipKeyPrefix + ip
+}
+
+func (c LoginThrottleConfig) backoff() store.LoginBackoff {
+	return store.LoginBackoff{
+		BaseDelay: c.BaseDelay,
+		MaxDelay:  c.MaxDelay,
+		Window:    c.FailureWindow,
+	}
+}
+
+func (c LoginThrottleConfig) maxFailures(key string) int {
+	if strings.HasPrefix(key, ipKeyPrefix) {
+		return c.MaxIPFailures
+	}
+	return c.MaxUsernameFailures
+}
+
+// retryAfter returns how long the client has to wait before attempt allows
+// another login, or zero if it may try right away.
+func (c LoginThrottleConfig) retryAfter(attempt store.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+
+	if attempt.Failures == 0 || now.Sub(attempt.LastFailureAt) > c.FailureWindow {
+		return 0
+	}
+
+	delay := c.BaseDelay
+	for i := 1; i < attempt.Failures && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+
+	next := attempt.LastFailureAt.Add(delay)
+	if next.After(now) {
+		return next.Sub(now)
+	}
+
+	return 0
+}
+
+// loginThrottle guards every endpoint that checks a password or a second
+// factor. Each attempt is counted as a failure up front by take, and only
+// turns back into a non-failure once it's known to have succeeded.
+type loginThrottle struct {
+	store  store.LoginAttemptStore
+	config LoginThrottleConfig
+	logger *slog.Logger
+}
+
+func newLoginThrottle(loginAttemptStore store.LoginAttemptStore, config LoginThrottleConfig, logger *slog.Logger) *loginThrottle {
+	return &loginThrottle{
+		store:  loginAttemptStore,
+		config: config,
+		logger: logger,
+	}
+}
+
+// take counts an attempt against keys, or rejects the request with a 429
+// while any of them is locked or backing off. It returns false when the
+// request must stop.
+func (t *loginThrottle) take(w http.ResponseWriter, keys []string) bool {
+	taken, err := t.store.TakeLoginAttempts(keys, t.config.backoff())
+	if err != nil {
+		t.logger.Error("TakeLoginAttempts", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if len(taken) == len(keys) {
+		return true
+	}
+
+	// a throttled key rejects the whole attempt, so the others must not
+	// count it either
+	if len(taken) > 0 {
+		takenKeys := make([]string, 0, len(taken))
+		for _, attempt := range taken {
+			takenKeys = append(takenKeys, attempt.Key)
+		}
+		t.release(takenKeys)
+	}
+
+	attempts, err := t.store.GetLoginAttempts(keys...)
+	if err != nil {
+		t.logger.Error("GetLoginAttempts", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	now := time.Now()
+	wait := time.Second
+	for _, attempt := range attempts {
+		wait = max(wait, t.config.retryAfter(attempt, now))
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.WriteJSON(w, http.StatusTooManyRequests, utils.Envelope{"error": "too many failed login attempts, try again later"})
+	return false
+}
+
+// release takes back an attempt that never got to check the credentials,
+// because the server failed first.
+func (t *loginThrottle) release(keys []string) {
+	err := t.store.ReleaseLoginAttempts(keys...)
+	if err != nil {
+		t.logger.Error("ReleaseLoginAttempts", "err", err)
+	}
+}
+
+// succeed clears the failures of username and takes back the attempt
+// counted against the other keys, which may be shared by other users.
+func (t *loginThrottle) succeed(username string, keys []string) {
+	err := t.store.ResetLoginAttempts(usernameKey(username))
+	if err != nil {
+		t.logger.Error("ResetLoginAttempts", "err", err)
+	}
+
+	others := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != usernameKey(username) {
+			others = append(others, key)
+		}
+	}
+	if len(others) > 0 {
+		t.release(others)
+	}
+}
+
+// fail locks the keys whose counted failures reached their limit. Errors are
+// only logged: the client gets the same 401 either way.
+func (t *loginThrottle) fail(username, clientIP string, keys []string) {
+	attempts, err := t.store.GetLoginAttempts(keys...)
+	if err != nil {
+		t.logger.Error("GetLoginAttempts", "err", err)
+		return
+	}
+
+	for _, attempt := range attempts {
+		if attempt.Failures < t.config.maxFailures(attempt.Key) {
+			continue
+		}
+
+		lockedUntil := time.Now().Add(t.config.LockoutDuration)
+		err = t.store.LockLogin(attempt.Key, lockedUntil)
+		if err != nil {
+			t.logger.Error("LockLogin", "err", err)
+			continue
+		}
+
+		event := &store.LockoutEvent{
+			Key:         attempt.Key,
+			Username:    username,
+			IPAddress:   clientIP,
+			Failures:    attempt.Failures,
+			LockedUntil: lockedUntil,
+		}
+		err = t.store.CreateLockoutEvent(event)
+		if err != nil {
+			t.logger.Error("CreateLockoutEvent", "err", err)
+		}
+
+		t.logger.Warn("login locked", "key", attempt.Key, "ip", clientIP, "failures", attempt.Failures, "locked_until", lockedUntil)
+	}
+}
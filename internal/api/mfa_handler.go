@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/totp"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type MFAHandler struct {
+	mfaStore store.MFAStore
+	throttle *loginThrottle
+	issuer   string
+	logger   *slog.Logger
+}
+
+type secondFactorRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func NewMFAHandler(mfaStore store.MFAStore, loginAttemptStore store.LoginAttemptStore, throttle LoginThrottleConfig, issuer string, logger *slog.Logger) *MFAHandler {
+	return &MFAHandler{
+		mfaStore: mfaStore,
+		throttle: newLoginThrottle(loginAttemptStore, throttle, logger),
+		issuer:   issuer,
+		logger:   logger,
+	}
+}
+
+// verifySecondFactor checks either a TOTP code or a recovery code. Both can
+// only be used once.
+func verifySecondFactor(mfaStore store.MFAStore, secret *store.TOTPSecret, req secondFactorRequest) (bool, error) {
+	switch {
+	case req.Code != "":
+		step, ok, err := totp.Validate(secret.Secret, req.Code, time.Now())
+		if err != nil || !ok {
+			return false, err
+		}
+		return mfaStore.UseTOTPStep(secret.UserID, step)
+	case req.RecoveryCode != "":
+		return mfaStore.UseRecoveryCode(secret.UserID, totp.HashRecoveryCode(req.RecoveryCode))
+	}
+
+	return false, nil
+}
+
+func (mh *MFAHandler) HandleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	existing, err := mh.mfaStore.GetTOTPSecret(currentUser.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		mh.logger.Error("GetTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if existing != nil && existing.IsConfirmed() {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		mh.logger.Error("GenerateSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	err = mh.mfaStore.SaveTOTPSecret(currentUser.ID, secret)
+	if err != nil {
+		mh.logger.Error("SaveTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(mh.issuer, currentUser.Username, secret),
+	})
+}
+
+func (mh *MFAHandler) HandleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mh.logger.Error("DecodingConfirmTOTP", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	secret, err := mh.mfaStore.GetTOTPSecret(currentUser.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "no two-factor enrollment in progress"})
+		return
+	} else if err != nil {
+		mh.logger.Error("GetTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if secret.IsConfirmed() {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "two-factor authentication is already enabled"})
+		return
+	}
+
+	step, ok, err := totp.Validate(secret.Secret, req.Code, time.Now())
+	if err != nil {
+		mh.logger.Error("totp.Validate", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid code"})
+		return
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes(totp.RecoveryCodeCount)
+	if err != nil {
+		mh.logger.Error("GenerateRecoveryCodes", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	hashes := make([][]byte, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+
+	err = mh.mfaStore.ConfirmTOTPSecret(currentUser.ID, step, hashes)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "two-factor authentication is already enabled"})
+		return
+	} else if err != nil {
+		mh.logger.Error("ConfirmTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"recovery_codes": recoveryCodes})
+}
+
+func (mh *MFAHandler) HandleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	var req secondFactorRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mh.logger.Error("DecodingDisableTOTP", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	secret, err := mh.mfaStore.GetTOTPSecret(currentUser.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "two-factor authentication is not enabled"})
+		return
+	} else if err != nil {
+		mh.logger.Error("GetTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	// an unfinished enrollment can be dropped without proving anything.
+	// Guesses share the login throttle, a stolen session must not be a way
+	// around it.
+	if secret.IsConfirmed() {
+		clientIP := utils.ClientIP(r)
+		keys := []string{usernameKey(currentUser.Username), ipKey(clientIP)}
+		if !mh.throttle.take(w, keys) {
+			return
+		}
+
+		ok, err := verifySecondFactor(mh.mfaStore, secret, req)
+		if err != nil {
+			mh.throttle.release(keys)
+			mh.logger.Error("verifySecondFactor", "err", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+		if !ok {
+			mh.throttle.fail(currentUser.Username, clientIP, keys)
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "invalid code"})
+			return
+		}
+
+		mh.throttle.succeed(currentUser.Username, keys)
+	}
+
+	err = mh.mfaStore.DeleteTOTPSecret(currentUser.ID)
+	if err != nil {
+		mh.logger.Error("DeleteTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "two-factor authentication disabled"})
+}
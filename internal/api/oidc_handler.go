@@ -0,0 +1,170 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/oidc"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+const oidcAuthRequestTTL = 10 * time.Minute
+
+type OIDCHandler struct {
+	providers     map[string]*oidc.Provider
+	identityStore store.IdentityStore
+	userStore     store.UserStore
+	tokenHandler  *TokenHandler
+	logger        *slog.Logger
+}
+
+func NewOIDCHandler(providers []*oidc.Provider, identityStore store.IdentityStore, userStore store.UserStore, tokenHandler *TokenHandler, logger *slog.Logger) *OIDCHandler {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OIDCHandler{
+		providers:     byName,
+		identityStore: identityStore,
+		userStore:     userStore,
+		tokenHandler:  tokenHandler,
+		logger:        logger,
+	}
+}
+
+func (oh *OIDCHandler) HandleListProviders(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(oh.providers))
+	for name := range oh.providers {
+		names = append(names, name)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"providers": names})
+}
+
+// HandleLogin redirects the user to the identity provider's login page.
+func (oh *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := oh.providers[chi.URLParam(r, "provider")]
+	if !ok {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "unknown identity provider"})
+		return
+	}
+
+	authRequest := &store.OIDCAuthRequest{
+		Provider:  provider.Name(),
+		ExpiresAt: time.Now().Add(oidcAuthRequestTTL),
+	}
+
+	var err error
+	for _, value := range []*string{&authRequest.State, &authRequest.Nonce, &authRequest.CodeVerifier} {
+		*value, err = oidc.RandomString()
+		if err != nil {
+			oh.logger.Error("oidc.RandomString", "err", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+	}
+
+	authURL, err := provider.AuthCodeURL(authRequest.State, authRequest.Nonce, authRequest.CodeVerifier)
+	if err != nil {
+		oh.logger.Error("AuthCodeURL", "err", err)
+		utils.WriteJSON(w, http.StatusBadGateway, utils.Envelope{"error": "identity provider unavailable"})
+		return
+	}
+
+	err = oh.identityStore.CreateAuthRequest(authRequest)
+	if err != nil {
+		oh.logger.Error("CreateAuthRequest", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleCallback completes the login when the identity provider redirects
+// back. The external identity is linked to the user with the same verified
+// email on first login.
+func (oh *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := oh.providers[chi.URLParam(r, "provider")]
+	if !ok {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "unknown identity provider"})
+		return
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		oh.logger.Debug("oidc callback error", "provider", provider.Name(), "error", providerErr)
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "login was not completed at the identity provider"})
+		return
+	}
+
+	authRequest, err := oh.identityStore.ConsumeAuthRequest(query.Get("state"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && authRequest.Provider != provider.Name()) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "login request expired or invalid"})
+		return
+	} else if err != nil {
+		oh.logger.Error("ConsumeAuthRequest", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	claims, err := provider.Exchange(query.Get("code"), authRequest.CodeVerifier, authRequest.Nonce)
+	if err != nil {
+		oh.logger.Error("Exchange", "provider", provider.Name(), "err", err)
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "could not verify the identity provider response"})
+		return
+	}
+
+	user, err := oh.identityStore.GetUserByIdentity(provider.Name(), claims.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = oh.linkByEmail(provider.Name(), claims)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "no account matches this identity"})
+		return
+	} else if err != nil {
+		oh.logger.Error("resolving oidc identity", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if user.IsSuspended() {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "account suspended"})
+		return
+	}
+
+	oh.tokenHandler.completeLogin(w, user)
+}
+
+// linkByEmail links the identity to the user with the same email. It only
+// trusts emails the provider verified, and returns sql.ErrNoRows when there
+// is nothing to link to.
+func (oh *OIDCHandler) linkByEmail(provider string, claims *oidc.Claims) (*store.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, sql.ErrNoRows
+	}
+
+	user, err := oh.userStore.GetUserByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = oh.identityStore.LinkIdentity(&store.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oh.logger.Info("linked external identity", "user_id", user.ID, "provider", provider)
+	return user, nil
+}
@@ -0,0 +1,542 @@
+package api
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/programs"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type programRequest struct {
+	Title        string                  `json:"title"`
+	Description  string                  `json:"description"`
+	Weeks        int                     `json:"weeks"`
+	Public       bool                    `json:"public"`
+	Rounding     float64                 `json:"rounding"`
+	Unit         string                  `json:"unit"`
+	Days         []store.ProgramDay      `json:"days"`
+	Progressions []store.ProgressionRule `json:"progressions"`
+}
+
+type ProgramHandler struct {
+	programStore  store.ProgramStore
+	templateStore store.TemplateStore
+	workoutStore  store.WorkoutStore
+	logger        *slog.Logger
+}
+
+func NewProgramHandler(programStore store.ProgramStore, templateStore store.TemplateStore, workoutStore store.WorkoutStore, logger *slog.Logger) *ProgramHandler {
+	return &ProgramHandler{
+		programStore:  programStore,
+		templateStore: templateStore,
+		workoutStore:  workoutStore,
+		logger:        logger,
+	}
+}
+
+func (ph *ProgramHandler) HandleListPrograms(w http.ResponseWriter, r *http.Request) {
+	limit, err := utils.ReadIntQuery(r, "limit", 50)
+	if err != nil || limit < 1 || limit > 500 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 500"})
+		return
+	}
+	offset, err := utils.ReadIntQuery(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "offset must be a positive number"})
+		return
+	}
+
+	programList, err := ph.programStore.ListPrograms(middleware.GetUser(r).ID, limit, offset)
+	if err != nil {
+		ph.logger.Error("ListPrograms", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"programs": programList})
+}
+
+func (ph *ProgramHandler) HandleGetProgram(w http.ResponseWriter, r *http.Request) {
+	program, ok := ph.readProgram(w, r, false)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"program": program})
+}
+
+func (ph *ProgramHandler) HandleCreateProgram(w http.ResponseWriter, r *http.Request) {
+	var req programRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ph.logger.Error("DecodingCreateProgram", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	program := &store.Program{OwnerID: middleware.GetUser(r).ID}
+	err = applyProgramRequest(program, &req, middleware.GetUser(r).PreferredUnit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = ph.programStore.CreateProgram(program)
+	if err != nil {
+		ph.writeProgramError(w, "CreateProgram", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"program": program})
+}
+
+func (ph *ProgramHandler) HandleUpdateProgram(w http.ResponseWriter, r *http.Request) {
+	program, ok := ph.readProgram(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req programRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ph.logger.Error("DecodingUpdateProgram", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	err = applyProgramRequest(program, &req, middleware.GetUser(r).PreferredUnit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = ph.programStore.UpdateProgram(program)
+	if err != nil {
+		ph.writeProgramError(w, "UpdateProgram", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"program": program})
+}
+
+func (ph *ProgramHandler) HandleDeleteProgram(w http.ResponseWriter, r *http.Request) {
+	program, ok := ph.readProgram(w, r, true)
+	if !ok {
+		return
+	}
+
+	err := ph.programStore.DeleteProgram(program.ID)
+	if errors.Is(err, store.ErrInUse) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "users are enrolled in this program"})
+		return
+	} else if err != nil {
+		ph.writeProgramError(w, "DeleteProgram", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleEnroll enrolls the current user in a program, starting at its first
+// day. Training maxes missing from the request default to 90% of the user's
+// estimated 1RM record of the exercise. Training maxes are read and shown in
+// the user's preferred unit.
+func (ph *ProgramHandler) HandleEnroll(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TrainingMaxes []store.TrainingMax `json:"training_maxes"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		ph.logger.Error("DecodingEnroll", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	program, ok := ph.readProgram(w, r, false)
+	if !ok {
+		return
+	}
+	if len(program.Days) == 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "program has no days"})
+		return
+	}
+
+	var exerciseIDs []int64
+	for _, day := range program.Days {
+		for _, p := range day.Prescriptions {
+			exerciseIDs = append(exerciseIDs, p.ExerciseID)
+		}
+	}
+	for _, rule := range program.Progressions {
+		exerciseIDs = append(exerciseIDs, rule.ExerciseID)
+	}
+	exerciseIDs = slices.Compact(slices.Sorted(slices.Values(exerciseIDs)))
+
+	for _, tm := range req.TrainingMaxes {
+		if !slices.Contains(exerciseIDs, tm.ExerciseID) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": fmt.Sprintf("exercise %d is not part of the program", tm.ExerciseID)})
+			return
+		}
+		if tm.Weight <= 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "training max weights must be positive"})
+			return
+		}
+	}
+
+	currentUser := middleware.GetUser(r)
+	for i := range req.TrainingMaxes {
+		req.TrainingMaxes[i].Weight = units.ToKilograms(req.TrainingMaxes[i].Weight, currentUser.PreferredUnit)
+	}
+
+	enrollment := &store.Enrollment{
+		UserID:        currentUser.ID,
+		ProgramID:     program.ID,
+		Week:          program.Days[0].Week,
+		Day:           program.Days[0].Day,
+		TrainingMaxes: req.TrainingMaxes,
+	}
+
+	err = ph.programStore.CreateEnrollment(enrollment, exerciseIDs)
+	if err != nil {
+		ph.logger.Error("CreateEnrollment", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	enrollment.TrainingMaxes = trainingMaxesFromKilograms(enrollment.TrainingMaxes, currentUser.PreferredUnit)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"enrollment": enrollment})
+}
+
+func (ph *ProgramHandler) HandleListEnrollments(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	enrollments, err := ph.programStore.ListEnrollments(currentUser.ID)
+	if err != nil {
+		ph.logger.Error("ListEnrollments", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for i := range enrollments {
+		enrollments[i].TrainingMaxes = trainingMaxesFromKilograms(enrollments[i].TrainingMaxes, currentUser.PreferredUnit)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"enrollments": enrollments})
+}
+
+func (ph *ProgramHandler) HandleDeleteEnrollment(w http.ResponseWriter, r *http.Request) {
+	enrollment, ok := ph.readEnrollment(w, r)
+	if !ok {
+		return
+	}
+
+	err := ph.programStore.DeleteEnrollment(enrollment.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "enrollment not found"})
+		return
+	} else if err != nil {
+		ph.logger.Error("DeleteEnrollment", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetNextSession returns the workout prescribed for the next session
+// of an enrollment in the unit of its program, ready to be logged with POST
+// /workouts in that unit.
+func (ph *ProgramHandler) HandleGetNextSession(w http.ResponseWriter, r *http.Request) {
+	enrollment, ok := ph.readEnrollment(w, r)
+	if !ok {
+		return
+	}
+
+	session, program, ok := ph.nextSession(w, enrollment)
+	if !ok {
+		return
+	}
+
+	sessionFromKilograms(session, program.Unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": program.Unit, "session": session})
+}
+
+// HandleCompleteSession marks the next session of an enrollment as performed
+// by one of the user's workouts. Exercises whose targets the workout met
+// progress by the increment of their rule, then the enrollment moves to the
+// following day.
+func (ph *ProgramHandler) HandleCompleteSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkoutID int64 `json:"workout_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ph.logger.Error("DecodingCompleteSession", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	enrollment, ok := ph.readEnrollment(w, r)
+	if !ok {
+		return
+	}
+
+	workout, err := ph.workoutStore.GetWorkoutByID(req.WorkoutID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && workout.UserID != enrollment.UserID) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "workout_id must be one of your workouts"})
+		return
+	} else if err != nil {
+		ph.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	session, program, ok := ph.nextSession(w, enrollment)
+	if !ok {
+		return
+	}
+
+	increments := programs.Progress(session.Targets, program.Progressions, workout)
+	for i := range increments {
+		increments[i].Weight = units.ToKilograms(increments[i].Weight, program.Unit)
+	}
+	next, _ := programs.NextDay(program.Days, session.Week, session.Day)
+
+	err = ph.programStore.CompleteSession(enrollment, int64(workout.ID), increments, next)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "session was already completed"})
+		return
+	} else if err != nil {
+		ph.logger.Error("CompleteSession", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	unit := middleware.GetUser(r).PreferredUnit
+	enrollment.TrainingMaxes = trainingMaxesFromKilograms(enrollment.TrainingMaxes, unit)
+	increments = trainingMaxesFromKilograms(increments, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"enrollment": enrollment, "increments": increments})
+}
+
+// nextSession prescribes the next session of an active enrollment, writing
+// the error response itself when it can't.
+func (ph *ProgramHandler) nextSession(w http.ResponseWriter, enrollment *store.Enrollment) (*programs.Session, *store.Program, bool) {
+	if enrollment.Status != store.EnrollmentActive {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "program is already completed"})
+		return nil, nil, false
+	}
+
+	program, err := ph.programStore.GetProgramByID(enrollment.ProgramID)
+	if err != nil {
+		ph.logger.Error("GetProgramByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, nil, false
+	}
+
+	day, ok := programs.FindDay(program.Days, enrollment.Week, enrollment.Day)
+	if !ok {
+		// the program was edited since, carry on with the day after
+		day, ok = programs.NextDay(program.Days, enrollment.Week, enrollment.Day)
+		if !ok {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "program has no sessions left"})
+			return nil, nil, false
+		}
+	}
+
+	template, ok := loadTemplate(w, ph.templateStore, ph.logger, day.TemplateID)
+	if !ok {
+		return nil, nil, false
+	}
+
+	session := programs.Prescribe(day, template, enrollment.TrainingMaxes, program.Rounding, program.Unit, enrollment.UserID)
+	return session, program, true
+}
+
+// sessionFromKilograms converts the weights of session to unit, the unit of
+// its program, in which they are rounded.
+func sessionFromKilograms(session *programs.Session, unit units.Unit) {
+	for i := range session.Targets {
+		target := &session.Targets[i]
+		target.Weight = weightFromKilograms(target.Weight, unit)
+	}
+	entriesFromKilograms(session.Workout.Entries, unit)
+}
+
+// readProgram loads the program named in the URL. Public programs can be
+// read by everyone, manage requires being allowed to change the program.
+func (ph *ProgramHandler) readProgram(w http.ResponseWriter, r *http.Request, manage bool) (*store.Program, bool) {
+	programID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid program id"})
+		return nil, false
+	}
+
+	program, err := ph.programStore.GetProgramByID(programID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "program not found"})
+		return nil, false
+	} else if err != nil {
+		ph.logger.Error("GetProgramByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	allowed := policy.Can(middleware.GetUser(r), policy.ActionManageProgram, policy.Resource{OwnerID: program.OwnerID})
+	if !allowed && (manage || !program.Public) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return nil, false
+	}
+
+	return program, true
+}
+
+func (ph *ProgramHandler) readEnrollment(w http.ResponseWriter, r *http.Request) (*store.Enrollment, bool) {
+	enrollmentID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid enrollment id"})
+		return nil, false
+	}
+
+	enrollment, err := ph.programStore.GetEnrollment(enrollmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "enrollment not found"})
+		return nil, false
+	} else if err != nil {
+		ph.logger.Error("GetEnrollment", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if !policy.Can(middleware.GetUser(r), policy.ActionManageEnrollment, policy.Resource{OwnerID: enrollment.UserID}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return nil, false
+	}
+
+	return enrollment, true
+}
+
+func (ph *ProgramHandler) writeProgramError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "program not found"})
+	case errors.Is(err, store.ErrUnknownTemplate):
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "days must use templates of the program owner"})
+	case errors.Is(err, store.ErrUnknownExercise):
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "unknown exercise_id in prescriptions or progressions"})
+	default:
+		ph.logger.Error(op, "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+	}
+}
+
+// applyProgramRequest validates req and applies it to program. The unit
+// defaults to defaultUnit, rounding to the smallest common plate pair of the
+// unit.
+func applyProgramRequest(program *store.Program, req *programRequest, defaultUnit units.Unit) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len(title) > 255 {
+		return errors.New("title must be between 1 and 255 characters")
+	}
+	if req.Weeks < 1 || req.Weeks > 52 {
+		return errors.New("weeks must be between 1 and 52")
+	}
+	unit := defaultUnit
+	if req.Unit != "" {
+		var err error
+		unit, err = units.Parse(req.Unit)
+		if err != nil {
+			return errors.New("unit must be kg or lb")
+		}
+	}
+	if req.Rounding == 0 {
+		req.Rounding = 2.5
+		if unit == units.Pounds {
+			req.Rounding = 5
+		}
+	}
+	if req.Rounding < 0 {
+		return errors.New("rounding must be positive")
+	}
+	if len(req.Days) == 0 {
+		return errors.New("at least one day is required")
+	}
+
+	slices.SortFunc(req.Days, func(a, b store.ProgramDay) int {
+		return cmp.Or(cmp.Compare(a.Week, b.Week), cmp.Compare(a.Day, b.Day))
+	})
+	for i, day := range req.Days {
+		if day.Week < 1 || day.Week > req.Weeks || day.Day < 1 || day.Day > 7 {
+			return fmt.Errorf("days[%d]: week must be between 1 and weeks, day between 1 and 7", i)
+		}
+		if i > 0 && day.Week == req.Days[i-1].Week && day.Day == req.Days[i-1].Day {
+			return fmt.Errorf("week %d day %d is listed twice", day.Week, day.Day)
+		}
+		if day.TemplateID < 1 {
+			return fmt.Errorf("week %d day %d: template_id is required", day.Week, day.Day)
+		}
+		if req.Days[i].Prescriptions == nil {
+			req.Days[i].Prescriptions = []store.Prescription{}
+		}
+
+		for _, p := range day.Prescriptions {
+			err := validatePrescription(p)
+			if err != nil {
+				return fmt.Errorf("week %d day %d: %w", day.Week, day.Day, err)
+			}
+		}
+	}
+
+	seen := map[int64]bool{}
+	for _, rule := range req.Progressions {
+		if rule.ExerciseID < 1 || rule.Increment <= 0 {
+			return errors.New("progressions need an exercise_id and a positive increment")
+		}
+		if seen[rule.ExerciseID] {
+			return fmt.Errorf("exercise %d has more than one progression", rule.ExerciseID)
+		}
+		seen[rule.ExerciseID] = true
+	}
+
+	program.Title = title
+	program.Description = req.Description
+	program.Weeks = req.Weeks
+	program.Public = req.Public
+	program.Rounding = req.Rounding
+	program.Unit = unit
+	program.Days = req.Days
+	program.Progressions = req.Progressions
+	if program.Progressions == nil {
+		program.Progressions = []store.ProgressionRule{}
+	}
+	return nil
+}
+
+func validatePrescription(p store.Prescription) error {
+	if p.ExerciseID < 1 {
+		return errors.New("prescriptions need an exercise_id")
+	}
+	if p.Sets < 1 || p.Reps < 1 {
+		return errors.New("prescriptions need at least one set of one rep")
+	}
+	if (p.PercentOneRepMax == nil) == (p.RPE == nil) {
+		return errors.New("prescriptions need exactly one of percent_1rm or rpe")
+	}
+	if p.PercentOneRepMax != nil && (*p.PercentOneRepMax <= 0 || *p.PercentOneRepMax > 150) {
+		return errors.New("percent_1rm must be between 0 and 150")
+	}
+	if p.RPE != nil && (*p.RPE < 1 || *p.RPE > 10) {
+		return errors.New("rpe must be between 1 and 10")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type RecordHandler struct {
+	recordStore store.RecordStore
+	logger      *slog.Logger
+}
+
+func NewRecordHandler(recordStore store.RecordStore, logger *slog.Logger) *RecordHandler {
+	return &RecordHandler{
+		recordStore: recordStore,
+		logger:      logger,
+	}
+}
+
+// HandleListRecords returns the current personal records of the user. With
+// an exercise_id or exercise query parameter, it returns the history of the
+// records set for that exercise instead. Weights are in the unit query
+// parameter, the user's preferred unit by default.
+func (rh *RecordHandler) HandleListRecords(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	query := r.URL.Query()
+
+	unit, err := readUnit(r, currentUser.PreferredUnit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "unit must be kg or lb"})
+		return
+	}
+
+	var exerciseKey string
+	if value := query.Get("exercise_id"); value != "" {
+		exerciseID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid exercise_id query parameter"})
+			return
+		}
+		exerciseKey = strength.ExerciseKey(&exerciseID, "")
+	} else if value := query.Get("exercise"); value != "" {
+		exerciseKey = strength.ExerciseKey(nil, value)
+	}
+
+	var records []store.PersonalRecord
+	if exerciseKey != "" {
+		records, err = rh.recordStore.ListRecordHistory(currentUser.ID, exerciseKey)
+	} else {
+		records, err = rh.recordStore.ListRecords(currentUser.ID)
+	}
+	if err != nil {
+		rh.logger.Error("ListRecords", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	recordsFromKilograms(records, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "records": records})
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+type templateRequest struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Entries     []store.WorkoutEntry `json:"entries"`
+}
+
+type TemplateHandler struct {
+	templateStore store.TemplateStore
+	logger        *slog.Logger
+}
+
+func NewTemplateHandler(templateStore store.TemplateStore, logger *slog.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateStore: templateStore,
+		logger:        logger,
+	}
+}
+
+func (th *TemplateHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	limit, err := utils.ReadIntQuery(r, "limit", 50)
+	if err != nil || limit < 1 || limit > 500 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 500"})
+		return
+	}
+	offset, err := utils.ReadIntQuery(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "offset must be a positive number"})
+		return
+	}
+
+	templates, err := th.templateStore.ListTemplates(middleware.GetUser(r).ID, limit, offset)
+	if err != nil {
+		th.logger.Error("ListTemplates", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"templates": templates})
+}
+
+func (th *TemplateHandler) HandleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	template, ok := th.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	entriesFromKilograms(template.Entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "template": template})
+}
+
+func (th *TemplateHandler) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	var req templateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		th.logger.Error("DecodingCreateTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	template := &store.WorkoutTemplate{UserID: middleware.GetUser(r).ID}
+	err = applyTemplateRequest(template, &req, unit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = th.templateStore.CreateTemplate(template)
+	if err != nil {
+		th.logger.Error("CreateTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	entriesFromKilograms(template.Entries, unit)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"unit": unit, "template": template})
+}
+
+func (th *TemplateHandler) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	template, ok := th.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req templateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		th.logger.Error("DecodingUpdateTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	err = applyTemplateRequest(template, &req, unit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = th.templateStore.UpdateTemplate(template)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return
+	} else if err != nil {
+		th.logger.Error("UpdateTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	entriesFromKilograms(template.Entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "template": template})
+}
+
+func (th *TemplateHandler) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := th.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := th.templateStore.DeleteTemplate(template.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return
+	} else if errors.Is(err, store.ErrInUse) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "template is used by a program"})
+		return
+	} else if err != nil {
+		th.logger.Error("DeleteTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readTemplate loads the template named in the URL if the current user may
+// manage it.
+func (th *TemplateHandler) readTemplate(w http.ResponseWriter, r *http.Request) (*store.WorkoutTemplate, bool) {
+	templateID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return nil, false
+	}
+
+	template, ok := loadTemplate(w, th.templateStore, th.logger, templateID)
+	if !ok {
+		return nil, false
+	}
+
+	if !policy.Can(middleware.GetUser(r), policy.ActionManageTemplate, policy.Resource{OwnerID: template.UserID}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return nil, false
+	}
+
+	return template, true
+}
+
+// loadTemplate loads a template, writing the error response itself when it
+// can't.
+func loadTemplate(w http.ResponseWriter, templateStore store.TemplateStore, logger *slog.Logger, templateID int64) (*store.WorkoutTemplate, bool) {
+	template, err := templateStore.GetTemplateByID(templateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return nil, false
+	} else if err != nil {
+		logger.Error("GetTemplateByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	return template, true
+}
+
+// applyTemplateRequest validates req and applies it to template. Entry
+// weights are in unit.
+func applyTemplateRequest(template *store.WorkoutTemplate, req *templateRequest, unit units.Unit) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len(title) > 255 {
+		return errors.New("title must be between 1 and 255 characters")
+	}
+
+	for i := range req.Entries {
+		err := entryToKilograms(&req.Entries[i], unit)
+		if err == nil {
+			err = validateWorkoutEntry(&req.Entries[i])
+		}
+		if err != nil {
+			return fmt.Errorf("entries[%d]: %w", i, err)
+		}
+	}
+
+	err := store.ValidateEntryGroups(req.Entries)
+	if err != nil {
+		return err
+	}
+
+	template.Title = title
+	template.Description = req.Description
+	template.Entries = req.Entries
+	if template.Entries == nil {
+		template.Entries = []store.WorkoutEntry{}
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
@@ -14,6 +16,8 @@ import (
 type TokenHandler struct {
 	tokenStore store.TokenStore
 	userStore  store.UserStore
+	mfaStore   store.MFAStore
+	throttle   *loginThrottle
 	logger     *slog.Logger
 }
 
@@ -22,10 +26,22 @@ type createTokenRequest struct {
 	Password string `json:"password"`
 }
 
-func NewTokenHandler(tokenStore store.TokenStore, userStore store.UserStore, logger *slog.Logger) *TokenHandler {
+type verifyMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	secondFactorRequest
+}
+
+const (
+	authTokenTTL       = 24 * time.Hour
+	mfaPendingTokenTTL = 5 * time.Minute
+)
+
+func NewTokenHandler(tokenStore store.TokenStore, userStore store.UserStore, loginAttemptStore store.LoginAttemptStore, mfaStore store.MFAStore, throttle LoginThrottleConfig, logger *slog.Logger) *TokenHandler {
 	return &TokenHandler{
 		tokenStore: tokenStore,
 		userStore:  userStore,
+		mfaStore:   mfaStore,
+		throttle:   newLoginThrottle(loginAttemptStore, throttle, logger),
 		logger:     logger,
 	}
 }
@@ -40,27 +56,172 @@ func (h *TokenHandler) HandleCreateToken(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	clientIP := utils.ClientIP(r)
+	keys := []string{usernameKey(req.Username), ipKey(clientIP)}
+
+	if !h.throttle.take(w, keys) {
+		return
+	}
+
 	// get user
 	user, err := h.userStore.GetUserByUsername(req.Username)
-	if err != nil || user == nil {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.throttle.release(keys)
 		h.logger.Error("GetUserByUsername", "err", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	passwordsDoMatch, err := user.PasswordHash.Matches(req.Password)
+	passwordsDoMatch := false
+	if user == nil {
+		store.SimulatePasswordCheck(req.Password)
+	} else {
+		passwordsDoMatch, err = user.PasswordHash.Matches(req.Password)
+		if err != nil {
+			h.throttle.release(keys)
+			h.logger.Error("PasswordHash.Matches", "err", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+	}
+
+	if !passwordsDoMatch {
+		h.throttle.fail(req.Username, clientIP, keys)
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "invalid credentials"})
+		return
+	}
+
+	h.throttle.succeed(req.Username, keys)
+
+	if user.PasswordHash.NeedsRehash() {
+		h.upgradePasswordHash(user, req.Password)
+	}
+
+	if user.IsSuspended() {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "account suspended"})
+		return
+	}
+
+	h.completeLogin(w, user)
+}
+
+func (h *TokenHandler) HandleVerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req verifyMFARequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Error("DecodingVerifyMFA", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	user, err := h.userStore.GetUserToken(tokens.ScopeMFAPending, req.MFAToken)
 	if err != nil {
-		h.logger.Error("PasswordHash.Matches", "err", err)
+		h.logger.Error("GetUserToken", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	} else if user == nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "mfa token expired or invalid"})
+		return
+	}
+
+	clientIP := utils.ClientIP(r)
+	keys := []string{usernameKey(user.Username), ipKey(clientIP)}
+	if !h.throttle.take(w, keys) {
+		return
+	}
+
+	secret, err := h.mfaStore.GetTOTPSecret(user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// two-factor authentication was disabled after the password step
+		h.throttle.release(keys)
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "mfa token expired or invalid"})
+		return
+	} else if err != nil {
+		h.throttle.release(keys)
+		h.logger.Error("GetTOTPSecret", "err", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	if !passwordsDoMatch {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "invalid credentials"})
+	ok, err := verifySecondFactor(h.mfaStore, secret, req.secondFactorRequest)
+	if err != nil {
+		h.throttle.release(keys)
+		h.logger.Error("verifySecondFactor", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !ok {
+		h.throttle.fail(user.Username, clientIP, keys)
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "invalid code"})
+		return
+	}
+
+	h.throttle.succeed(user.Username, keys)
+
+	err = h.tokenStore.DeleteAllTokensForUser(user.ID, tokens.ScopeMFAPending)
+	if err != nil {
+		h.logger.Error("DeleteAllTokensForUser", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	token, err := h.tokenStore.CreateNewToken(user.ID, 24*time.Hour, tokens.ScopeAuth)
+	if user.IsSuspended() {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "account suspended"})
+		return
+	}
+
+	h.issueAuthToken(w, user)
+}
+
+// upgradePasswordHash rehashes a legacy password hash with the current
+// algorithm. Failing to do so is logged but doesn't fail the login, the
+// upgrade is retried on the next one.
+func (h *TokenHandler) upgradePasswordHash(user *store.User, plainText string) {
+	err := user.PasswordHash.Set(plainText)
+	if err != nil {
+		h.logger.Error("PasswordHash.Set", "err", err)
+		return
+	}
+
+	err = h.userStore.UpdatePasswordHash(user)
+	if err != nil {
+		h.logger.Error("UpdatePasswordHash", "err", err)
+		return
+	}
+
+	h.logger.Info("upgraded password hash", "user_id", user.ID)
+}
+
+// completeLogin finishes a login whose credentials were verified. Users with
+// two-factor authentication get a short lived MFA pending token instead of
+// an auth token.
+func (h *TokenHandler) completeLogin(w http.ResponseWriter, user *store.User) {
+	secret, err := h.mfaStore.GetTOTPSecret(user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.logger.Error("GetTOTPSecret", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if secret == nil || !secret.IsConfirmed() {
+		h.issueAuthToken(w, user)
+		return
+	}
+
+	token, err := h.tokenStore.CreateNewToken(user.ID, mfaPendingTokenTTL, tokens.ScopeMFAPending)
+	if err != nil {
+		h.logger.Error("CreateNewToken", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"mfa_required": true, "mfa_token": token})
+}
+
+func (h *TokenHandler) issueAuthToken(w http.ResponseWriter, user *store.User) {
+	token, err := h.tokenStore.CreateNewToken(user.ID, authTokenTTL, tokens.ScopeAuth)
 	if err != nil {
 		h.logger.Error("CreateNewToken", "err", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+// readUnit reads the unit query parameter, fallback when it is missing.
+func readUnit(r *http.Request, fallback units.Unit) (units.Unit, error) {
+	value := r.URL.Query().Get("unit")
+	if value == "" {
+		return fallback, nil
+	}
+	return units.Parse(value)
+}
+
+// requestUnit is readUnit defaulting to the user's preferred unit. It writes
+// a 400 and returns false when the unit is invalid.
+func requestUnit(w http.ResponseWriter, r *http.Request) (units.Unit, bool) {
+	unit, err := readUnit(r, middleware.GetUser(r).PreferredUnit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "unit must be kg or lb"})
+		return "", false
+	}
+	return unit, true
+}
+
+// entriesToKilograms converts the weights of entries sent by a client to
+// the kilograms they are stored in. Each entry's weights are in its
+// weight_unit, unit when it doesn't give one.
+func entriesToKilograms(entries []store.WorkoutEntry, unit units.Unit) error {
+	for i := range entries {
+		err := entryToKilograms(&entries[i], unit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryToKilograms records the unit the entry was logged in as its
+// logged_unit, for entries read and written back, and its weight_unit
+// otherwise.
+func entryToKilograms(entry *store.WorkoutEntry, unit units.Unit) error {
+	weightUnit := unit
+	if entry.WeightUnit != "" {
+		parsed, err := units.Parse(string(entry.WeightUnit))
+		if err != nil {
+			return errors.New("weight_unit must be kg or lb")
+		}
+		weightUnit = parsed
+	}
+
+	loggedUnit := weightUnit
+	if entry.LoggedUnit != "" {
+		parsed, err := units.Parse(string(entry.LoggedUnit))
+		if err != nil {
+			return errors.New("logged_unit must be kg or lb")
+		}
+		loggedUnit = parsed
+	}
+
+	entry.Weight = weightToKilograms(entry.Weight, weightUnit)
+	for i := range entry.SetDetails {
+		set := &entry.SetDetails[i]
+		set.Weight = weightToKilograms(set.Weight, weightUnit)
+	}
+
+	entry.WeightUnit = loggedUnit
+	entry.LoggedUnit = ""
+	return nil
+}
+
+// entriesFromKilograms converts stored weights to unit, which becomes the
+// weight_unit of the entries. Their logged_unit is the unit they were
+// logged in.
+func entriesFromKilograms(entries []store.WorkoutEntry, unit units.Unit) {
+	for i := range entries {
+		entryFromKilograms(&entries[i], unit)
+	}
+}
+
+func entryFromKilograms(entry *store.WorkoutEntry, unit units.Unit) {
+	loggedUnit := entry.WeightUnit
+	if loggedUnit == "" {
+		// logged before entries had a unit
+		loggedUnit = units.Kilograms
+	}
+
+	// weights read in their own unit read as they were logged, the others
+	// finely enough to be written back unchanged
+	convert := units.FromKilograms
+	if loggedUnit != unit {
+		convert = units.FromKilogramsExact
+	}
+
+	entry.Weight = convertWeight(entry.Weight, unit, convert)
+	for i := range entry.SetDetails {
+		set := &entry.SetDetails[i]
+		set.Weight = convertWeight(set.Weight, unit, convert)
+	}
+
+	entry.WeightUnit = unit
+	entry.LoggedUnit = loggedUnit
+}
+
+// recordsFromKilograms converts the weights of records, and the values of
+// the records that are weights, to unit.
+func recordsFromKilograms(records []store.PersonalRecord, unit units.Unit) {
+	for i := range records {
+		record := &records[i]
+		record.Weight = weightFromKilograms(record.Weight, unit)
+		switch record.Type {
+		case strength.RecordHeaviestWeight, strength.RecordEstimated1RM, strength.RecordHighestVolume:
+			record.Value = units.FromKilograms(record.Value, unit)
+		}
+	}
+}
+
+// trainingMaxesFromKilograms returns a copy of trainingMaxes in unit, the
+// stored ones being used by prescriptions.
+func trainingMaxesFromKilograms(trainingMaxes []store.TrainingMax, unit units.Unit) []store.TrainingMax {
+	converted := make([]store.TrainingMax, len(trainingMaxes))
+	for i, tm := range trainingMaxes {
+		converted[i] = store.TrainingMax{ExerciseID: tm.ExerciseID, Weight: units.FromKilograms(tm.Weight, unit)}
+	}
+	return converted
+}
+
+func weightToKilograms(weight *float64, unit units.Unit) *float64 {
+	if weight == nil {
+		return nil
+	}
+	converted := units.ToKilograms(*weight, unit)
+	return &converted
+}
+
+func weightFromKilograms(weight *float64, unit units.Unit) *float64 {
+	return convertWeight(weight, unit, units.FromKilograms)
+}
+
+func convertWeight(weight *float64, unit units.Unit, convert func(float64, units.Unit) float64) *float64 {
+	if weight == nil {
+		return nil
+	}
+	converted := convert(*weight, unit)
+	return &converted
+}
@@ -7,7 +7,10 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/passwords"
 	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
 	"github.com/gbuenodev/goProject/internal/utils"
 )
 
@@ -19,14 +22,16 @@ type registerUserRequest struct {
 }
 
 type UserHandler struct {
-	userStore store.UserStore
-	logger    *slog.Logger
+	userStore      store.UserStore
+	passwordPolicy passwords.Policy
+	logger         *slog.Logger
 }
 
-func NewUserHandler(userStore store.UserStore, logger *slog.Logger) *UserHandler {
+func NewUserHandler(userStore store.UserStore, passwordPolicy passwords.Policy, logger *slog.Logger) *UserHandler {
 	return &UserHandler{
-		userStore: userStore,
-		logger:    logger,
+		userStore:      userStore,
+		passwordPolicy: passwordPolicy,
+		logger:         logger,
 	}
 }
 
@@ -53,11 +58,12 @@ func (uh *UserHandler) validateRegisterUserRequest(r *registerUserRequest) error
 	}
 
 	// Password validation
-	if len(r.Password) < 8 {
-		return errors.New("password must be at least 8 characters long")
-	}
-	if !utils.IsValidPassword(r.Password) {
-		return errors.New("password must contain at least one uppercase letter, one lowercase letter, one number, and one special character")
+	err = uh.passwordPolicy.Validate(r.Password, passwords.UserInputs{Username: r.Username, Email: r.Email})
+	if passwords.IsValidationError(err) {
+		return err
+	} else if err != nil {
+		uh.logger.Error("passwordPolicy.Validate", "err", err)
+		return errors.New("internal server error")
 	}
 
 	// Bio validation
@@ -111,3 +117,62 @@ func (uh *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request
 
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"user": user})
 }
+
+func (uh *UserHandler) HandleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": middleware.GetUser(r)})
+}
+
+// HandleUpdateCurrentUser updates the profile and preferences of the current
+// user. Fields left out of the request keep their value.
+func (uh *UserHandler) HandleUpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Bio           *string `json:"bio"`
+		Timezone      *string `json:"timezone"`
+		PreferredUnit *string `json:"preferred_unit"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		uh.logger.Error("Decoding update user request", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	// work on a copy, the request context keeps the user as authenticated
+	user := *middleware.GetUser(r)
+
+	if req.Bio != nil {
+		if len(*req.Bio) > 160 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "bio must be 160 characters or less"})
+			return
+		}
+		user.Bio = *req.Bio
+	}
+	if req.Timezone != nil {
+		_, err = loadTimezone(*req.Timezone, "")
+		if err != nil || *req.Timezone == "" {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "timezone must be an IANA timezone like Europe/Lisbon"})
+			return
+		}
+		user.Timezone = *req.Timezone
+	}
+	if req.PreferredUnit != nil {
+		user.PreferredUnit, err = units.Parse(*req.PreferredUnit)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "preferred_unit must be kg or lb"})
+			return
+		}
+	}
+
+	err = uh.userStore.UpdateUser(&user)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "user not found"})
+		return
+	} else if err != nil {
+		uh.logger.Error("UpdateUser", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
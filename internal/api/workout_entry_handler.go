@@ -0,0 +1,326 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+func (wh *WorkoutHandler) HandleListWorkoutEntries(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
+	entries, err := wh.workoutStore.ListWorkoutEntries(workoutID)
+	if err != nil {
+		wh.logger.Error("ListWorkoutEntries", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	entriesFromKilograms(entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "entries": entries})
+}
+
+func (wh *WorkoutHandler) HandleGetWorkoutEntry(w http.ResponseWriter, r *http.Request) {
+	workoutID, entryID, ok := wh.readEntryParams(w, r)
+	if !ok {
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
+	entry, err := wh.workoutStore.GetWorkoutEntry(workoutID, entryID)
+	if err != nil {
+		wh.writeEntryError(w, "GetWorkoutEntry", err)
+		return
+	}
+
+	entryFromKilograms(entry, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "entry": entry})
+}
+
+func (wh *WorkoutHandler) HandleCreateWorkoutEntry(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
+	version, ok := wh.checkWorkoutIfMatch(w, r, workoutID)
+	if !ok {
+		return
+	}
+
+	var entry store.WorkoutEntry
+	err = json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		wh.logger.Error("DecodingCreateWorkoutEntry", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	err = entryToKilograms(&entry, unit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	err = validateWorkoutEntry(&entry)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	newVersion, err := wh.workoutStore.CreateWorkoutEntry(workoutID, version, &entry, middleware.GetUser(r).ID)
+	if err != nil {
+		wh.writeEntryError(w, "CreateWorkoutEntry", err)
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(newVersion))
+	newRecords := wh.refreshRecordsByID(workoutID)
+	entryFromKilograms(&entry, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"unit": unit, "entry": entry, "new_records": newRecords})
+}
+
+func (wh *WorkoutHandler) HandleUpdateWorkoutEntry(w http.ResponseWriter, r *http.Request) {
+	workoutID, entryID, ok := wh.readEntryParams(w, r)
+	if !ok {
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
+	version, ok := wh.checkWorkoutIfMatch(w, r, workoutID)
+	if !ok {
+		return
+	}
+
+	var entry store.WorkoutEntry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		wh.logger.Error("DecodingUpdateWorkoutEntry", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	err = entryToKilograms(&entry, unit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	err = validateWorkoutEntry(&entry)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	entry.ID = int(entryID)
+	newVersion, err := wh.workoutStore.UpdateWorkoutEntry(workoutID, version, &entry, middleware.GetUser(r).ID)
+	if err != nil {
+		wh.writeEntryError(w, "UpdateWorkoutEntry", err)
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(newVersion))
+	newRecords := wh.refreshRecordsByID(workoutID)
+	entryFromKilograms(&entry, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "entry": entry, "new_records": newRecords})
+}
+
+func (wh *WorkoutHandler) HandleDeleteWorkoutEntry(w http.ResponseWriter, r *http.Request) {
+	workoutID, entryID, ok := wh.readEntryParams(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
+	version, ok := wh.checkWorkoutIfMatch(w, r, workoutID)
+	if !ok {
+		return
+	}
+
+	newVersion, err := wh.workoutStore.DeleteWorkoutEntry(workoutID, version, entryID, middleware.GetUser(r).ID)
+	if err != nil {
+		wh.writeEntryError(w, "DeleteWorkoutEntry", err)
+		return
+	}
+
+	wh.refreshRecordsByID(workoutID)
+
+	w.Header().Set("ETag", utils.ETag(newVersion))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (wh *WorkoutHandler) HandleReorderWorkoutEntries(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
+	version, ok := wh.checkWorkoutIfMatch(w, r, workoutID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		EntryIDs []int64 `json:"entry_ids"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		wh.logger.Error("DecodingReorderWorkoutEntries", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	newVersion, err := wh.workoutStore.ReorderWorkoutEntries(workoutID, version, req.EntryIDs, middleware.GetUser(r).ID)
+	if err != nil {
+		wh.writeEntryError(w, "ReorderWorkoutEntries", err)
+		return
+	}
+
+	entries, err := wh.workoutStore.ListWorkoutEntries(workoutID)
+	if err != nil {
+		wh.logger.Error("ListWorkoutEntries", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(newVersion))
+	entriesFromKilograms(entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "entries": entries})
+}
+
+func (wh *WorkoutHandler) readEntryParams(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return 0, 0, false
+	}
+
+	entryID, err := utils.ReadInt64Param(r, "entryID")
+	if err != nil {
+		wh.logger.Error("ReadInt64Param", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid entry id"})
+		return 0, 0, false
+	}
+
+	return workoutID, entryID, true
+}
+
+func (wh *WorkoutHandler) writeEntryError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout entry not found"})
+	case errors.Is(err, store.ErrVersionConflict):
+		utils.WriteJSON(w, http.StatusPreconditionFailed, utils.Envelope{"error": "resource was modified, fetch it again before updating"})
+	case errors.Is(err, store.ErrInvalidEntryOrder), errors.Is(err, store.ErrInvalidEntryGroup), errors.Is(err, store.ErrInvalidSet):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+	default:
+		wh.logger.Error(op, "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+	}
+}
+
+// checkWorkoutIfMatch enforces the If-Match precondition of an entry write
+// against the current version of the workout, which it returns so the store
+// can check it again inside the write.
+func (wh *WorkoutHandler) checkWorkoutIfMatch(w http.ResponseWriter, r *http.Request, workoutID int64) (int, bool) {
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return 0, false
+	} else if err != nil {
+		wh.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return 0, false
+	}
+
+	if !utils.CheckIfMatch(w, r, utils.ETag(workout.Version)) {
+		return 0, false
+	}
+
+	return workout.Version, true
+}
+
+// validateWorkoutEntry validates entry once its legacy fields are derived
+// from its set log, if it has one.
+func validateWorkoutEntry(entry *store.WorkoutEntry) error {
+	err := store.SummarizeSets(entry)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(entry.ExerciseName) == "" && entry.ExerciseID == nil {
+		return errors.New("exercise_name or exercise_id is required")
+	}
+	if entry.Sets < 0 || entry.OrderIndex < 0 {
+		return errors.New("sets and order_index cannot be negative")
+	}
+	if (entry.Reps == nil) == (entry.DurationSeconds == nil) {
+		return errors.New("exactly one of reps or duration_seconds is required")
+	}
+	return nil
+}
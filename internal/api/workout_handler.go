@@ -4,26 +4,102 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
 	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
 	"github.com/gbuenodev/goProject/internal/utils"
 )
 
 type WorkoutHandler struct {
-	workoutStore store.WorkoutStore
-	logger       *slog.Logger
+	workoutStore  store.WorkoutStore
+	recordStore   store.RecordStore
+	templateStore store.TemplateStore
+	logger        *slog.Logger
 }
 
-func NewWorkoutHandler(workoutStore store.WorkoutStore, logger *slog.Logger) *WorkoutHandler {
+func NewWorkoutHandler(workoutStore store.WorkoutStore, recordStore store.RecordStore, templateStore store.TemplateStore, logger *slog.Logger) *WorkoutHandler {
 	return &WorkoutHandler{
-		workoutStore: workoutStore,
-		logger:       logger,
+		workoutStore:  workoutStore,
+		recordStore:   recordStore,
+		templateStore: templateStore,
+		logger:        logger,
 	}
 }
 
+// authorizeWorkout checks that the current user may perform action on the
+// workout. It writes the error response itself and returns false when the
+// request must not go any further.
+func (wh *WorkoutHandler) authorizeWorkout(w http.ResponseWriter, r *http.Request, workoutID int64, action policy.Action) bool {
+	currentUser := middleware.GetUser(r)
+
+	workoutOwner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			wh.logger.Debug("GetWorkoutOwner: the workout doesn't exist")
+			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+			return false
+		}
+
+		wh.logger.Error("GetWorkoutOwner", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if !policy.Can(currentUser, action, policy.Resource{OwnerID: workoutOwner}) {
+		wh.logger.Debug("authorizeWorkout: user not allowed to perform action", "action", action, "workout_id", workoutID)
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return false
+	}
+
+	return true
+}
+
+// HandleListWorkouts lists the current user's workouts performed between the
+// from and to dates, both inclusive. Dates are read in the user's timezone
+// unless a tz parameter names another one.
+func (wh *WorkoutHandler) HandleListWorkouts(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	location, err := loadTimezone(r.URL.Query().Get("tz"), currentUser.Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	from, to, err := utils.ReadDateRange(r, location, 30)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	limit, err := utils.ReadIntQuery(r, "limit", 50)
+	if err != nil || limit < 1 || limit > 500 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 500"})
+		return
+	}
+	offset, err := utils.ReadIntQuery(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "offset must be a positive number"})
+		return
+	}
+
+	workouts, err := wh.workoutStore.ListWorkouts(currentUser.ID, from, to, limit, offset)
+	if err != nil {
+		wh.logger.Error("ListWorkouts", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workouts": workouts})
+}
+
 func (wh *WorkoutHandler) HandleGetWorkoutByID(w http.ResponseWriter, r *http.Request) {
 	workoutID, err := utils.ReadIDParam(r)
 	if err != nil {
@@ -32,6 +108,15 @@ func (wh *WorkoutHandler) HandleGetWorkoutByID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
 	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -45,7 +130,16 @@ func (wh *WorkoutHandler) HandleGetWorkoutByID(w http.ResponseWriter, r *http.Re
 		}
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": workout})
+	etag := utils.ETag(workout.Version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Accept-Patch", acceptPatch)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && utils.MatchesETag(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	entriesFromKilograms(workout.Entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "workout": workout})
 }
 
 func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Request) {
@@ -65,14 +159,38 @@ func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Req
 
 	workout.UserID = currentUser.ID
 
-	createdWorkout, err := wh.workoutStore.CreateWorkout(&workout)
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	err = prepareWorkoutTimes(&workout, currentUser.Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	err = entriesToKilograms(workout.Entries, unit)
 	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdWorkout, err := wh.workoutStore.CreateWorkout(&workout)
+	if errors.Is(err, store.ErrInvalidEntryGroup) || errors.Is(err, store.ErrInvalidSet) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	} else if err != nil {
 		wh.logger.Error("CreateWorkout", "err", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout})
+	w.Header().Set("ETag", utils.ETag(createdWorkout.Version))
+	newRecords := wh.refreshRecords(createdWorkout)
+	entriesFromKilograms(createdWorkout.Entries, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"unit": unit, "workout": createdWorkout, "new_records": newRecords})
 }
 
 func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http.Request) {
@@ -83,14 +201,27 @@ func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http
 		return
 	}
 
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
 	existingWorkout, err := wh.workoutStore.GetWorkoutByID(workoutID)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		wh.logger.Error("GetWorkoutByID", "err", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
 		return
-	} else if existingWorkout == nil {
+	} else if err != nil {
 		wh.logger.Error("GetWorkoutByID", "err", err)
-		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !utils.CheckIfMatch(w, r, utils.ETag(existingWorkout.Version)) {
 		return
 	}
 
@@ -99,6 +230,10 @@ func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http
 		Description     *string              `json:"description"`
 		DurationMinutes *int                 `json:"duration_minutes"`
 		CaloriesBurned  *int                 `json:"calories_burned"`
+		PerformedAt     *time.Time           `json:"performed_at"`
+		Timezone        *string              `json:"timezone"`
+		StartedAt       *time.Time           `json:"started_at"`
+		EndedAt         *time.Time           `json:"ended_at"`
 		Entries         []store.WorkoutEntry `json:"entries"`
 	}
 
@@ -121,44 +256,52 @@ func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http
 	if updateWorkoutRequest.CaloriesBurned != nil {
 		existingWorkout.CaloriesBurned = *updateWorkoutRequest.CaloriesBurned
 	}
-	if updateWorkoutRequest.Entries != nil {
-		existingWorkout.Entries = updateWorkoutRequest.Entries
+	if updateWorkoutRequest.PerformedAt != nil {
+		existingWorkout.PerformedAt = *updateWorkoutRequest.PerformedAt
 	}
-
-	currentUser := middleware.GetUser(r)
-	if currentUser == nil || currentUser == store.AnonymousUser {
-		wh.logger.Debug("HandleUpdateWorkoutByID: no login detected")
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "must be logged in"})
-		return
+	if updateWorkoutRequest.Timezone != nil {
+		existingWorkout.Timezone = *updateWorkoutRequest.Timezone
 	}
-
-	workoutOwner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			wh.logger.Debug("GetWorkoutOwner: the workout doesn't exist for the specified user")
-			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+	if updateWorkoutRequest.StartedAt != nil {
+		existingWorkout.StartedAt = updateWorkoutRequest.StartedAt
+	}
+	if updateWorkoutRequest.EndedAt != nil {
+		existingWorkout.EndedAt = updateWorkoutRequest.EndedAt
+	}
+	if updateWorkoutRequest.Entries != nil {
+		err = entriesToKilograms(updateWorkoutRequest.Entries, unit)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
 			return
 		}
-
-		wh.logger.Error("GetWorkoutOwner", "err", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
-		return
+		existingWorkout.Entries = updateWorkoutRequest.Entries
 	}
 
-	if workoutOwner != currentUser.ID {
-		wh.logger.Debug("UpdateWorkoutByID: user not allowed to update the specified workout")
-		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+	err = prepareWorkoutTimes(existingWorkout, middleware.GetUser(r).Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
 		return
 	}
 
-	err = wh.workoutStore.UpdateWorkoutByID(existingWorkout)
-	if err != nil {
+	err = wh.workoutStore.UpdateWorkoutByID(existingWorkout, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJSON(w, http.StatusPreconditionFailed, utils.Envelope{"error": "resource was modified, fetch it again before updating"})
+		return
+	} else if errors.Is(err, store.ErrInvalidEntryID) || errors.Is(err, store.ErrInvalidEntryGroup) || errors.Is(err, store.ErrInvalidSet) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	} else if err != nil {
 		wh.logger.Error("UpdateWorkoutByID", "err", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": existingWorkout})
+	w.Header().Set("ETag", utils.ETag(existingWorkout.Version))
+	newRecords := wh.refreshRecords(existingWorkout)
+	entriesFromKilograms(existingWorkout.Entries, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "workout": existingWorkout, "new_records": newRecords})
 }
 
 func (wh *WorkoutHandler) HandleDeleteWorkoutByID(w http.ResponseWriter, r *http.Request) {
@@ -169,34 +312,29 @@ func (wh *WorkoutHandler) HandleDeleteWorkoutByID(w http.ResponseWriter, r *http
 		return
 	}
 
-	currentUser := middleware.GetUser(r)
-	if currentUser == nil || currentUser == store.AnonymousUser {
-		wh.logger.Debug("HandleDeleteWorkoutByID: no login detected")
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "must be logged in"})
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionDeleteWorkout) {
 		return
 	}
 
-	workoutOwner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			wh.logger.Debug("GetWorkoutOwner: the workout doesn't exist for the specified user")
-			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
-			return
-		}
-
-		wh.logger.Error("GetWorkoutOwner", "err", err)
+	existingWorkout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	} else if err != nil {
+		wh.logger.Error("GetWorkoutByID", "err", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	if workoutOwner != currentUser.ID {
-		wh.logger.Debug("DeleteWorkoutByID: user not allowed to update the specified workout")
-		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+	if !utils.CheckIfMatch(w, r, utils.ETag(existingWorkout.Version)) {
 		return
 	}
 
-	err = wh.workoutStore.DeleteWorkoutByID(workoutID)
-	if err == sql.ErrNoRows {
+	err = wh.workoutStore.DeleteWorkoutByID(workoutID, existingWorkout.Version)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJSON(w, http.StatusPreconditionFailed, utils.Envelope{"error": "resource was modified, fetch it again before deleting"})
+		return
+	} else if err == sql.ErrNoRows {
 		wh.logger.Error("DeleteWorkoutByID", "err", err)
 		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
 		return
@@ -208,3 +346,104 @@ func (wh *WorkoutHandler) HandleDeleteWorkoutByID(w http.ResponseWriter, r *http
 
 	utils.WriteJSON(w, http.StatusNoContent, utils.Envelope{"message": "workout deleted"})
 }
+
+// prepareWorkoutTimes validates the time fields of a workout and fills in
+// what the client left out: the timezone defaults to the user's, performed_at
+// to the start of the workout or now, and duration_minutes is derived from
+// started_at and ended_at when both are known.
+func prepareWorkoutTimes(workout *store.Workout, defaultTimezone string) error {
+	if workout.Timezone == "" {
+		workout.Timezone = defaultTimezone
+	}
+	_, err := loadTimezone(workout.Timezone, "")
+	if err != nil {
+		return err
+	}
+
+	if workout.StartedAt != nil && workout.EndedAt != nil {
+		if workout.EndedAt.Before(*workout.StartedAt) {
+			return errors.New("ended_at cannot be before started_at")
+		}
+		workout.DurationMinutes = int(math.Round(workout.EndedAt.Sub(*workout.StartedAt).Minutes()))
+	}
+
+	if workout.PerformedAt.IsZero() {
+		if workout.StartedAt != nil {
+			workout.PerformedAt = *workout.StartedAt
+		} else {
+			workout.PerformedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+// loadTimezone loads the named IANA timezone, or fallback when name is
+// empty. An empty result is UTC.
+func loadTimezone(name, fallback string) (*time.Location, error) {
+	if name == "" {
+		name = fallback
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return location, nil
+}
+
+// refreshRecords recomputes the personal records set by workout and returns
+// the new ones. Records are derived data: failing to compute them is logged
+// but doesn't fail a request that already saved the workout.
+func (wh *WorkoutHandler) refreshRecords(workout *store.Workout) []store.PersonalRecord {
+	var performances []strength.Performance
+	for _, entry := range workout.Entries {
+		if len(entry.SetDetails) == 0 {
+			performances = append(performances, strength.Performance{
+				EntryID:         entry.ID,
+				ExerciseID:      entry.ExerciseID,
+				ExerciseName:    entry.ExerciseName,
+				Sets:            entry.Sets,
+				Reps:            entry.Reps,
+				DurationSeconds: entry.DurationSeconds,
+				Weight:          entry.Weight,
+			})
+			continue
+		}
+
+		// entries with a set log are judged set by set
+		for _, set := range entry.SetDetails {
+			if !set.Counts() {
+				continue
+			}
+			performances = append(performances, strength.Performance{
+				EntryID:         entry.ID,
+				ExerciseID:      entry.ExerciseID,
+				ExerciseName:    entry.ExerciseName,
+				Sets:            1,
+				Reps:            set.Reps,
+				DurationSeconds: set.DurationSeconds,
+				Weight:          set.Weight,
+			})
+		}
+	}
+
+	newRecords, err := wh.recordStore.ReplaceWorkoutRecords(workout.UserID, int64(workout.ID), workout.PerformedAt, strength.WorkoutRecords(performances))
+	if err != nil {
+		wh.logger.Error("ReplaceWorkoutRecords", "err", err, "workout_id", workout.ID)
+		return []store.PersonalRecord{}
+	}
+
+	return newRecords
+}
+
+// refreshRecordsByID is refreshRecords for changes made to single entries.
+func (wh *WorkoutHandler) refreshRecordsByID(workoutID int64) []store.PersonalRecord {
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil {
+		wh.logger.Error("GetWorkoutByID", "err", err, "workout_id", workoutID)
+		return []store.PersonalRecord{}
+	}
+
+	return wh.refreshRecords(workout)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gbuenodev/goProject/internal/jsonpatch"
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+const maxPatchBytes = 1 << 20
+
+var acceptPatch = strings.Join([]string{jsonpatch.MergePatchContentType, jsonpatch.PatchContentType}, ", ")
+
+// HandlePatchWorkoutByID applies a JSON Merge Patch or a JSON Patch to the
+// JSON representation of a workout. Entries can be addressed by ID in JSON
+// Patch paths, e.g. /entries/id:42/reps, so single entries can be edited,
+// moved or removed without resending the whole list.
+func (wh *WorkoutHandler) HandlePatchWorkoutByID(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout patch id"})
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case jsonpatch.MergePatchContentType:
+		apply = jsonpatch.MergePatch
+	case jsonpatch.PatchContentType:
+		apply = jsonpatch.Apply
+	default:
+		w.Header().Set("Accept-Patch", acceptPatch)
+		utils.WriteJSON(w, http.StatusUnsupportedMediaType, utils.Envelope{"error": "unsupported patch format"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
+	existingWorkout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	} else if err != nil {
+		wh.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !utils.CheckIfMatch(w, r, utils.ETag(existingWorkout.Version)) {
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		wh.logger.Error("ReadPatchBody", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	// the document is patched as clients see it, in unit
+	entriesFromKilograms(existingWorkout.Entries, unit)
+	doc, err := json.Marshal(existingWorkout)
+	if err != nil {
+		wh.logger.Error("MarshalWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	patched, err := apply(doc, patch)
+	if err != nil {
+		wh.logger.Debug("ApplyPatch", "err", err)
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		case errors.Is(err, jsonpatch.ErrPathNotFound):
+			utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		default:
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid patch document"})
+		}
+		return
+	}
+
+	var patchedWorkout store.Workout
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&patchedWorkout)
+	if err != nil {
+		wh.logger.Debug("DecodingPatchedWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "patched workout is invalid"})
+		return
+	}
+
+	if patchedWorkout.ID != existingWorkout.ID ||
+		patchedWorkout.UserID != existingWorkout.UserID ||
+		patchedWorkout.Version != existingWorkout.Version {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": "id, user_id and version cannot be changed"})
+		return
+	}
+	orderFromPositions(existingWorkout.Entries, patchedWorkout.Entries)
+	for i := range patchedWorkout.Entries {
+		// the document holds every set log, so an entry left without one
+		// has had it removed
+		if patchedWorkout.Entries[i].SetDetails == nil {
+			patchedWorkout.Entries[i].SetDetails = []store.WorkoutSet{}
+		}
+	}
+
+	err = prepareWorkoutTimes(&patchedWorkout, middleware.GetUser(r).Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	}
+	err = entriesToKilograms(patchedWorkout.Entries, unit)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.workoutStore.UpdateWorkoutByID(&patchedWorkout, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJSON(w, http.StatusPreconditionFailed, utils.Envelope{"error": "resource was modified, fetch it again before updating"})
+		return
+	} else if errors.Is(err, store.ErrInvalidEntryID) || errors.Is(err, store.ErrInvalidEntryGroup) || errors.Is(err, store.ErrInvalidSet) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	} else if err != nil {
+		wh.logger.Error("UpdateWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(patchedWorkout.Version))
+	newRecords := wh.refreshRecords(&patchedWorkout)
+	entriesFromKilograms(patchedWorkout.Entries, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "workout": patchedWorkout, "new_records": newRecords})
+}
+
+// orderFromPositions numbers the patched entries after their position in the
+// entries array, so entries moved or added by a patch keep the place it gave
+// them. A patch that edits order_index itself is taken at its word.
+func orderFromPositions(original, patched []store.WorkoutEntry) {
+	orderIndexes := map[int]int{}
+	for _, entry := range original {
+		orderIndexes[entry.ID] = entry.OrderIndex
+	}
+	for _, entry := range patched {
+		if orderIndex, ok := orderIndexes[entry.ID]; ok && entry.ID != 0 && entry.OrderIndex != orderIndex {
+			return
+		}
+	}
+
+	for i := range patched {
+		patched[i].OrderIndex = i + 1
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/revisions"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+func (wh *WorkoutHandler) HandleListWorkoutRevisions(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
+	workoutRevisions, err := wh.workoutStore.ListWorkoutRevisions(workoutID)
+	if err != nil {
+		wh.logger.Error("ListWorkoutRevisions", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"revisions": workoutRevisions})
+}
+
+func (wh *WorkoutHandler) HandleGetWorkoutRevision(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	version, err := utils.ReadInt64Param(r, "version")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid revision version"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
+	revision, ok := wh.getRevision(w, workoutID, int(version))
+	if !ok {
+		return
+	}
+
+	entriesFromKilograms(revision.Workout.Entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "revision": revision})
+}
+
+// HandleDiffWorkoutRevisions compares the revisions named by the from and to
+// query parameters. to defaults to the current version.
+func (wh *WorkoutHandler) HandleDiffWorkoutRevisions(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	fromVersion, err := utils.ReadIntQuery(r, "from", 0)
+	if err != nil || fromVersion < 1 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "from must be a revision version"})
+		return
+	}
+	toVersion, err := utils.ReadIntQuery(r, "to", 0)
+	if err != nil || toVersion < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "to must be a revision version"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
+	from, ok := wh.getRevision(w, workoutID, fromVersion)
+	if !ok {
+		return
+	}
+
+	var to *store.Workout
+	if toVersion == 0 {
+		to, err = wh.workoutStore.GetWorkoutByID(workoutID)
+		if err != nil {
+			wh.logger.Error("GetWorkoutByID", "err", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+	} else {
+		toRevision, ok := wh.getRevision(w, workoutID, toVersion)
+		if !ok {
+			return
+		}
+		to = toRevision.Workout
+	}
+
+	entriesFromKilograms(from.Workout.Entries, unit)
+	entriesFromKilograms(to.Entries, unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "diff": revisions.Compare(from.Workout, to)})
+}
+
+// HandleRestoreWorkoutRevision saves a past revision as the newest version
+// of the workout. The restore is itself recorded as a new revision.
+func (wh *WorkoutHandler) HandleRestoreWorkoutRevision(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Error("ReadIDParam", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	version, err := utils.ReadInt64Param(r, "version")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid revision version"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionUpdateWorkout) {
+		return
+	}
+
+	existingWorkout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	} else if err != nil {
+		wh.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !utils.CheckIfMatch(w, r, utils.ETag(existingWorkout.Version)) {
+		return
+	}
+
+	revision, ok := wh.getRevision(w, workoutID, int(version))
+	if !ok {
+		return
+	}
+
+	restoredWorkout := revisions.Restore(existingWorkout, revision.Workout)
+	err = wh.workoutStore.UpdateWorkoutByID(restoredWorkout, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJSON(w, http.StatusPreconditionFailed, utils.Envelope{"error": "resource was modified, fetch it again before updating"})
+		return
+	} else if err != nil {
+		wh.logger.Error("UpdateWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(restoredWorkout.Version))
+	newRecords := wh.refreshRecords(restoredWorkout)
+	entriesFromKilograms(restoredWorkout.Entries, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"unit": unit, "workout": restoredWorkout, "new_records": newRecords})
+}
+
+func (wh *WorkoutHandler) getRevision(w http.ResponseWriter, workoutID int64, version int) (*store.WorkoutRevision, bool) {
+	revision, err := wh.workoutStore.GetWorkoutRevision(workoutID, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "revision not found"})
+		return nil, false
+	} else if err != nil {
+		wh.logger.Error("GetWorkoutRevision", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	return revision, true
+}
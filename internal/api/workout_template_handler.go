@@ -0,0 +1,145 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/utils"
+)
+
+// HandleCreateWorkoutFromTemplate creates a workout pre-filled with the
+// entries of a template. The optional body overrides the title and sets
+// when the workout was performed, like on POST /workouts.
+func (wh *WorkoutHandler) HandleCreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Title       string     `json:"title"`
+		PerformedAt time.Time  `json:"performed_at"`
+		Timezone    string     `json:"timezone"`
+		StartedAt   *time.Time `json:"started_at"`
+		EndedAt     *time.Time `json:"ended_at"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		wh.logger.Error("DecodingCreateWorkoutFromTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	template, ok := loadTemplate(w, wh.templateStore, wh.logger, templateID)
+	if !ok {
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	if !policy.Can(currentUser, policy.ActionManageTemplate, policy.Resource{OwnerID: template.UserID}) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+		return
+	}
+
+	workout := template.NewWorkout(currentUser.ID)
+	if title := strings.TrimSpace(req.Title); title != "" {
+		workout.Title = title
+	}
+	workout.PerformedAt = req.PerformedAt
+	workout.Timezone = req.Timezone
+	workout.StartedAt = req.StartedAt
+	workout.EndedAt = req.EndedAt
+
+	err = prepareWorkoutTimes(workout, currentUser.Timezone)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdWorkout, err := wh.workoutStore.CreateWorkout(workout)
+	if err != nil {
+		wh.logger.Error("CreateWorkout", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(createdWorkout.Version))
+	newRecords := wh.refreshRecords(createdWorkout)
+	entriesFromKilograms(createdWorkout.Entries, unit)
+	recordsFromKilograms(newRecords, unit)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"unit": unit, "workout": createdWorkout, "new_records": newRecords})
+}
+
+// HandleSaveWorkoutAsTemplate saves the entries of a workout the current
+// user can read as a new template of their own. The optional body renames
+// it.
+func (wh *WorkoutHandler) HandleSaveWorkoutAsTemplate(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	unit, ok := requestUnit(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Title       string  `json:"title"`
+		Description *string `json:"description"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		wh.logger.Error("DecodingSaveWorkoutAsTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		return
+	}
+
+	if !wh.authorizeWorkout(w, r, workoutID, policy.ActionReadWorkout) {
+		return
+	}
+
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	} else if err != nil {
+		wh.logger.Error("GetWorkoutByID", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	template := store.NewTemplateFromWorkout(workout, middleware.GetUser(r).ID)
+	if title := strings.TrimSpace(req.Title); title != "" {
+		template.Title = title
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+
+	err = wh.templateStore.CreateTemplate(template)
+	if err != nil {
+		wh.logger.Error("CreateTemplate", "err", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	entriesFromKilograms(template.Entries, unit)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"unit": unit, "template": template})
+}
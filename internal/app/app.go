@@ -1,28 +1,64 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gbuenodev/goProject/internal/api"
 	"github.com/gbuenodev/goProject/internal/errors"
 	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/oidc"
+	"github.com/gbuenodev/goProject/internal/passwords"
+	"github.com/gbuenodev/goProject/internal/ratelimit"
 	"github.com/gbuenodev/goProject/internal/store"
 	"github.com/gbuenodev/goProject/internal/utils"
 	"github.com/gbuenodev/goProject/migrations"
 )
 
+type Config struct {
+	LogLevel string
+	// OIDCConfigPath points to a JSON file with the external identity
+	// providers. Leave empty to disable external logins.
+	OIDCConfigPath string
+	// Argon2id configures how new password hashes are computed. Hashes with
+	// other parameters are upgraded on the next login.
+	Argon2id passwords.Argon2idParams
+	// BreachedPasswordsPath points to a local copy of the Pwned Passwords
+	// list, see passwords.NewBreachedList. Leave empty to skip the check.
+	BreachedPasswordsPath string
+	// TrashRetention is how long deleted workouts stay in the trash before
+	// they are purged. Zero keeps them forever.
+	TrashRetention time.Duration
+	// SharedRateLimits keeps the rate limit buckets in the database, so
+	// every instance behind a load balancer enforces the same limits.
+	SharedRateLimits bool
+}
+
 type App struct {
-	Logger         *slog.Logger
-	WorkoutHandler *api.WorkoutHandler
-	UserHandler    *api.UserHandler
-	TokenHandler   *api.TokenHandler
-	Middleware     middleware.UserMiddleware
-	DBConn         *sql.DB
+	Logger           *slog.Logger
+	WorkoutHandler   *api.WorkoutHandler
+	UserHandler      *api.UserHandler
+	TokenHandler     *api.TokenHandler
+	MFAHandler       *api.MFAHandler
+	OIDCHandler      *api.OIDCHandler
+	AdminHandler     *api.AdminHandler
+	ExerciseHandler  *api.ExerciseHandler
+	RecordHandler    *api.RecordHandler
+	AnalyticsHandler *api.AnalyticsHandler
+	TemplateHandler  *api.TemplateHandler
+	ProgramHandler   *api.ProgramHandler
+	CalendarHandler  *api.CalendarHandler
+	Middleware       middleware.UserMiddleware
+	RateLimiter      middleware.RateLimiter
+	DBConn           *sql.DB
 }
 
-func NewApp(logLevel string) (*App, error) {
+// NewApp wires the application together. Background jobs run until ctx is
+// done.
+func NewApp(ctx context.Context, cfg Config) (*App, error) {
 	dbConfig := store.DBConfig{
 		Provider: "Postgres",
 		Driver:   "pgx",
@@ -44,25 +80,89 @@ func NewApp(logLevel string) (*App, error) {
 		panic(err)
 	}
 
-	loggerOpts := errors.SetLoggerLevel(logLevel)
+	passwords.SetDefault(passwords.NewManager(
+		passwords.NewArgon2idHasher(cfg.Argon2id),
+		passwords.NewBcryptHasher(passwords.DefaultBcryptCost),
+	))
+
+	passwordPolicy := passwords.DefaultPolicy
+	if cfg.BreachedPasswordsPath != "" {
+		passwordPolicy.Breached, err = passwords.NewBreachedList(cfg.BreachedPasswordsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loggerOpts := errors.SetLoggerLevel(cfg.LogLevel)
 	logger := errors.SetupDefaultLogger(loggerOpts)
 
 	workoutStore := store.NewPostgresWorkoutStore(DBConn)
 	userStore := store.NewPostgresUserStore(DBConn)
 	tokenStore := store.NewPostgresTokenStore(DBConn)
+	loginAttemptStore := store.NewPostgresLoginAttemptStore(DBConn)
+	mfaStore := store.NewPostgresMFAStore(DBConn)
+	identityStore := store.NewPostgresIdentityStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+	recordStore := store.NewPostgresRecordStore(DBConn)
+	analyticsStore := store.NewPostgresAnalyticsStore(DBConn)
+	templateStore := store.NewPostgresTemplateStore(DBConn)
+	programStore := store.NewPostgresProgramStore(DBConn)
+	plannedWorkoutStore := store.NewPostgresPlannedWorkoutStore(DBConn)
+
+	var oidcProviders []*oidc.Provider
+	if cfg.OIDCConfigPath != "" {
+		providerConfigs, err := oidc.LoadProviderConfigs(cfg.OIDCConfigPath)
+		if err != nil {
+			return nil, err
+		}
 
-	workoutHandler := api.NewWorkoutHandler(workoutStore, logger)
-	userHandler := api.NewUserHandler(userStore, logger)
-	tokenHandler := api.NewTokenHandler(tokenStore, userStore, logger)
+		httpClient := &http.Client{Timeout: 10 * time.Second}
+		for _, providerConfig := range providerConfigs {
+			oidcProviders = append(oidcProviders, oidc.NewProvider(providerConfig, httpClient))
+		}
+	}
+
+	workoutHandler := api.NewWorkoutHandler(workoutStore, recordStore, templateStore, logger)
+	userHandler := api.NewUserHandler(userStore, passwordPolicy, logger)
+	tokenHandler := api.NewTokenHandler(tokenStore, userStore, loginAttemptStore, mfaStore, api.DefaultLoginThrottleConfig, logger)
+	oidcHandler := api.NewOIDCHandler(oidcProviders, identityStore, userStore, tokenHandler, logger)
+	mfaHandler := api.NewMFAHandler(mfaStore, loginAttemptStore, api.DefaultLoginThrottleConfig, "WorkoutAPI", logger)
+	adminHandler := api.NewAdminHandler(userStore, workoutStore, logger)
+	exerciseHandler := api.NewExerciseHandler(exerciseStore, logger)
+	recordHandler := api.NewRecordHandler(recordStore, logger)
+	analyticsHandler := api.NewAnalyticsHandler(analyticsStore, logger)
+	templateHandler := api.NewTemplateHandler(templateStore, logger)
+	programHandler := api.NewProgramHandler(programStore, templateStore, workoutStore, logger)
+	calendarHandler := api.NewCalendarHandler(plannedWorkoutStore, workoutStore, templateStore, tokenStore, userStore, logger)
 	middlewareHandler := middleware.UserMiddleware{UserStore: userStore}
+	rateLimiter := middleware.RateLimiter{Store: ratelimit.NewMemoryStore(), Logger: logger}
+	if cfg.SharedRateLimits {
+		rateLimitStore := store.NewPostgresRateLimitStore(DBConn)
+		rateLimiter.Store = rateLimitStore
+		go purgeRateLimitBuckets(ctx, rateLimitStore, logger)
+	}
+
+	if cfg.TrashRetention > 0 {
+		go purgeTrash(ctx, workoutStore, cfg.TrashRetention, logger)
+	}
 
 	app := &App{
-		Logger:         logger,
-		WorkoutHandler: workoutHandler,
-		UserHandler:    userHandler,
-		TokenHandler:   tokenHandler,
-		Middleware:     middlewareHandler,
-		DBConn:         DBConn,
+		Logger:           logger,
+		WorkoutHandler:   workoutHandler,
+		UserHandler:      userHandler,
+		TokenHandler:     tokenHandler,
+		MFAHandler:       mfaHandler,
+		OIDCHandler:      oidcHandler,
+		AdminHandler:     adminHandler,
+		ExerciseHandler:  exerciseHandler,
+		RecordHandler:    recordHandler,
+		AnalyticsHandler: analyticsHandler,
+		TemplateHandler:  templateHandler,
+		ProgramHandler:   programHandler,
+		CalendarHandler:  calendarHandler,
+		Middleware:       middlewareHandler,
+		RateLimiter:      rateLimiter,
+		DBConn:           DBConn,
 	}
 
 	return app, nil
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+)
+
+const rateLimitPurgeInterval = 10 * time.Minute
+
+// purgeRateLimitBuckets deletes full rate limit buckets every
+// rateLimitPurgeInterval until ctx is done.
+func purgeRateLimitBuckets(ctx context.Context, rateLimitStore *store.PostgresRateLimitStore, logger *slog.Logger) {
+	ticker := time.NewTicker(rateLimitPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := rateLimitStore.PurgeFullBuckets(time.Now())
+		if err != nil {
+			logger.Error("PurgeFullBuckets", "err", err)
+		} else if purged > 0 {
+			logger.Debug("purged full rate limit buckets", "count", purged)
+		}
+	}
+}
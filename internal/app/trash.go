@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+)
+
+const trashPurgeInterval = time.Hour
+
+// purgeTrash permanently deletes workouts that have been in the trash for
+// longer than retention, once at startup and then every trashPurgeInterval
+// until ctx is done.
+func purgeTrash(ctx context.Context, workoutStore store.WorkoutStore, retention time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := workoutStore.PurgeDeletedWorkouts(time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("PurgeDeletedWorkouts", "err", err)
+		} else if purged > 0 {
+			logger.Info("purged workouts from the trash", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
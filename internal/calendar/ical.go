@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT of a feed.
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	// Status is CONFIRMED, TENTATIVE or CANCELLED, left out when empty.
+	Status string
+}
+
+const icalTime = "20060102T150405Z"
+
+// WriteFeed writes events as an RFC 5545 calendar named name. Times are
+// written in UTC, calendar apps show them in the timezone of the device.
+func WriteFeed(w io.Writer, name string, events []Event, now time.Time) error {
+	bw := bufio.NewWriter(w)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//goProject//Workout Calendar//EN")
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	writeLine(bw, "X-WR-CALNAME:"+escapeText(name))
+
+	for _, event := range events {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+event.UID)
+		writeLine(bw, "DTSTAMP:"+now.UTC().Format(icalTime))
+		writeLine(bw, "DTSTART:"+event.Start.UTC().Format(icalTime))
+		writeLine(bw, "DTEND:"+event.End.UTC().Format(icalTime))
+		writeLine(bw, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(bw, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Status != "" {
+			writeLine(bw, "STATUS:"+event.Status)
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// writeLine writes a content line folded at 75 octets, never splitting a
+// UTF-8 sequence.
+func writeLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space, which counts
+		limit = 74
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
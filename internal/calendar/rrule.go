@@ -0,0 +1,203 @@
+// Package calendar expands recurring plans and writes iCalendar feeds.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// maxPeriods bounds the expansion of a rule, about 27 years of days.
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Rule is the subset of RFC 5545 recurrence rules plans can use: FREQ of
+// DAILY, WEEKLY or MONTHLY, INTERVAL, BYDAY with WEEKLY, and COUNT or UNTIL.
+// Weeks start on Monday.
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	// Until is the last moment an occurrence may start at, zero when the
+	// rule doesn't end on a date.
+	Until time.Time
+}
+
+// ParseRule parses a recurrence rule like "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=24".
+// Dates of UNTIL without a time are read in location and include the whole
+// day.
+func ParseRule(value string, location *time.Location) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(strings.TrimPrefix(value, "RRULE:"), ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		if !ok || arg == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(arg)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return nil, errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(arg)
+			if err != nil || rule.Interval < 1 || rule.Interval > 365 {
+				return nil, errors.New("INTERVAL must be between 1 and 365")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(arg)
+			if err != nil || rule.Count < 1 {
+				return nil, errors.New("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			rule.Until, err = parseUntil(arg, location)
+			if err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(arg), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", day)
+				}
+				if !slices.Contains(rule.ByDay, weekday) {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot be used together")
+	}
+
+	slices.SortFunc(rule.ByDay, func(a, b time.Weekday) int { return mondayOffset(a) - mondayOffset(b) })
+	return rule, nil
+}
+
+func parseUntil(value string, location *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+
+	day, err := time.ParseInLocation("20060102", value, location)
+	if err != nil {
+		return time.Time{}, errors.New("UNTIL must be a date like 20060102 or a UTC time like 20060102T150405Z")
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// String formats the rule back in RFC 5545 syntax.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Expand returns the occurrences of a plan starting at start and repeating
+// by rule that start in [from, to). A nil rule means start only. Occurrences
+// keep the wall clock time of start in its location across DST changes.
+func Expand(rule *Rule, start, from, to time.Time) []time.Time {
+	occurrences := []time.Time{}
+	if rule == nil {
+		if !start.Before(from) && start.Before(to) {
+			occurrences = append(occurrences, start)
+		}
+		return occurrences
+	}
+
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, occurrence := range rule.periodOccurrences(start, period) {
+			if occurrence.Before(start) {
+				continue
+			}
+			if !occurrence.Before(to) || (!rule.Until.IsZero() && occurrence.After(rule.Until)) {
+				return occurrences
+			}
+
+			count++
+			if !occurrence.Before(from) {
+				occurrences = append(occurrences, occurrence)
+			}
+			if rule.Count > 0 && count >= rule.Count {
+				return occurrences
+			}
+		}
+	}
+	return occurrences
+}
+
+// periodOccurrences returns the candidate occurrences of the nth period of
+// the rule, in order.
+func (r *Rule) periodOccurrences(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+
+	switch r.Freq {
+	case Weekly:
+		weekStart := start.AddDate(0, 0, -mondayOffset(start.Weekday())+7*step)
+		if len(r.ByDay) == 0 {
+			return []time.Time{weekStart.AddDate(0, 0, mondayOffset(start.Weekday()))}
+		}
+
+		occurrences := make([]time.Time, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			occurrences[i] = weekStart.AddDate(0, 0, mondayOffset(weekday))
+		}
+		return occurrences
+	case Monthly:
+		occurrence := time.Date(start.Year(), start.Month()+time.Month(step), start.Day(),
+			start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		if occurrence.Day() != start.Day() {
+			// months without that day are skipped
+			return nil
+		}
+		return []time.Time{occurrence}
+	default:
+		return []time.Time{start.AddDate(0, 0, step)}
+	}
+}
+
+func mondayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
@@ -0,0 +1,393 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	PatchContentType      = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch means the patch document itself is malformed.
+	ErrInvalidPatch = errors.New("jsonpatch: invalid patch")
+	// ErrPathNotFound means an operation refers to a location that doesn't
+	// exist in the document.
+	ErrPathNotFound = errors.New("jsonpatch: path not found")
+	// ErrTestFailed means a "test" operation didn't match.
+	ErrTestFailed = errors.New("jsonpatch: test failed")
+)
+
+// idSelectorPrefix lets a path address an array element by its "id" member
+// instead of its position, e.g. /entries/id:42/weight. Positions shift when
+// elements are added or removed, IDs don't.
+const idSelectorPrefix = "id:"
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MergePatch applies an RFC 7386 JSON Merge Patch to doc.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+// Apply applies an RFC 6902 JSON Patch to doc. Operations are applied in
+// order and the patch is atomic: any failing operation fails it all.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	err := json.Unmarshal(patch, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	root, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		root, err = applyOperation(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func applyOperation(root any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			return replace(root, path, value)
+		}
+
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(normalize(current), normalize(value)) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+
+	case "remove":
+		return remove(root, path)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+			}
+			root, err = remove(root, from)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+
+		return add(root, path, value)
+	}
+
+	return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+}
+
+func add(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(root, path, func(container any, key string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[key] = value
+			return c, nil
+		case []any:
+			i := len(c)
+			if key != "-" {
+				var err error
+				i, err = arrayIndex(c, key, true)
+				if err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, ErrPathNotFound
+	})
+}
+
+func replace(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(root, path, func(container any, key string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[key]; !ok {
+				return nil, ErrPathNotFound
+			}
+			c[key] = value
+			return c, nil
+		case []any:
+			i, err := arrayIndex(c, key, false)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = value
+			return c, nil
+		}
+		return nil, ErrPathNotFound
+	})
+}
+
+func remove(root any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	return update(root, path, func(container any, key string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[key]; !ok {
+				return nil, ErrPathNotFound
+			}
+			delete(c, key)
+			return c, nil
+		case []any:
+			i, err := arrayIndex(c, key, false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return nil, ErrPathNotFound
+	})
+}
+
+func get(root any, path []string) (any, error) {
+	node := root
+	for _, key := range path {
+		switch c := node.(type) {
+		case map[string]any:
+			value, ok := c[key]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			node = value
+		case []any:
+			i, err := arrayIndex(c, key, false)
+			if err != nil {
+				return nil, err
+			}
+			node = c[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return node, nil
+}
+
+// update walks down to the parent of the last path token and calls fn with
+// it. fn returns the modified parent, which is stored back into its own
+// parent since appending to an array can reallocate it.
+func update(node any, path []string, fn func(container any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	child, err := get(node, path[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	newChild, err := update(child, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := node.(type) {
+	case map[string]any:
+		c[path[0]] = newChild
+	case []any:
+		i, _ := arrayIndex(c, path[0], false)
+		c[i] = newChild
+	}
+	return node, nil
+}
+
+// arrayIndex resolves a path token to a position in arr. When adding, the
+// position right after the last element is valid too.
+func arrayIndex(arr []any, key string, adding bool) (int, error) {
+	if id, ok := strings.CutPrefix(key, idSelectorPrefix); ok {
+		for i, element := range arr {
+			obj, ok := element.(map[string]any)
+			if ok && fmt.Sprint(obj["id"]) == id {
+				return i, nil
+			}
+		}
+		return 0, ErrPathNotFound
+	}
+
+	if key == "" || (len(key) > 1 && key[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, key)
+	}
+
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, key)
+	}
+
+	limit := len(arr)
+	if adding {
+		limit++
+	}
+	if i >= limit {
+		return 0, ErrPathNotFound
+	}
+
+	return i, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	err := decoder.Decode(&v)
+	return v, err
+}
+
+func deepCopy(v any) any {
+	data, _ := json.Marshal(v)
+	copied, _ := decode(data)
+	return copied
+}
+
+// normalize makes numbers comparable regardless of their notation, so that
+// 1 and 1.0 are equal in a "test" operation.
+func normalize(v any) any {
+	switch value := v.(type) {
+	case json.Number:
+		f, err := value.Float64()
+		if err != nil {
+			return value.String()
+		}
+		return f
+	case map[string]any:
+		normalized := make(map[string]any, len(value))
+		for k, item := range value {
+			normalized[k] = normalize(item)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(value))
+		for i, item := range value {
+			normalized[i] = normalize(item)
+		}
+		return normalized
+	}
+	return v
+}
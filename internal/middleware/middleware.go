@@ -56,6 +56,9 @@ func (um *UserMiddleware) Auth(next http.Handler) http.Handler {
 		} else if user == nil {
 			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "token expired or invalid"})
 			return
+		} else if user.IsSuspended() {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "account suspended"})
+			return
 		}
 
 		r = SetUser(r, user)
@@ -75,3 +78,23 @@ func (um *UserMiddleware) RequireUser(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (um *UserMiddleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+
+			if user.IsAnonymous() {
+				utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "must be logged in to access this route"})
+				return
+			}
+
+			if !user.HasRole(roles...) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "not authorized to perform this action"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
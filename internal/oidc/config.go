@@ -0,0 +1,39 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type ProviderConfig struct {
+	// Name identifies the provider in URLs and linked identities, e.g. "google".
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// LoadProviderConfigs reads a JSON array of provider configurations.
+func LoadProviderConfigs(path string) ([]ProviderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: read config: %w", err)
+	}
+
+	var configs []ProviderConfig
+	err = json.Unmarshal(data, &configs)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: parse config: %w", err)
+	}
+
+	for _, config := range configs {
+		if config.Name == "" || config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc: provider %q needs a name, issuer, client_id and redirect_url", config.Name)
+		}
+	}
+
+	return configs, nil
+}
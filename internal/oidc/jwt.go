@@ -0,0 +1,172 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken   = errors.New("oidc: invalid id token")
+	ErrUnknownKey     = errors.New("oidc: id token signed with an unknown key")
+	ErrNonceMismatch  = errors.New("oidc: nonce mismatch")
+	ErrTokenExpired   = errors.New("oidc: id token expired")
+	ErrIssuerMismatch = errors.New("oidc: issuer mismatch")
+)
+
+// clockSkew is tolerated when checking exp and iat.
+const clockSkew = time.Minute
+
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	Expiry        int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// audience is either a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	err := json.Unmarshal(data, &many)
+	*a = many
+	return err
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// flexBool accepts true/false as well as "true"/"false", some providers send
+// email_verified as a string.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("oidc: invalid boolean %s", data)
+	}
+	return nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// parseJWT splits a compact serialized RS256 JWT and returns its header,
+// the signed content, the signature and the raw payload.
+func parseJWT(raw string) (jwtHeader, []byte, []byte, []byte, error) {
+	var header jwtHeader
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+	err = json.Unmarshal(headerJSON, &header)
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+	if header.Algorithm != "RS256" {
+		return header, nil, nil, nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	return header, []byte(parts[0] + "." + parts[1]), signature, payload, nil
+}
+
+func verifyRS256(key *rsa.PublicKey, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+func (c *Claims) validate(issuer, clientID, nonce string, now time.Time) error {
+	if c.Issuer != issuer {
+		return ErrIssuerMismatch
+	}
+	if !c.Audience.contains(clientID) {
+		return fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	if now.After(time.Unix(c.Expiry, 0).Add(clockSkew)) {
+		return ErrTokenExpired
+	}
+	if c.IssuedAt != 0 && time.Unix(c.IssuedAt, 0).After(now.Add(clockSkew)) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+	if c.Nonce != nonce {
+		return ErrNonceMismatch
+	}
+	return nil
+}
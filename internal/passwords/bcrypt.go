@@ -0,0 +1,51 @@
+package passwords
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+// BcryptHasher is kept to verify hashes created before argon2id. Note that
+// bcrypt only looks at the first 72 bytes of a password.
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(plainText string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(plainText), h.cost)
+}
+
+func (h *BcryptHasher) Matches(plainText string, hash []byte) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(hash, []byte(plainText))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (h *BcryptHasher) Identifies(hash []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if bytes.HasPrefix(hash, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *BcryptHasher) IsCurrent(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err == nil && cost == h.cost
+}
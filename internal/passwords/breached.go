@@ -0,0 +1,117 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BreachChecker reports whether a password is known from a data breach.
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+const hashPrefixLength = 5
+
+// hashRange splits the SHA-1 of password the way the Pwned Passwords range
+// API does: a 5 character prefix and the 35 character suffix.
+func hashRange(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:hashPrefixLength], hash[hashPrefixLength:]
+}
+
+// NewBreachedList opens a local copy of the Pwned Passwords SHA-1 list.
+// path is either a single file with one HASH or HASH:COUNT per line, which
+// is loaded in memory, or a directory of range files named after the hash
+// prefix (e.g. 21BD1.txt) holding SUFFIX:COUNT lines, which are read on
+// demand. Either way passwords are only compared by hash prefix bucket,
+// like the k-anonymity range API.
+func NewBreachedList(path string) (BreachChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("passwords: breached list: %w", err)
+	}
+
+	if info.IsDir() {
+		return &rangeDirectory{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("passwords: breached list: %w", err)
+	}
+	defer f.Close()
+
+	return loadBreachedList(f)
+}
+
+// breachedList keeps the hash suffixes in memory, grouped by prefix.
+type breachedList struct {
+	buckets map[string][]string
+}
+
+func loadBreachedList(r io.Reader) (*breachedList, error) {
+	list := &breachedList{buckets: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+
+		hash = strings.ToUpper(hash)
+		prefix := hash[:hashPrefixLength]
+		list.buckets[prefix] = append(list.buckets[prefix], hash[hashPrefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("passwords: breached list: %w", err)
+	}
+
+	for _, bucket := range list.buckets {
+		slices.Sort(bucket)
+	}
+
+	return list, nil
+}
+
+func (l *breachedList) IsBreached(password string) (bool, error) {
+	prefix, suffix := hashRange(password)
+	_, found := slices.BinarySearch(l.buckets[prefix], suffix)
+	return found, nil
+}
+
+type rangeDirectory struct {
+	dir string
+}
+
+func (d *rangeDirectory) IsBreached(password string) (bool, error) {
+	prefix, suffix := hashRange(password)
+
+	f, err := os.Open(filepath.Join(d.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
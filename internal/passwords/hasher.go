@@ -0,0 +1,88 @@
+package passwords
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrUnknownHashFormat = errors.New("passwords: unknown hash format")
+
+// Hasher is a single password hashing algorithm. Hashes are self describing
+// strings that embed the algorithm and its parameters, so they can be
+// verified after the configuration changed.
+type Hasher interface {
+	Hash(plainText string) ([]byte, error)
+	Matches(plainText string, hash []byte) (bool, error)
+	// Identifies reports whether hash was produced by this algorithm.
+	Identifies(hash []byte) bool
+	// IsCurrent reports whether hash uses this hasher's parameters.
+	IsCurrent(hash []byte) bool
+}
+
+// Manager hashes new passwords with the current hasher and verifies hashes
+// of every registered algorithm, which lets legacy hashes be upgraded.
+type Manager struct {
+	current Hasher
+	hashers []Hasher
+
+	dummyOnce sync.Once
+	dummyHash []byte
+}
+
+func NewManager(current Hasher, legacy ...Hasher) *Manager {
+	return &Manager{
+		current: current,
+		hashers: append([]Hasher{current}, legacy...),
+	}
+}
+
+func (m *Manager) Hash(plainText string) ([]byte, error) {
+	return m.current.Hash(plainText)
+}
+
+func (m *Manager) Matches(plainText string, hash []byte) (bool, error) {
+	for _, hasher := range m.hashers {
+		if hasher.Identifies(hash) {
+			return hasher.Matches(plainText, hash)
+		}
+	}
+
+	return false, ErrUnknownHashFormat
+}
+
+// SimulateMatches does the work Matches does for a hash of the current
+// hasher, for logins that name a user who doesn't exist. The hash it checks
+// against is made once, so the check costs what verifying a real password
+// costs, not what hashing one does.
+func (m *Manager) SimulateMatches(plainText string) {
+	m.dummyOnce.Do(func() {
+		m.dummyHash, _ = m.current.Hash("not a real password")
+	})
+	if m.dummyHash != nil {
+		_, _ = m.current.Matches(plainText, m.dummyHash)
+	}
+}
+
+// NeedsRehash reports whether hash should be replaced by a new hash of the
+// same password, because it uses another algorithm or outdated parameters.
+func (m *Manager) NeedsRehash(hash []byte) bool {
+	return !m.current.Identifies(hash) || !m.current.IsCurrent(hash)
+}
+
+var (
+	mu             sync.RWMutex
+	defaultManager = NewManager(NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(DefaultBcryptCost))
+)
+
+// Default returns the manager used for user passwords.
+func Default() *Manager {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultManager
+}
+
+func SetDefault(m *Manager) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultManager = m
+}
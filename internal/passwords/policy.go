@@ -0,0 +1,135 @@
+package passwords
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationError is returned when a password doesn't satisfy the policy.
+// Its message is meant to be shown to the user.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...any) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// UserInputs are the user's other attributes, a password must not contain them.
+type UserInputs struct {
+	Username string
+	Email    string
+}
+
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinCharacterClasses is how many of lowercase, uppercase, digits and
+	// symbols (anything else, including spaces) must be present.
+	MinCharacterClasses int
+	// MinStrength is the minimum Strength score, from 0 to 4.
+	MinStrength int
+	// DisallowUserInputs rejects passwords containing the username or email.
+	DisallowUserInputs bool
+	// Breached, when set, rejects passwords known from data breaches.
+	Breached BreachChecker
+}
+
+// DefaultPolicy favours length and unpredictability over composition rules,
+// so long passphrases are accepted.
+var DefaultPolicy = Policy{
+	MinLength:           8,
+	MaxLength:           256,
+	MinCharacterClasses: 1,
+	MinStrength:         3,
+	DisallowUserInputs:  true,
+}
+
+// Validate returns a *ValidationError when password breaks the policy. Any
+// other error means the check itself failed.
+func (p Policy) Validate(password string, inputs UserInputs) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return invalid("password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return invalid("password must be at most %d characters long", p.MaxLength)
+	}
+
+	if characterClasses(password) < p.MinCharacterClasses {
+		return invalid("password must contain at least %d of: lowercase letters, uppercase letters, numbers and symbols", p.MinCharacterClasses)
+	}
+
+	if p.DisallowUserInputs && containsUserInputs(password, inputs) {
+		return invalid("password must not contain your username or email")
+	}
+
+	if Strength(password) < p.MinStrength {
+		return invalid("password is too easy to guess, try a longer passphrase or fewer common words and patterns")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return fmt.Errorf("passwords: breach check: %w", err)
+		}
+		if breached {
+			return invalid("password appeared in a data breach, please choose another one")
+		}
+	}
+
+	return nil
+}
+
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
+
+func containsUserInputs(password string, inputs UserInputs) bool {
+	lower := strings.ToLower(password)
+
+	candidates := []string{inputs.Username, inputs.Email}
+	if local, _, ok := strings.Cut(inputs.Email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+		if len(candidate) >= 3 && strings.Contains(lower, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package passwords
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonWords are passwords and fragments attackers try first. Matching is
+// done after undoing common character substitutions (p@ssw0rd).
+var commonWords = []string{
+	"password", "passwort", "passw", "pass", "qwerty", "azerty", "asdf", "zxcv",
+	"letmein", "welcome", "admin", "login", "master", "monkey", "dragon",
+	"football", "baseball", "soccer", "hockey", "iloveyou", "love", "princess",
+	"sunshine", "shadow", "superman", "batman", "trustno1", "starwars",
+	"michael", "jordan", "charlie", "hello", "freedom", "whatever", "secret",
+	"abc", "qazwsx", "changeme", "default", "summer", "winter", "spring",
+	"autumn", "gym", "workout", "fitness", "muscle", "strong", "lift", "user",
+	"test", "guest", "root", "god",
+}
+
+var substitutions = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i",
+)
+
+// Strength scores password from 0 (trivial) to 4 (very strong), in the
+// spirit of zxcvbn. It estimates the entropy of the password, counting
+// common words, repeated characters and sequences as a single guess each
+// instead of per character.
+func Strength(password string) int {
+	bits := entropyBits(password)
+
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 50:
+		return 2
+	case bits < 64:
+		return 3
+	}
+	return 4
+}
+
+func entropyBits(password string) float64 {
+	runes := []rune(strings.ToLower(password))
+	if len(runes) == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(charsetSize(password)))
+	covered := make([]bool, len(runes))
+	bits := 0.0
+
+	// common words, each costs one guess out of the word list plus a bit
+	// for capitalisation
+	normalized := []rune(substitutions.Replace(string(runes)))
+	wordBits := math.Log2(float64(len(commonWords))) + 1
+	for _, word := range commonWords {
+		w := []rune(word)
+		for i := 0; i+len(w) <= len(normalized); i++ {
+			if string(normalized[i:i+len(w)]) != word || anyCovered(covered[i:i+len(w)]) {
+				continue
+			}
+			markCovered(covered[i : i+len(w)])
+			bits += wordBits
+		}
+	}
+
+	// runs of repeated (aaaa) or sequential (abcd, 4321) characters
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && isPatternStep(runes[end-1], runes[end], runes[start], runes[min(start+1, len(runes)-1)]) {
+			end++
+		}
+
+		if end-start >= 3 && !anyCovered(covered[start:end]) {
+			markCovered(covered[start:end])
+			bits += perChar + math.Log2(float64(end-start))
+		}
+		start = end
+	}
+
+	for _, c := range covered {
+		if !c {
+			bits += perChar
+		}
+	}
+
+	return bits
+}
+
+// isPatternStep reports whether b follows a in the same direction as the
+// first step of the run (first, second): repeat, ascending or descending.
+func isPatternStep(a, b, first, second rune) bool {
+	step := second - first
+	if step < -1 || step > 1 {
+		return false
+	}
+	return b-a == step
+}
+
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	return max(size, 2)
+}
+
+func anyCovered(covered []bool) bool {
+	for _, c := range covered {
+		if c {
+			return true
+		}
+	}
+	return false
+}
+
+func markCovered(covered []bool) {
+	for i := range covered {
+		covered[i] = true
+	}
+}
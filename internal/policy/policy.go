@@ -0,0 +1,48 @@
+package policy
+
+import "github.com/gbuenodev/goProject/internal/store"
+
+type Action string
+
+const (
+	ActionReadWorkout      Action = "workout:read"
+	ActionUpdateWorkout    Action = "workout:update"
+	ActionDeleteWorkout    Action = "workout:delete"
+	ActionModerateWorkout  Action = "workout:moderate"
+	ActionListUsers        Action = "user:list"
+	ActionManageUser       Action = "user:manage"
+	ActionManageExercise   Action = "exercise:manage"
+	ActionManageTemplate   Action = "template:manage"
+	ActionManageProgram    Action = "program:manage"
+	ActionManageEnrollment Action = "enrollment:manage"
+	ActionManagePlan       Action = "plan:manage"
+)
+
+// Resource describes the object an action is performed on. OwnerID is the
+// ID of the user the resource belongs to, or 0 for resources without owner.
+type Resource struct {
+	OwnerID int
+}
+
+// Can reports whether user is allowed to perform action on resource.
+// Anonymous and suspended users are never allowed to do anything.
+func Can(user *store.User, action Action, resource Resource) bool {
+	if user == nil || user.IsAnonymous() || user.IsSuspended() {
+		return false
+	}
+
+	if user.HasRole(store.RoleAdmin) {
+		return true
+	}
+
+	isOwner := resource.OwnerID != 0 && resource.OwnerID == user.ID
+
+	switch action {
+	case ActionReadWorkout, ActionUpdateWorkout, ActionDeleteWorkout, ActionManageExercise, ActionManageTemplate,
+		ActionManageProgram, ActionManageEnrollment, ActionManagePlan:
+		// built-in exercises have no owner, only admins curate them
+		return isOwner
+	}
+
+	return false
+}
@@ -0,0 +1,231 @@
+// Package programs runs training programs: it turns a program day into the
+// workout to perform and works out the progression a performed workout
+// earned.
+package programs
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
+)
+
+// Target is a prescription with the weight it calls for in kilograms, nil
+// when there is no training max to compute it from. WeightUnit is the unit
+// of the program it was rounded in.
+type Target struct {
+	store.Prescription
+	Weight     *float64   `json:"weight"`
+	WeightUnit units.Unit `json:"weight_unit"`
+}
+
+// Session is the workout prescribed for a program day, ready to be logged.
+type Session struct {
+	Week    int            `json:"week"`
+	Day     int            `json:"day"`
+	Workout *store.Workout `json:"workout"`
+	Targets []Target       `json:"targets"`
+}
+
+// Round rounds weight to the nearest multiple of increment.
+func Round(weight, increment float64) float64 {
+	if increment <= 0 {
+		return weight
+	}
+	return math.Round(weight/increment) * increment
+}
+
+// Weight returns the weight a prescription calls for given the training max
+// of its exercise. RPE prescriptions treat the training max as a one-rep max
+// and the reps left in reserve as extra reps of the Epley formula.
+func Weight(p store.Prescription, trainingMax, rounding float64) (float64, bool) {
+	if trainingMax <= 0 {
+		return 0, false
+	}
+
+	switch {
+	case p.PercentOneRepMax != nil:
+		return Round(trainingMax**p.PercentOneRepMax/100, rounding), true
+	case p.RPE != nil:
+		repsInReserve := 10 - *p.RPE
+		return Round(trainingMax/(1+(float64(p.Reps)+repsInReserve)/30), rounding), true
+	}
+	return 0, false
+}
+
+// FindDay returns the day of days at week and day.
+func FindDay(days []store.ProgramDay, week, day int) (*store.ProgramDay, bool) {
+	for i := range days {
+		if days[i].Week == week && days[i].Day == day {
+			return &days[i], true
+		}
+	}
+	return nil, false
+}
+
+// NextDay returns the first day of days after week and day. days must be in
+// (week, day) order.
+func NextDay(days []store.ProgramDay, week, day int) (*store.ProgramDay, bool) {
+	for i := range days {
+		if days[i].Week > week || (days[i].Week == week && days[i].Day > day) {
+			return &days[i], true
+		}
+	}
+	return nil, false
+}
+
+// Prescribe builds the session of a program day for userID. The template
+// entries of a prescribed exercise are replaced by one entry per
+// prescription, at the place of the first of them; the other entries are
+// kept as they are. Weights are rounded to rounding in unit, the unit of the
+// program, so they land on the plates it was written for.
+func Prescribe(day *store.ProgramDay, template *store.WorkoutTemplate, trainingMaxes []store.TrainingMax, rounding float64, unit units.Unit, userID int) *Session {
+	maxes := map[int64]float64{}
+	for _, tm := range trainingMaxes {
+		maxes[tm.ExerciseID] = units.FromKilograms(tm.Weight, unit)
+	}
+
+	session := &Session{
+		Week:    day.Week,
+		Day:     day.Day,
+		Workout: template.NewWorkout(userID),
+		Targets: make([]Target, len(day.Prescriptions)),
+	}
+
+	byExercise := map[int64][]Target{}
+	for i, p := range day.Prescriptions {
+		target := Target{Prescription: p, WeightUnit: unit}
+		if weight, ok := Weight(p, maxes[p.ExerciseID], rounding); ok {
+			weight = units.ToKilograms(weight, unit)
+			target.Weight = &weight
+		}
+		session.Targets[i] = target
+		byExercise[p.ExerciseID] = append(byExercise[p.ExerciseID], target)
+	}
+
+	entries := []store.WorkoutEntry{}
+	for _, entry := range session.Workout.Entries {
+		if entry.ExerciseID == nil {
+			entries = append(entries, entry)
+			continue
+		}
+
+		targets, ok := byExercise[*entry.ExerciseID]
+		if !ok {
+			entries = append(entries, entry)
+			continue
+		}
+		delete(byExercise, *entry.ExerciseID)
+
+		for _, target := range targets {
+			prescribed := entry
+			prescribed.Sets = target.Sets
+			prescribed.Reps = &target.Reps
+			prescribed.DurationSeconds = nil
+			if target.Weight != nil {
+				prescribed.Weight = target.Weight
+				prescribed.WeightUnit = unit
+			}
+			if target.AMRAP {
+				prescribed.Notes = strings.TrimSpace(prescribed.Notes + " AMRAP on the last set")
+			}
+			entries = append(entries, prescribed)
+		}
+	}
+	for i := range entries {
+		entries[i].OrderIndex = i + 1
+	}
+	session.Workout.Entries = entries
+
+	return session
+}
+
+// Progress returns the training max increments a workout earned: the
+// increment of each progression rule whose exercise met all its targets.
+func Progress(targets []Target, rules []store.ProgressionRule, workout *store.Workout) []store.TrainingMax {
+	increments := []store.TrainingMax{}
+	for _, rule := range rules {
+		var exerciseTargets []Target
+		for _, target := range targets {
+			if target.ExerciseID == rule.ExerciseID {
+				exerciseTargets = append(exerciseTargets, target)
+			}
+		}
+
+		if len(exerciseTargets) > 0 && met(exerciseTargets, workout.Entries, rule.ExerciseID) {
+			increments = append(increments, store.TrainingMax{ExerciseID: rule.ExerciseID, Weight: rule.Increment})
+		}
+	}
+	return increments
+}
+
+type performedSets struct {
+	count  int
+	reps   int
+	weight float64
+}
+
+// met reports whether entries hold enough sets of the exercise to cover
+// every target. Targets are covered heaviest first by the lightest sets that
+// qualify, so that heavy sets are kept for the targets that need them.
+// Entries with a set log count the sets that count as performed.
+func met(targets []Target, entries []store.WorkoutEntry, exerciseID int64) bool {
+	var performed []performedSets
+	add := func(count int, reps *int, weight *float64) {
+		if reps == nil {
+			return
+		}
+		sets := performedSets{count: count, reps: *reps}
+		if weight != nil {
+			sets.weight = *weight
+		}
+		performed = append(performed, sets)
+	}
+	for _, entry := range entries {
+		if entry.ExerciseID == nil || *entry.ExerciseID != exerciseID {
+			continue
+		}
+		if len(entry.SetDetails) == 0 {
+			add(max(entry.Sets, 1), entry.Reps, entry.Weight)
+			continue
+		}
+		for _, set := range entry.SetDetails {
+			if set.Counts() {
+				add(1, set.Reps, set.Weight)
+			}
+		}
+	}
+	slices.SortFunc(performed, func(a, b performedSets) int { return cmp.Compare(a.weight, b.weight) })
+
+	targetWeight := func(t Target) float64 {
+		if t.Weight == nil || t.RPE != nil {
+			// RPE weights are a suggestion, only the reps count
+			return 0
+		}
+		return *t.Weight
+	}
+	targets = slices.Clone(targets)
+	slices.SortFunc(targets, func(a, b Target) int { return cmp.Compare(targetWeight(b), targetWeight(a)) })
+
+	for _, target := range targets {
+		needed := target.Sets
+		for i := range performed {
+			if needed == 0 {
+				break
+			}
+			if performed[i].reps < target.Reps || performed[i].weight < targetWeight(target)-1e-9 {
+				continue
+			}
+			taken := min(needed, performed[i].count)
+			performed[i].count -= taken
+			needed -= taken
+		}
+		if needed > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, use
+// a shared Store when running several instances behind a load balancer.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]Bucket
+	limits    map[string]Limit
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]Bucket),
+		limits:    make(map[string]Limit),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (m *MemoryStore) Take(key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = NewBucket(limit, now)
+	}
+
+	bucket, result := bucket.Take(limit, now)
+	m.buckets[key] = bucket
+	m.limits[key] = limit
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, they behave exactly
+// like a missing bucket.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, bucket := range m.buckets {
+		limit := m.limits[key]
+		refilled := bucket.Tokens + now.Sub(bucket.UpdatedAt).Seconds()*limit.Rate
+		if refilled >= float64(limit.Burst) {
+			delete(m.buckets, key)
+			delete(m.limits, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit configures a token bucket: Burst tokens at most, refilled at Rate
+// tokens per second. Every request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func PerMinute(requests, burst int) Limit {
+	return Limit{Rate: float64(requests) / 60, Burst: burst}
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, zero when
+	// the request was allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps the buckets. Take consumes a token from the bucket for key,
+// creating a full one if it does not exist yet.
+type Store interface {
+	Take(key string, limit Limit) (Result, error)
+}
+
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time elapsed since it was last updated and
+// tries to consume one token. It returns the updated bucket, which callers
+// must persist whether or not the request was allowed.
+func (b Bucket) Take(limit Limit, now time.Time) (Bucket, Result) {
+	burst := float64(limit.Burst)
+
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*limit.Rate)
+	}
+	b.UpdatedAt = now
+
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.Tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.ResetAfter = secondsToDuration((burst - b.Tokens) / limit.Rate)
+
+	return b, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,143 @@
+// Package revisions compares and restores workout revisions.
+package revisions
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+
+	"github.com/gbuenodev/goProject/internal/store"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type EntryChange struct {
+	ID      int           `json:"id"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type Diff struct {
+	FromVersion    int                  `json:"from_version"`
+	ToVersion      int                  `json:"to_version"`
+	Changes        []FieldChange        `json:"changes"`
+	AddedEntries   []store.WorkoutEntry `json:"added_entries"`
+	RemovedEntries []store.WorkoutEntry `json:"removed_entries"`
+	ChangedEntries []EntryChange        `json:"changed_entries"`
+}
+
+// fields that identify or track a workout or entry rather than describe it
+var (
+	workoutIdentityFields = []string{"id", "user_id", "version", "created_at", "updated_at", "deleted_at", "entries"}
+	entryIdentityFields   = []string{"id"}
+)
+
+// Compare returns what changed between two snapshots of the same workout.
+// Entries are matched by ID, so a moved entry shows up as an order_index
+// change rather than a removal and an addition.
+func Compare(from, to *store.Workout) Diff {
+	diff := Diff{
+		FromVersion:    from.Version,
+		ToVersion:      to.Version,
+		Changes:        fieldChanges(from, to, workoutIdentityFields),
+		AddedEntries:   []store.WorkoutEntry{},
+		RemovedEntries: []store.WorkoutEntry{},
+		ChangedEntries: []EntryChange{},
+	}
+
+	fromEntries := make(map[int]store.WorkoutEntry, len(from.Entries))
+	for _, entry := range from.Entries {
+		fromEntries[entry.ID] = entry
+	}
+
+	toIDs := make(map[int]bool, len(to.Entries))
+	for _, entry := range to.Entries {
+		toIDs[entry.ID] = true
+
+		previous, ok := fromEntries[entry.ID]
+		if !ok {
+			diff.AddedEntries = append(diff.AddedEntries, entry)
+			continue
+		}
+
+		changes := fieldChanges(previous, entry, entryIdentityFields)
+		if len(changes) > 0 {
+			diff.ChangedEntries = append(diff.ChangedEntries, EntryChange{ID: entry.ID, Changes: changes})
+		}
+	}
+
+	for _, entry := range from.Entries {
+		if !toIDs[entry.ID] {
+			diff.RemovedEntries = append(diff.RemovedEntries, entry)
+		}
+	}
+
+	return diff
+}
+
+// Restore returns the workout to save in order to bring current back to
+// past. Entries that still exist keep their IDs, the ones deleted since are
+// recreated.
+func Restore(current, past *store.Workout) *store.Workout {
+	restored := *past
+	restored.ID = current.ID
+	restored.UserID = current.UserID
+	restored.Version = current.Version
+	restored.DeletedAt = nil
+
+	currentIDs := make(map[int]bool, len(current.Entries))
+	for _, entry := range current.Entries {
+		currentIDs[entry.ID] = true
+	}
+
+	restored.Entries = make([]store.WorkoutEntry, len(past.Entries))
+	for i, entry := range past.Entries {
+		if !currentIDs[entry.ID] {
+			entry.ID = 0
+		}
+		if entry.SetDetails == nil {
+			// clears a set log added since, rather than keeping it
+			entry.SetDetails = []store.WorkoutSet{}
+		}
+		restored.Entries[i] = entry
+	}
+
+	return &restored
+}
+
+// fieldChanges compares the JSON fields of a and b, in field name order.
+func fieldChanges(a, b any, skip []string) []FieldChange {
+	fromFields, toFields := jsonFields(a), jsonFields(b)
+
+	var names []string
+	for name := range fromFields {
+		names = append(names, name)
+	}
+	for name := range toFields {
+		if _, ok := fromFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	changes := []FieldChange{}
+	for _, name := range names {
+		if slices.Contains(skip, name) {
+			continue
+		}
+		if !reflect.DeepEqual(fromFields[name], toFields[name]) {
+			changes = append(changes, FieldChange{Field: name, From: fromFields[name], To: toFields[name]})
+		}
+	}
+	return changes
+}
+
+func jsonFields(v any) map[string]any {
+	data, _ := json.Marshal(v)
+	fields := map[string]any{}
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -2,30 +2,133 @@ package routes
 
 import (
 	"github.com/gbuenodev/goProject/internal/app"
+	"github.com/gbuenodev/goProject/internal/ratelimit"
+	"github.com/gbuenodev/goProject/internal/store"
 	"github.com/go-chi/chi/v5"
 )
 
+var (
+	// authenticated requests, keyed by user
+	apiLimit          = ratelimit.PerMinute(300, 60)
+	workoutWriteLimit = ratelimit.PerMinute(30, 10)
+	// anonymous requests, keyed by client IP
+	publicLimit = ratelimit.PerMinute(20, 10)
+)
+
 func Routes(app *app.App) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Group(func(r chi.Router) {
 		r.Use(app.Middleware.Auth)
+		r.Use(app.RateLimiter.Limit("api", apiLimit))
 
 		// AUTHENTICATED ROUTES
 		// WORKOUT ROUTES
+		r.Get("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkouts))
+		r.Get("/workouts/trash", app.Middleware.RequireUser(app.WorkoutHandler.HandleListTrash))
 		r.Get("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleGetWorkoutByID))
-		r.Post("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkout))
-		r.Put("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateWorkoutByID))
-		r.Delete("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteWorkoutByID))
+		r.Get("/workouts/{id}/entries", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkoutEntries))
+		r.Get("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.WorkoutHandler.HandleGetWorkoutEntry))
+		r.Get("/workouts/{id}/revisions", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkoutRevisions))
+		r.Get("/workouts/{id}/revisions/diff", app.Middleware.RequireUser(app.WorkoutHandler.HandleDiffWorkoutRevisions))
+		r.Get("/workouts/{id}/revisions/{version}", app.Middleware.RequireUser(app.WorkoutHandler.HandleGetWorkoutRevision))
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.RateLimiter.Limit("workouts-write", workoutWriteLimit))
+
+			r.Post("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkout))
+			r.Post("/workouts/from-template/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkoutFromTemplate))
+			r.Put("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateWorkoutByID))
+			r.Patch("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandlePatchWorkoutByID))
+			r.Delete("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteWorkoutByID))
+			r.Post("/workouts/{id}/restore", app.Middleware.RequireUser(app.WorkoutHandler.HandleRestoreWorkout))
+			r.Post("/workouts/{id}/template", app.Middleware.RequireUser(app.WorkoutHandler.HandleSaveWorkoutAsTemplate))
+			r.Post("/workouts/{id}/revisions/{version}/restore", app.Middleware.RequireUser(app.WorkoutHandler.HandleRestoreWorkoutRevision))
+			r.Post("/workouts/{id}/entries", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkoutEntry))
+			r.Put("/workouts/{id}/entries/order", app.Middleware.RequireUser(app.WorkoutHandler.HandleReorderWorkoutEntries))
+			r.Put("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateWorkoutEntry))
+			r.Delete("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteWorkoutEntry))
+		})
+
+		// EXERCISE ROUTES
+		r.Get("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleListExercises))
+		r.Post("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleCreateExercise))
+		r.Get("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleGetExercise))
+		r.Put("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleUpdateExercise))
+		r.Delete("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleDeleteExercise))
+
+		// TEMPLATE ROUTES
+		r.Get("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleListTemplates))
+		r.Post("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleCreateTemplate))
+		r.Get("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleGetTemplate))
+		r.Put("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleUpdateTemplate))
+		r.Delete("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleDeleteTemplate))
+
+		// PROGRAM ROUTES
+		r.Get("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleListPrograms))
+		r.Post("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleCreateProgram))
+		r.Get("/programs/enrollments", app.Middleware.RequireUser(app.ProgramHandler.HandleListEnrollments))
+		r.Delete("/programs/enrollments/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleDeleteEnrollment))
+		r.Get("/programs/enrollments/{id}/next", app.Middleware.RequireUser(app.ProgramHandler.HandleGetNextSession))
+		r.Post("/programs/enrollments/{id}/complete", app.Middleware.RequireUser(app.ProgramHandler.HandleCompleteSession))
+		r.Get("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleGetProgram))
+		r.Put("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleUpdateProgram))
+		r.Delete("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleDeleteProgram))
+		r.Post("/programs/{id}/enrollments", app.Middleware.RequireUser(app.ProgramHandler.HandleEnroll))
+
+		// CALENDAR ROUTES
+		r.Get("/calendar", app.Middleware.RequireUser(app.CalendarHandler.HandleGetCalendar))
+		r.Post("/calendar/feed-token", app.Middleware.RequireUser(app.CalendarHandler.HandleCreateFeedToken))
+		r.Delete("/calendar/feed-token", app.Middleware.RequireUser(app.CalendarHandler.HandleRevokeFeedToken))
+		r.Get("/planned-workouts", app.Middleware.RequireUser(app.CalendarHandler.HandleListPlannedWorkouts))
+		r.Post("/planned-workouts", app.Middleware.RequireUser(app.CalendarHandler.HandleCreatePlannedWorkout))
+		r.Get("/planned-workouts/{id}", app.Middleware.RequireUser(app.CalendarHandler.HandleGetPlannedWorkout))
+		r.Put("/planned-workouts/{id}", app.Middleware.RequireUser(app.CalendarHandler.HandleUpdatePlannedWorkout))
+		r.Delete("/planned-workouts/{id}", app.Middleware.RequireUser(app.CalendarHandler.HandleDeletePlannedWorkout))
+		r.Post("/planned-workouts/{id}/completions", app.Middleware.RequireUser(app.CalendarHandler.HandleCompletePlannedWorkout))
+		r.Delete("/planned-workouts/{id}/completions/{date}", app.Middleware.RequireUser(app.CalendarHandler.HandleUncompletePlannedWorkout))
 
+		// USER ROUTES
+		r.Get("/users/me", app.Middleware.RequireUser(app.UserHandler.HandleGetCurrentUser))
+		r.Patch("/users/me", app.Middleware.RequireUser(app.UserHandler.HandleUpdateCurrentUser))
+		r.Get("/users/me/records", app.Middleware.RequireUser(app.RecordHandler.HandleListRecords))
+		r.Get("/users/me/progression", app.Middleware.RequireUser(app.AnalyticsHandler.HandleGetProgression))
+		r.Get("/users/me/stats", app.Middleware.RequireUser(app.AnalyticsHandler.HandleGetStats))
+
+		// MFA ROUTES
+		r.Post("/users/me/mfa/totp", app.Middleware.RequireUser(app.MFAHandler.HandleEnrollTOTP))
+		r.Post("/users/me/mfa/totp/confirm", app.Middleware.RequireUser(app.MFAHandler.HandleConfirmTOTP))
+		r.Delete("/users/me/mfa/totp", app.Middleware.RequireUser(app.MFAHandler.HandleDisableTOTP))
+
+		// ADMIN ROUTES
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequireRole(store.RoleAdmin))
+
+			r.Get("/admin/users", app.AdminHandler.HandleListUsers)
+			r.Post("/admin/users/{id}/suspend", app.AdminHandler.HandleSuspendUser)
+			r.Delete("/admin/users/{id}/suspend", app.AdminHandler.HandleUnsuspendUser)
+			r.Put("/admin/users/{id}/role", app.AdminHandler.HandleSetUserRole)
+			r.Delete("/admin/workouts/{id}", app.AdminHandler.HandleModerateWorkout)
+		})
 	})
 
 	// HEALTH CHECK
 	r.Get("/health", app.HealthCheck)
 
-	// USER ROUTES
-	r.Post("/users/register", app.UserHandler.HandleRegisterUser)
-	r.Post("/auth", app.TokenHandler.HandleCreateToken)
+	r.Group(func(r chi.Router) {
+		r.Use(app.RateLimiter.Limit("public", publicLimit))
+
+		// USER ROUTES
+		r.Post("/users/register", app.UserHandler.HandleRegisterUser)
+		r.Post("/auth", app.TokenHandler.HandleCreateToken)
+		r.Post("/auth/mfa", app.TokenHandler.HandleVerifyMFA)
+		r.Get("/auth/oidc", app.OIDCHandler.HandleListProviders)
+		r.Get("/auth/oidc/{provider}", app.OIDCHandler.HandleLogin)
+		r.Get("/auth/oidc/{provider}/callback", app.OIDCHandler.HandleCallback)
+
+		// CALENDAR ROUTES
+		r.Get("/calendar/feed/{token}.ics", app.CalendarHandler.HandleGetFeed)
+	})
 
 	return r
 }
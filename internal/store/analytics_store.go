@@ -0,0 +1,76 @@
+package store
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/strength"
+)
+
+// Buckets are the periods analytics group workouts by, named after the
+// date_trunc fields they map to.
+var Buckets = []string{"day", "week", "month"}
+
+func IsValidBucket(bucket string) bool {
+	return slices.Contains(Buckets, bucket)
+}
+
+// ProgressionQuery selects the sets of one exercise performed in [From, To).
+// Entries linked to the catalog are matched by ExerciseID, free-text entries
+// by ExerciseName, ignoring case and punctuation.
+type ProgressionQuery struct {
+	UserID       int
+	ExerciseID   *int64
+	ExerciseName string
+	From         time.Time
+	To           time.Time
+	// Timezone is the IANA timezone buckets start at midnight in.
+	Timezone string
+	Bucket   string
+	Formula  strength.Formula
+}
+
+// ProgressionPoint sums up an exercise over one bucket. EstimatedOneRepMax
+// is the best estimate among the sets of at most strength.MaxEstimateReps
+// reps, nil when there were none.
+type ProgressionPoint struct {
+	Bucket             string   `json:"bucket"`
+	EstimatedOneRepMax *float64 `json:"estimated_1rm"`
+	TopSetWeight       float64  `json:"top_set_weight"`
+	TopSetReps         int      `json:"top_set_reps"`
+	Volume             float64  `json:"volume"`
+	Sets               int      `json:"sets"`
+	Workouts           int      `json:"workouts"`
+}
+
+// TrainingStats sums up the workouts a user performed in [From, To).
+// Sets and MuscleGroupSets only count hard sets, working and failure sets:
+// a drop set extends the set before it, so it adds to Tonnage but isn't a
+// set of its own. MuscleGroupSets counts the sets of entries linked to the
+// catalog, a full set for each primary muscle of the exercise and half a
+// set for each secondary one.
+type TrainingStats struct {
+	From               time.Time          `json:"from"`
+	To                 time.Time          `json:"to"`
+	Workouts           int                `json:"workouts"`
+	TrainingDays       int                `json:"training_days"`
+	DurationMinutes    int                `json:"duration_minutes"`
+	CaloriesBurned     int                `json:"calories_burned"`
+	Sets               int                `json:"sets"`
+	Tonnage            float64            `json:"tonnage"`
+	MuscleGroupSets    map[string]float64 `json:"muscle_group_sets"`
+	WorkoutsPerWeek    float64            `json:"workouts_per_week"`
+	AvgDurationMinutes float64            `json:"avg_duration_minutes"`
+	AvgSetsPerWorkout  float64            `json:"avg_sets_per_workout"`
+}
+
+// AnalyticsStore counts the completed sets of entries logged set by set,
+// warm-ups aside, and the other entries as logged. Weights are in
+// kilograms.
+type AnalyticsStore interface {
+	// Progression returns one point per bucket the exercise was performed
+	// in, oldest first.
+	Progression(query ProgressionQuery) ([]ProgressionPoint, error)
+	// TrainingStats counts training days in timezone.
+	TrainingStats(userID int, from, to time.Time, timezone string) (*TrainingStats, error)
+}
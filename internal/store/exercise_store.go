@@ -0,0 +1,67 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrDuplicateExercise is returned when an exercise name is already taken in
+// the library it is added to.
+var ErrDuplicateExercise = errors.New("store: exercise already exists")
+
+var (
+	MuscleGroups = []string{
+		"chest", "back", "lats", "traps", "shoulders", "biceps", "triceps", "forearms",
+		"abs", "obliques", "lower_back", "glutes", "quads", "hamstrings", "calves", "adductors",
+		"full_body",
+	}
+	Equipment        = []string{"barbell", "dumbbell", "kettlebell", "machine", "cable", "bodyweight", "band", "other"}
+	MovementPatterns = []string{
+		"horizontal_push", "vertical_push", "horizontal_pull", "vertical_pull",
+		"squat", "hinge", "lunge", "carry", "core", "isolation", "cardio", "other",
+	}
+)
+
+// Exercise is an entry of the exercise catalog. Built-in exercises have no
+// owner, custom ones belong to the user who created them and are only
+// visible to that user.
+type Exercise struct {
+	ID               int64     `json:"id"`
+	OwnerID          *int      `json:"owner_id"`
+	Name             string    `json:"name"`
+	Aliases          []string  `json:"aliases"`
+	PrimaryMuscles   []string  `json:"primary_muscles"`
+	SecondaryMuscles []string  `json:"secondary_muscles"`
+	Equipment        string    `json:"equipment"`
+	MovementPattern  string    `json:"movement_pattern"`
+	Unilateral       bool      `json:"unilateral"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (e *Exercise) IsBuiltIn() bool {
+	return e.OwnerID == nil
+}
+
+func IsValidMuscleGroup(muscle string) bool {
+	return slices.Contains(MuscleGroups, muscle)
+}
+
+type ExerciseFilter struct {
+	// Search matches names and aliases, ignoring case and punctuation.
+	Search string
+	Muscle string
+	Limit  int
+	Offset int
+}
+
+type ExerciseStore interface {
+	// ListExercises returns the built-in exercises and the custom ones of
+	// userID.
+	ListExercises(userID int, filter ExerciseFilter) ([]Exercise, error)
+	GetExercise(id int64, userID int) (*Exercise, error)
+	CreateExercise(exercise *Exercise) error
+	UpdateExercise(exercise *Exercise) error
+	DeleteExercise(id int64) error
+}
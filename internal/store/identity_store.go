@@ -0,0 +1,30 @@
+package store
+
+import "time"
+
+// UserIdentity links a user to an account at an external identity provider.
+type UserIdentity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCAuthRequest holds the per-login secrets between the redirect to the
+// identity provider and the callback.
+type OIDCAuthRequest struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type IdentityStore interface {
+	CreateAuthRequest(req *OIDCAuthRequest) error
+	ConsumeAuthRequest(state string) (*OIDCAuthRequest, error)
+	GetUserByIdentity(provider, subject string) (*User, error)
+	LinkIdentity(identity *UserIdentity) error
+}
@@ -0,0 +1,41 @@
+package store
+
+import "time"
+
+// LoginAttempt tracks consecutive failed logins for a single key, which is
+// either a username or a client IP address.
+type LoginAttempt struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// LockoutEvent is the audit record written whenever a key gets locked.
+type LockoutEvent struct {
+	ID          int       `json:"id"`
+	Key         string    `json:"key"`
+	Username    string    `json:"username"`
+	IPAddress   string    `json:"ip_address"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LoginBackoff is how long a key has to wait after a failure: BaseDelay
+// doubles on every consecutive failure, up to MaxDelay. Failures older than
+// Window no longer count.
+type LoginBackoff struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+type LoginAttemptStore interface {
+	GetLoginAttempts(keys ...string) ([]LoginAttempt, error)
+	TakeLoginAttempts(keys []string, backoff LoginBackoff) ([]LoginAttempt, error)
+	ReleaseLoginAttempts(keys ...string) error
+	LockLogin(key string, until time.Time) error
+	ResetLoginAttempts(keys ...string) error
+	CreateLockoutEvent(event *LockoutEvent) error
+}
@@ -0,0 +1,25 @@
+package store
+
+import "time"
+
+type TOTPSecret struct {
+	UserID       int
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
+
+// IsConfirmed reports whether enrollment finished, only then is the second
+// factor required on login.
+func (t *TOTPSecret) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+type MFAStore interface {
+	GetTOTPSecret(userID int) (*TOTPSecret, error)
+	SaveTOTPSecret(userID int, secret string) error
+	ConfirmTOTPSecret(userID int, step int64, recoveryCodeHashes [][]byte) error
+	UseTOTPStep(userID int, step int64) (bool, error)
+	DeleteTOTPSecret(userID int) error
+	UseRecoveryCode(userID int, codeHash []byte) (bool, error)
+}
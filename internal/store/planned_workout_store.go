@@ -0,0 +1,38 @@
+package store
+
+import "time"
+
+// PlannedWorkout is a workout scheduled at ScheduledAt and, with an RRule,
+// repeated from there on. Completions link occurrences, by their date in
+// Timezone, to the workouts that performed them.
+type PlannedWorkout struct {
+	ID              int64        `json:"id"`
+	UserID          int          `json:"user_id"`
+	Title           string       `json:"title"`
+	Notes           string       `json:"notes"`
+	TemplateID      *int64       `json:"template_id"`
+	ScheduledAt     time.Time    `json:"scheduled_at"`
+	Timezone        string       `json:"timezone"`
+	DurationMinutes int          `json:"duration_minutes"`
+	RRule           string       `json:"rrule"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	Completions     []Completion `json:"completions"`
+}
+
+type Completion struct {
+	Date      string `json:"date"`
+	WorkoutID int64  `json:"workout_id"`
+}
+
+type PlannedWorkoutStore interface {
+	ListPlannedWorkouts(userID int) ([]PlannedWorkout, error)
+	GetPlannedWorkout(id int64) (*PlannedWorkout, error)
+	CreatePlannedWorkout(plan *PlannedWorkout) error
+	UpdatePlannedWorkout(plan *PlannedWorkout) error
+	DeletePlannedWorkout(id int64) error
+	// CompletePlannedWorkout links the occurrence on date to workoutID,
+	// replacing the workout linked before.
+	CompletePlannedWorkout(id int64, date time.Time, workoutID int64) error
+	UncompletePlannedWorkout(id int64, date time.Time) error
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/strength"
+)
+
+type PostgresAnalyticsStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresAnalyticsStore(DBConn *sql.DB) *PostgresAnalyticsStore {
+	return &PostgresAnalyticsStore{DBConn: DBConn}
+}
+
+func (pg *PostgresAnalyticsStore) Progression(q ProgressionQuery) ([]ProgressionPoint, error) {
+	// the formulas mirror strength.EstimateOneRepMax, a performed set row
+	// stands for sets identical sets
+	query := `
+	WITH performed AS (
+		SELECT
+			date_trunc($2, w.performed_at AT TIME ZONE $3) AS bucket,
+			w.id AS workout_id,
+			GREATEST(we.sets, 1) AS sets,
+			we.reps,
+			we.weight,
+			CASE
+				WHEN we.reps = 1 THEN we.weight
+				WHEN we.reps > $9 THEN NULL
+				WHEN $8 = 'brzycki' THEN we.weight * 36 / (37 - we.reps)
+				ELSE we.weight * (1 + we.reps / 30.0)
+			END AS estimated_1rm
+		FROM performed_sets we
+		JOIN workouts w ON w.id = we.workout_id AND w.deleted_at IS NULL
+		WHERE w.user_id = $1
+			AND w.performed_at >= $4 AND w.performed_at < $5
+			AND we.reps >= 1 AND we.weight > 0
+			AND CASE
+				WHEN $6::bigint IS NOT NULL THEN we.exercise_id = $6
+				ELSE we.exercise_id IS NULL AND normalize_exercise_name(we.exercise_name) = normalize_exercise_name($7)
+			END
+	)
+	SELECT
+		to_char(bucket, 'YYYY-MM-DD'),
+		round(MAX(estimated_1rm), 2),
+		(array_agg(weight ORDER BY weight DESC, reps DESC))[1],
+		(array_agg(reps ORDER BY weight DESC, reps DESC))[1],
+		SUM(sets * reps * weight),
+		SUM(sets),
+		COUNT(DISTINCT workout_id)
+	FROM performed
+	GROUP BY bucket
+	ORDER BY bucket
+	`
+
+	rows, err := pg.DBConn.Query(query,
+		q.UserID, q.Bucket, q.Timezone, q.From, q.To,
+		q.ExerciseID, q.ExerciseName, string(q.Formula), strength.MaxEstimateReps,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []ProgressionPoint{}
+	for rows.Next() {
+		var point ProgressionPoint
+		err = rows.Scan(
+			&point.Bucket,
+			&point.EstimatedOneRepMax,
+			&point.TopSetWeight,
+			&point.TopSetReps,
+			&point.Volume,
+			&point.Sets,
+			&point.Workouts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+func (pg *PostgresAnalyticsStore) TrainingStats(userID int, from, to time.Time, timezone string) (*TrainingStats, error) {
+	stats := &TrainingStats{
+		From:            from,
+		To:              to,
+		MuscleGroupSets: map[string]float64{},
+	}
+
+	query := `
+	SELECT
+		COUNT(*),
+		COUNT(DISTINCT (w.performed_at AT TIME ZONE $4)::date),
+		COALESCE(SUM(w.duration_minutes), 0),
+		COALESCE(SUM(w.calories_burned), 0)
+	FROM workouts w
+	WHERE w.user_id = $1 AND w.deleted_at IS NULL
+		AND w.performed_at >= $2 AND w.performed_at < $3
+	`
+
+	err := pg.DBConn.QueryRow(query, userID, from, to, timezone).Scan(
+		&stats.Workouts,
+		&stats.TrainingDays,
+		&stats.DurationMinutes,
+		&stats.CaloriesBurned,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT
+		COALESCE(SUM(we.sets) FILTER (WHERE we.set_type <> 'drop'), 0),
+		COALESCE(SUM(GREATEST(we.sets, 1) * we.reps * we.weight) FILTER (WHERE we.reps > 0 AND we.weight > 0), 0)
+	FROM performed_sets we
+	JOIN workouts w ON w.id = we.workout_id AND w.deleted_at IS NULL
+	WHERE w.user_id = $1 AND w.performed_at >= $2 AND w.performed_at < $3
+	`
+
+	err = pg.DBConn.QueryRow(query, userID, from, to).Scan(&stats.Sets, &stats.Tonnage)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT m.muscle, SUM(we.sets * m.share)
+	FROM performed_sets we
+	JOIN workouts w ON w.id = we.workout_id AND w.deleted_at IS NULL
+	JOIN exercises x ON x.id = we.exercise_id
+	CROSS JOIN LATERAL (
+		SELECT unnest(x.primary_muscles) AS muscle, 1.0 AS share
+		UNION ALL
+		SELECT unnest(x.secondary_muscles), 0.5
+	) AS m
+	WHERE w.user_id = $1 AND w.performed_at >= $2 AND w.performed_at < $3
+		AND we.set_type <> 'drop'
+	GROUP BY m.muscle
+	`
+
+	rows, err := pg.DBConn.Query(query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var muscle string
+		var sets float64
+		err = rows.Scan(&muscle, &sets)
+		if err != nil {
+			return nil, err
+		}
+		stats.MuscleGroupSets[muscle] = sets
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	weeks := to.Sub(from).Hours() / (24 * 7)
+	if weeks > 0 {
+		stats.WorkoutsPerWeek = roundTo(float64(stats.Workouts)/weeks, 2)
+	}
+	if stats.Workouts > 0 {
+		stats.AvgDurationMinutes = roundTo(float64(stats.DurationMinutes)/float64(stats.Workouts), 2)
+		stats.AvgSetsPerWorkout = roundTo(float64(stats.Sets)/float64(stats.Workouts), 2)
+	}
+
+	return stats, nil
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+)
+
+type PostgresExerciseStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresExerciseStore(DBConn *sql.DB) *PostgresExerciseStore {
+	return &PostgresExerciseStore{DBConn: DBConn}
+}
+
+const exerciseColumns = `x.id, x.owner_id, x.name, x.aliases, x.primary_muscles, x.secondary_muscles,
+	x.equipment, x.movement_pattern, x.unilateral, x.created_at, x.updated_at`
+
+func scanExercise(row rowScanner) (*Exercise, error) {
+	exercise := &Exercise{}
+	var aliases, primaryMuscles, secondaryMuscles pgtype.TextArray
+	err := row.Scan(
+		&exercise.ID,
+		&exercise.OwnerID,
+		&exercise.Name,
+		&aliases,
+		&primaryMuscles,
+		&secondaryMuscles,
+		&exercise.Equipment,
+		&exercise.MovementPattern,
+		&exercise.Unilateral,
+		&exercise.CreatedAt,
+		&exercise.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, array := range []struct {
+		src *pgtype.TextArray
+		dst *[]string
+	}{
+		{&aliases, &exercise.Aliases},
+		{&primaryMuscles, &exercise.PrimaryMuscles},
+		{&secondaryMuscles, &exercise.SecondaryMuscles},
+	} {
+		*array.dst = []string{}
+		err = array.src.AssignTo(array.dst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return exercise, nil
+}
+
+func (pg *PostgresExerciseStore) ListExercises(userID int, filter ExerciseFilter) ([]Exercise, error) {
+	query := `
+	SELECT ` + exerciseColumns + `
+	FROM exercises x
+	WHERE (x.owner_id IS NULL OR x.owner_id = $1)
+		AND ($2 = '' OR normalize_exercise_name(x.name) LIKE '%' || normalize_exercise_name($2) || '%'
+			OR EXISTS (
+				SELECT 1 FROM unnest(x.aliases) AS a
+				WHERE normalize_exercise_name(a) LIKE '%' || normalize_exercise_name($2) || '%'
+			))
+		AND ($3 = '' OR $3 = ANY(x.primary_muscles) OR $3 = ANY(x.secondary_muscles))
+	ORDER BY x.name, x.id
+	LIMIT $4 OFFSET $5
+	`
+
+	rows, err := pg.DBConn.Query(query, userID, filter.Search, filter.Muscle, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []Exercise{}
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, *exercise)
+	}
+
+	return exercises, rows.Err()
+}
+
+// GetExercise returns the exercise if it is visible to userID, that is if
+// it is built in or belongs to them.
+func (pg *PostgresExerciseStore) GetExercise(id int64, userID int) (*Exercise, error) {
+	query := `
+	SELECT ` + exerciseColumns + `
+	FROM exercises x
+	WHERE x.id = $1 AND (x.owner_id IS NULL OR x.owner_id = $2)
+	`
+
+	return scanExercise(pg.DBConn.QueryRow(query, id, userID))
+}
+
+func (pg *PostgresExerciseStore) CreateExercise(exercise *Exercise) error {
+	query := `
+	INSERT INTO exercises (owner_id, name, aliases, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at
+	`
+
+	err := pg.DBConn.QueryRow(query,
+		exercise.OwnerID, exercise.Name, exercise.Aliases, exercise.PrimaryMuscles, exercise.SecondaryMuscles,
+		exercise.Equipment, exercise.MovementPattern, exercise.Unilateral,
+	).Scan(&exercise.ID, &exercise.CreatedAt, &exercise.UpdatedAt)
+	return uniqueViolation(err)
+}
+
+func (pg *PostgresExerciseStore) UpdateExercise(exercise *Exercise) error {
+	query := `
+	UPDATE exercises
+	SET name = $1, aliases = $2, primary_muscles = $3, secondary_muscles = $4,
+		equipment = $5, movement_pattern = $6, unilateral = $7, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $8
+	RETURNING updated_at
+	`
+
+	err := pg.DBConn.QueryRow(query,
+		exercise.Name, exercise.Aliases, exercise.PrimaryMuscles, exercise.SecondaryMuscles,
+		exercise.Equipment, exercise.MovementPattern, exercise.Unilateral, exercise.ID,
+	).Scan(&exercise.UpdatedAt)
+	return uniqueViolation(err)
+}
+
+// DeleteExercise removes an exercise from the catalog. Entries that were
+// linked to it keep their exercise_name.
+func (pg *PostgresExerciseStore) DeleteExercise(id int64) error {
+	result, err := pg.DBConn.Exec(`DELETE FROM exercises WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateExercise
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package store
+
+import "database/sql"
+
+type PostgresIdentityStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresIdentityStore(DBConn *sql.DB) *PostgresIdentityStore {
+	return &PostgresIdentityStore{DBConn: DBConn}
+}
+
+func (pg *PostgresIdentityStore) CreateAuthRequest(req *OIDCAuthRequest) error {
+	query := `
+	INSERT INTO oidc_auth_requests (state, provider, nonce, code_verifier, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := pg.DBConn.Exec(query, req.State, req.Provider, req.Nonce, req.CodeVerifier, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	// opportunistic cleanup of abandoned logins
+	_, err = pg.DBConn.Exec(`DELETE FROM oidc_auth_requests WHERE expires_at < CURRENT_TIMESTAMP`)
+	return err
+}
+
+// ConsumeAuthRequest returns and deletes the auth request for state, so every
+// state can only be used once. Expired requests are not returned.
+func (pg *PostgresIdentityStore) ConsumeAuthRequest(state string) (*OIDCAuthRequest, error) {
+	req := &OIDCAuthRequest{}
+
+	query := `
+	DELETE FROM oidc_auth_requests
+	WHERE state = $1 AND expires_at > CURRENT_TIMESTAMP
+	RETURNING state, provider, nonce, code_verifier, expires_at
+	`
+	err := pg.DBConn.QueryRow(query, state).Scan(&req.State, &req.Provider, &req.Nonce, &req.CodeVerifier, &req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (pg *PostgresIdentityStore) GetUserByIdentity(provider, subject string) (*User, error) {
+	query := `
+	SELECT ` + userColumns + `
+	FROM users u
+	INNER JOIN user_identities i ON i.user_id = u.id
+	WHERE i.provider = $1 AND i.subject = $2
+	`
+	return scanUser(pg.DBConn.QueryRow(query, provider, subject))
+}
+
+func (pg *PostgresIdentityStore) LinkIdentity(identity *UserIdentity) error {
+	query := `
+	INSERT INTO user_identities (user_id, provider, subject, email)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at
+	`
+
+	return pg.DBConn.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+}
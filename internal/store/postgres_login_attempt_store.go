@@ -0,0 +1,131 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+type PostgresLoginAttemptStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresLoginAttemptStore(DBConn *sql.DB) *PostgresLoginAttemptStore {
+	return &PostgresLoginAttemptStore{DBConn: DBConn}
+}
+
+func (pg *PostgresLoginAttemptStore) GetLoginAttempts(keys ...string) ([]LoginAttempt, error) {
+	query := `
+	SELECT key, failures, last_failure_at, locked_until
+	FROM login_attempts
+	WHERE key = ANY($1)
+	`
+
+	rows, err := pg.DBConn.Query(query, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []LoginAttempt{}
+	for rows.Next() {
+		var attempt LoginAttempt
+		err = rows.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// TakeLoginAttempts counts an attempt as a failure against every key that
+// is neither locked nor backing off, and returns the keys it counted. The
+// check and the increment happen in the same statement, so concurrent
+// attempts on a key queue on its row and only the first one gets through.
+// Callers release the attempt again with ReleaseLoginAttempts when it
+// didn't actually fail.
+func (pg *PostgresLoginAttemptStore) TakeLoginAttempts(keys []string, backoff LoginBackoff) ([]LoginAttempt, error) {
+	query := `
+	INSERT INTO login_attempts AS a (key, failures, last_failure_at)
+	SELECT key, 1, CURRENT_TIMESTAMP
+	FROM unnest($1::text[]) AS key
+	ON CONFLICT (key) DO UPDATE
+	SET failures = CASE
+			WHEN a.last_failure_at < CURRENT_TIMESTAMP - make_interval(secs => $4) THEN 1
+			ELSE a.failures + 1
+		END,
+		last_failure_at = CURRENT_TIMESTAMP
+	WHERE (a.locked_until IS NULL OR a.locked_until <= CURRENT_TIMESTAMP)
+		AND (
+			a.failures = 0
+			OR a.last_failure_at < CURRENT_TIMESTAMP - make_interval(secs => $4)
+			OR a.last_failure_at + LEAST(
+				make_interval(secs => $2) * power(2, LEAST(a.failures - 1, 30)),
+				make_interval(secs => $3)
+			) <= CURRENT_TIMESTAMP
+		)
+	RETURNING key, failures, last_failure_at, locked_until
+	`
+
+	rows, err := pg.DBConn.Query(query, keys, backoff.BaseDelay.Seconds(), backoff.MaxDelay.Seconds(), backoff.Window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []LoginAttempt{}
+	for rows.Next() {
+		var attempt LoginAttempt
+		err = rows.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// ReleaseLoginAttempts takes back an attempt counted by TakeLoginAttempts.
+func (pg *PostgresLoginAttemptStore) ReleaseLoginAttempts(keys ...string) error {
+	query := `
+	UPDATE login_attempts
+	SET failures = GREATEST(failures - 1, 0)
+	WHERE key = ANY($1)
+	`
+
+	_, err := pg.DBConn.Exec(query, keys)
+	return err
+}
+
+func (pg *PostgresLoginAttemptStore) LockLogin(key string, until time.Time) error {
+	query := `
+	UPDATE login_attempts
+	SET locked_until = $1, failures = 0
+	WHERE key = $2
+	`
+
+	_, err := pg.DBConn.Exec(query, until, key)
+	return err
+}
+
+func (pg *PostgresLoginAttemptStore) ResetLoginAttempts(keys ...string) error {
+	query := `
+	DELETE FROM login_attempts
+	WHERE key = ANY($1)
+	`
+
+	_, err := pg.DBConn.Exec(query, keys)
+	return err
+}
+
+func (pg *PostgresLoginAttemptStore) CreateLockoutEvent(event *LockoutEvent) error {
+	query := `
+	INSERT INTO lockout_events (key, username, ip_address, failures, locked_until)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+
+	return pg.DBConn.QueryRow(query, event.Key, event.Username, event.IPAddress, event.Failures, event.LockedUntil).Scan(&event.ID, &event.CreatedAt)
+}
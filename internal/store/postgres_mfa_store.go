@@ -0,0 +1,143 @@
+package store
+
+import "database/sql"
+
+type PostgresMFAStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresMFAStore(DBConn *sql.DB) *PostgresMFAStore {
+	return &PostgresMFAStore{DBConn: DBConn}
+}
+
+func (pg *PostgresMFAStore) GetTOTPSecret(userID int) (*TOTPSecret, error) {
+	secret := &TOTPSecret{}
+
+	query := `
+	SELECT user_id, secret, confirmed_at, last_used_step
+	FROM user_totp
+	WHERE user_id = $1
+	`
+	err := pg.DBConn.QueryRow(query, userID).Scan(&secret.UserID, &secret.Secret, &secret.ConfirmedAt, &secret.LastUsedStep)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// SaveTOTPSecret stores a new, unconfirmed secret, replacing any previous
+// unfinished enrollment.
+func (pg *PostgresMFAStore) SaveTOTPSecret(userID int, secret string) error {
+	query := `
+	INSERT INTO user_totp (user_id, secret)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := pg.DBConn.Exec(query, userID, secret)
+	return err
+}
+
+func (pg *PostgresMFAStore) ConfirmTOTPSecret(userID int, step int64, recoveryCodeHashes [][]byte) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE user_totp
+	SET confirmed_at = CURRENT_TIMESTAMP, last_used_step = $1
+	WHERE user_id = $2 AND confirmed_at IS NULL
+	`
+
+	result, err := tx.Exec(query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as used. It returns false when the step, or a
+// later one, was already used, which means the code is being replayed.
+func (pg *PostgresMFAStore) UseTOTPStep(userID int, step int64) (bool, error) {
+	query := `
+	UPDATE user_totp
+	SET last_used_step = $1
+	WHERE user_id = $2 AND last_used_step < $1
+	`
+
+	result, err := pg.DBConn.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (pg *PostgresMFAStore) DeleteTOTPSecret(userID int) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresMFAStore) UseRecoveryCode(userID int, codeHash []byte) (bool, error) {
+	query := `
+	UPDATE recovery_codes
+	SET used_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := pg.DBConn.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+type PostgresPlannedWorkoutStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresPlannedWorkoutStore(DBConn *sql.DB) *PostgresPlannedWorkoutStore {
+	return &PostgresPlannedWorkoutStore{DBConn: DBConn}
+}
+
+const plannedWorkoutColumns = `p.id, p.user_id, p.title, p.notes, p.template_id, p.scheduled_at, p.timezone,
+	p.duration_minutes, p.rrule, p.created_at, p.updated_at`
+
+func scanPlannedWorkout(row rowScanner) (*PlannedWorkout, error) {
+	plan := &PlannedWorkout{Completions: []Completion{}}
+	err := row.Scan(
+		&plan.ID,
+		&plan.UserID,
+		&plan.Title,
+		&plan.Notes,
+		&plan.TemplateID,
+		&plan.ScheduledAt,
+		&plan.Timezone,
+		&plan.DurationMinutes,
+		&plan.RRule,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (pg *PostgresPlannedWorkoutStore) ListPlannedWorkouts(userID int) ([]PlannedWorkout, error) {
+	query := `
+	SELECT ` + plannedWorkoutColumns + `
+	FROM planned_workouts p
+	WHERE p.user_id = $1
+	ORDER BY p.scheduled_at, p.id
+	`
+
+	rows, err := pg.DBConn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []PlannedWorkout{}
+	index := map[int64]int{}
+	for rows.Next() {
+		plan, err := scanPlannedWorkout(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[plan.ID] = len(plans)
+		plans = append(plans, *plan)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT c.planned_workout_id, to_char(c.occurrence_date, 'YYYY-MM-DD'), c.workout_id
+	FROM planned_workout_completions c
+	JOIN planned_workouts p ON p.id = c.planned_workout_id
+	JOIN workouts w ON w.id = c.workout_id AND w.deleted_at IS NULL
+	WHERE p.user_id = $1
+	ORDER BY c.occurrence_date
+	`
+
+	rows, err = pg.DBConn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var planID int64
+		var completion Completion
+		err = rows.Scan(&planID, &completion.Date, &completion.WorkoutID)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[planID]; ok {
+			plans[i].Completions = append(plans[i].Completions, completion)
+		}
+	}
+
+	return plans, rows.Err()
+}
+
+func (pg *PostgresPlannedWorkoutStore) GetPlannedWorkout(id int64) (*PlannedWorkout, error) {
+	query := `
+	SELECT ` + plannedWorkoutColumns + `
+	FROM planned_workouts p
+	WHERE p.id = $1
+	`
+
+	plan, err := scanPlannedWorkout(pg.DBConn.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT to_char(c.occurrence_date, 'YYYY-MM-DD'), c.workout_id
+	FROM planned_workout_completions c
+	JOIN workouts w ON w.id = c.workout_id AND w.deleted_at IS NULL
+	WHERE c.planned_workout_id = $1
+	ORDER BY c.occurrence_date
+	`
+
+	rows, err := pg.DBConn.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var completion Completion
+		err = rows.Scan(&completion.Date, &completion.WorkoutID)
+		if err != nil {
+			return nil, err
+		}
+		plan.Completions = append(plan.Completions, completion)
+	}
+
+	return plan, rows.Err()
+}
+
+func (pg *PostgresPlannedWorkoutStore) CreatePlannedWorkout(plan *PlannedWorkout) error {
+	query := `
+	INSERT INTO planned_workouts (user_id, title, notes, template_id, scheduled_at, timezone, duration_minutes, rrule)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at
+	`
+
+	return pg.DBConn.QueryRow(query, plan.UserID, plan.Title, plan.Notes, plan.TemplateID, plan.ScheduledAt,
+		plan.Timezone, plan.DurationMinutes, plan.RRule).
+		Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
+}
+
+func (pg *PostgresPlannedWorkoutStore) UpdatePlannedWorkout(plan *PlannedWorkout) error {
+	query := `
+	UPDATE planned_workouts
+	SET title = $1, notes = $2, template_id = $3, scheduled_at = $4, timezone = $5,
+		duration_minutes = $6, rrule = $7, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $8
+	RETURNING updated_at
+	`
+
+	return pg.DBConn.QueryRow(query, plan.Title, plan.Notes, plan.TemplateID, plan.ScheduledAt, plan.Timezone,
+		plan.DurationMinutes, plan.RRule, plan.ID).
+		Scan(&plan.UpdatedAt)
+}
+
+func (pg *PostgresPlannedWorkoutStore) DeletePlannedWorkout(id int64) error {
+	result, err := pg.DBConn.Exec(`DELETE FROM planned_workouts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pg *PostgresPlannedWorkoutStore) CompletePlannedWorkout(id int64, date time.Time, workoutID int64) error {
+	query := `
+	INSERT INTO planned_workout_completions (planned_workout_id, occurrence_date, workout_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (planned_workout_id, occurrence_date) DO UPDATE SET workout_id = EXCLUDED.workout_id
+	`
+
+	_, err := pg.DBConn.Exec(query, id, date.Format(time.DateOnly), workoutID)
+	return err
+}
+
+func (pg *PostgresPlannedWorkoutStore) UncompletePlannedWorkout(id int64, date time.Time) error {
+	query := `
+	DELETE FROM planned_workout_completions
+	WHERE planned_workout_id = $1 AND occurrence_date = $2
+	`
+
+	result, err := pg.DBConn.Exec(query, id, date.Format(time.DateOnly))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,573 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/jackc/pgconn"
+)
+
+type PostgresProgramStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresProgramStore(DBConn *sql.DB) *PostgresProgramStore {
+	return &PostgresProgramStore{DBConn: DBConn}
+}
+
+const programColumns = `p.id, p.owner_id, p.title, p.description, p.weeks, p.public, p.rounding, p.unit, p.created_at, p.updated_at`
+
+func scanProgram(row rowScanner) (*Program, error) {
+	program := &Program{Days: []ProgramDay{}, Progressions: []ProgressionRule{}}
+	err := row.Scan(
+		&program.ID,
+		&program.OwnerID,
+		&program.Title,
+		&program.Description,
+		&program.Weeks,
+		&program.Public,
+		&program.Rounding,
+		&program.Unit,
+		&program.CreatedAt,
+		&program.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return program, nil
+}
+
+func (pg *PostgresProgramStore) ListPrograms(userID int, limit, offset int) ([]Program, error) {
+	query := `
+	SELECT ` + programColumns + `
+	FROM programs p
+	WHERE p.owner_id = $1 OR p.public
+	ORDER BY p.title, p.id
+	LIMIT $2 OFFSET $3
+	`
+
+	rows, err := pg.DBConn.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	programs := []Program{}
+	for rows.Next() {
+		program, err := scanProgram(rows)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, *program)
+	}
+
+	return programs, rows.Err()
+}
+
+func (pg *PostgresProgramStore) GetProgramByID(id int64) (*Program, error) {
+	query := `
+	SELECT ` + programColumns + `
+	FROM programs p
+	WHERE p.id = $1
+	`
+
+	program, err := scanProgram(pg.DBConn.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.DBConn.Query(`
+	SELECT week, day, template_id, prescriptions
+	FROM program_days
+	WHERE program_id = $1
+	ORDER BY week, day
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day ProgramDay
+		var prescriptions []byte
+		err = rows.Scan(&day.Week, &day.Day, &day.TemplateID, &prescriptions)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(prescriptions, &day.Prescriptions)
+		if err != nil {
+			return nil, err
+		}
+		program.Days = append(program.Days, day)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = pg.DBConn.Query(`
+	SELECT exercise_id, increment
+	FROM program_progressions
+	WHERE program_id = $1
+	ORDER BY exercise_id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule ProgressionRule
+		err = rows.Scan(&rule.ExerciseID, &rule.Increment)
+		if err != nil {
+			return nil, err
+		}
+		program.Progressions = append(program.Progressions, rule)
+	}
+
+	return program, rows.Err()
+}
+
+func (pg *PostgresProgramStore) CreateProgram(program *Program) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO programs (owner_id, title, description, weeks, public, rounding, unit)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(query, program.OwnerID, program.Title, program.Description, program.Weeks, program.Public, program.Rounding, program.Unit).
+		Scan(&program.ID, &program.CreatedAt, &program.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertProgramPlan(tx, program)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresProgramStore) UpdateProgram(program *Program) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE programs
+	SET title = $1, description = $2, weeks = $3, public = $4, rounding = $5, unit = $6, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $7
+	RETURNING updated_at
+	`
+
+	err = tx.QueryRow(query, program.Title, program.Description, program.Weeks, program.Public, program.Rounding, program.Unit, program.ID).
+		Scan(&program.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM program_days WHERE program_id = $1`, program.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM program_progressions WHERE program_id = $1`, program.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertProgramPlan(tx, program)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresProgramStore) DeleteProgram(id int64) error {
+	result, err := pg.DBConn.Exec(`DELETE FROM programs WHERE id = $1`, id)
+	if err != nil {
+		return foreignKeyViolation(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// insertProgramPlan inserts the days and progressions of a program once it
+// checked that the owner can use every template and exercise they name.
+func insertProgramPlan(tx *sql.Tx, program *Program) error {
+	var (
+		weeks, days          []int
+		templateIDs          []int64
+		prescriptions        []string
+		exerciseIDs, ruleIDs []int64
+		increments           []float64
+	)
+	for _, day := range program.Days {
+		encoded, err := json.Marshal(day.Prescriptions)
+		if err != nil {
+			return err
+		}
+
+		weeks = append(weeks, day.Week)
+		days = append(days, day.Day)
+		templateIDs = append(templateIDs, day.TemplateID)
+		prescriptions = append(prescriptions, string(encoded))
+		for _, p := range day.Prescriptions {
+			exerciseIDs = append(exerciseIDs, p.ExerciseID)
+		}
+	}
+	for _, rule := range program.Progressions {
+		ruleIDs = append(ruleIDs, rule.ExerciseID)
+		increments = append(increments, rule.Increment)
+	}
+
+	ok, err := allVisible(tx, `SELECT COUNT(*) FROM workout_templates WHERE id = ANY($1) AND user_id = $2`, templateIDs, program.OwnerID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnknownTemplate
+	}
+
+	ok, err = allVisible(tx, `SELECT COUNT(*) FROM exercises WHERE id = ANY($1) AND (owner_id IS NULL OR owner_id = $2)`,
+		slices.Concat(exerciseIDs, ruleIDs), program.OwnerID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnknownExercise
+	}
+
+	if len(program.Days) > 0 {
+		query := `
+		INSERT INTO program_days (program_id, week, day, template_id, prescriptions)
+		SELECT $1, v.week, v.day, v.template_id, v.prescriptions::jsonb
+		FROM unnest($2::int[], $3::int[], $4::bigint[], $5::text[]) AS v(week, day, template_id, prescriptions)
+		`
+		_, err = tx.Exec(query, program.ID, weeks, days, templateIDs, prescriptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(program.Progressions) > 0 {
+		query := `
+		INSERT INTO program_progressions (program_id, exercise_id, increment)
+		SELECT $1, v.exercise_id, v.increment
+		FROM unnest($2::bigint[], $3::numeric[]) AS v(exercise_id, increment)
+		`
+		_, err = tx.Exec(query, program.ID, ruleIDs, increments)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// allVisible reports whether query, counting the rows among ids the owner
+// can use, finds all of them.
+func allVisible(tx *sql.Tx, query string, ids []int64, ownerID int) (bool, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) == 0 {
+		return true, nil
+	}
+
+	var count int
+	err := tx.QueryRow(query, ids, ownerID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == len(ids), nil
+}
+
+const enrollmentColumns = `e.id, e.user_id, e.program_id, e.status, e.week, e.day, e.started_at, e.completed_at`
+
+func scanEnrollment(row rowScanner) (*Enrollment, error) {
+	enrollment := &Enrollment{TrainingMaxes: []TrainingMax{}}
+	err := row.Scan(
+		&enrollment.ID,
+		&enrollment.UserID,
+		&enrollment.ProgramID,
+		&enrollment.Status,
+		&enrollment.Week,
+		&enrollment.Day,
+		&enrollment.StartedAt,
+		&enrollment.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+func (pg *PostgresProgramStore) CreateEnrollment(enrollment *Enrollment, exerciseIDs []int64) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO program_enrollments (user_id, program_id, week, day)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, status, started_at
+	`
+
+	err = tx.QueryRow(query, enrollment.UserID, enrollment.ProgramID, enrollment.Week, enrollment.Day).
+		Scan(&enrollment.ID, &enrollment.Status, &enrollment.StartedAt)
+	if err != nil {
+		return err
+	}
+
+	if len(enrollment.TrainingMaxes) > 0 {
+		var ids []int64
+		var weights []float64
+		for _, tm := range enrollment.TrainingMaxes {
+			ids = append(ids, tm.ExerciseID)
+			weights = append(weights, tm.Weight)
+		}
+
+		query = `
+		INSERT INTO enrollment_training_maxes (enrollment_id, exercise_id, weight)
+		SELECT $1, v.exercise_id, v.weight
+		FROM unnest($2::bigint[], $3::numeric[]) AS v(exercise_id, weight)
+		ON CONFLICT DO NOTHING
+		`
+		_, err = tx.Exec(query, enrollment.ID, ids, weights)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(exerciseIDs) > 0 {
+		keys := make([]string, len(exerciseIDs))
+		for i := range exerciseIDs {
+			keys[i] = strength.ExerciseKey(&exerciseIDs[i], "")
+		}
+
+		query = `
+		INSERT INTO enrollment_training_maxes (enrollment_id, exercise_id, weight)
+		SELECT $1, v.exercise_id, round(MAX(pr.value) * 0.9, 3)
+		FROM unnest($3::bigint[], $4::text[]) AS v(exercise_id, exercise_key)
+		JOIN personal_records pr ON pr.user_id = $2 AND pr.exercise_key = v.exercise_key AND pr.record_type = $5
+		JOIN workouts w ON w.id = pr.workout_id AND w.deleted_at IS NULL
+		GROUP BY v.exercise_id
+		ON CONFLICT DO NOTHING
+		`
+		_, err = tx.Exec(query, enrollment.ID, enrollment.UserID, exerciseIDs, keys, string(strength.RecordEstimated1RM))
+		if err != nil {
+			return err
+		}
+	}
+
+	enrollment.TrainingMaxes, err = trainingMaxes(tx, enrollment.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresProgramStore) ListEnrollments(userID int) ([]Enrollment, error) {
+	query := `
+	SELECT ` + enrollmentColumns + `
+	FROM program_enrollments e
+	WHERE e.user_id = $1
+	ORDER BY e.started_at DESC, e.id DESC
+	`
+
+	rows, err := pg.DBConn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := []Enrollment{}
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, *enrollment)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range enrollments {
+		enrollments[i].TrainingMaxes, err = trainingMaxes(pg.DBConn, enrollments[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return enrollments, nil
+}
+
+func (pg *PostgresProgramStore) GetEnrollment(id int64) (*Enrollment, error) {
+	query := `
+	SELECT ` + enrollmentColumns + `
+	FROM program_enrollments e
+	WHERE e.id = $1
+	`
+
+	enrollment, err := scanEnrollment(pg.DBConn.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment.TrainingMaxes, err = trainingMaxes(pg.DBConn, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+func (pg *PostgresProgramStore) DeleteEnrollment(id int64) error {
+	result, err := pg.DBConn.Exec(`DELETE FROM program_enrollments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pg *PostgresProgramStore) CompleteSession(enrollment *Enrollment, workoutID int64, increments []TrainingMax, next *ProgramDay) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	week, day, status := enrollment.Week, enrollment.Day, EnrollmentCompleted
+	var completedAt *time.Time
+	if next != nil {
+		week, day, status = next.Week, next.Day, EnrollmentActive
+	}
+
+	query := `
+	UPDATE program_enrollments
+	SET week = $1, day = $2, status = $3,
+		completed_at = CASE WHEN $3 = 'completed' THEN CURRENT_TIMESTAMP END
+	WHERE id = $4 AND week = $5 AND day = $6 AND status = 'active'
+	RETURNING completed_at
+	`
+
+	err = tx.QueryRow(query, week, day, status, enrollment.ID, enrollment.Week, enrollment.Day).Scan(&completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	} else if err != nil {
+		return err
+	}
+
+	query = `
+	INSERT INTO enrollment_sessions (enrollment_id, week, day, workout_id)
+	VALUES ($1, $2, $3, $4)
+	`
+	_, err = tx.Exec(query, enrollment.ID, enrollment.Week, enrollment.Day, workoutID)
+	if err != nil {
+		return err
+	}
+
+	if len(increments) > 0 {
+		var ids []int64
+		var weights []float64
+		for _, increment := range increments {
+			ids = append(ids, increment.ExerciseID)
+			weights = append(weights, increment.Weight)
+		}
+
+		query = `
+		UPDATE enrollment_training_maxes AS tm
+		SET weight = tm.weight + v.increment
+		FROM unnest($2::bigint[], $3::numeric[]) AS v(exercise_id, increment)
+		WHERE tm.enrollment_id = $1 AND tm.exercise_id = v.exercise_id
+		`
+		_, err = tx.Exec(query, enrollment.ID, ids, weights)
+		if err != nil {
+			return err
+		}
+	}
+
+	trainingMaxes, err := trainingMaxes(tx, enrollment.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	enrollment.Week, enrollment.Day, enrollment.Status = week, day, status
+	enrollment.CompletedAt = completedAt
+	enrollment.TrainingMaxes = trainingMaxes
+	return nil
+}
+
+func trainingMaxes(q querier, enrollmentID int64) ([]TrainingMax, error) {
+	rows, err := q.Query(`
+	SELECT exercise_id, weight
+	FROM enrollment_training_maxes
+	WHERE enrollment_id = $1
+	ORDER BY exercise_id
+	`, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	maxes := []TrainingMax{}
+	for rows.Next() {
+		var tm TrainingMax
+		err = rows.Scan(&tm.ExerciseID, &tm.Weight)
+		if err != nil {
+			return nil, err
+		}
+		maxes = append(maxes, tm)
+	}
+
+	return maxes, rows.Err()
+}
+
+func foreignKeyViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrInUse
+	}
+	return err
+}
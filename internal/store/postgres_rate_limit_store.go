@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/ratelimit"
+)
+
+// PostgresRateLimitStore shares rate limit buckets between every instance
+// connected to the same database.
+type PostgresRateLimitStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresRateLimitStore(DBConn *sql.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{DBConn: DBConn}
+}
+
+func (pg *PostgresRateLimitStore) Take(key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	bucket := ratelimit.NewBucket(limit, now)
+
+	// the no-op update locks an existing row, so a concurrent purge can't
+	// delete it between reading and writing the bucket
+	err = tx.QueryRow(`
+	INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+	VALUES ($1, $2, $3, $3)
+	ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+	RETURNING tokens, updated_at
+	`, key, bucket.Tokens, bucket.UpdatedAt).Scan(&bucket.Tokens, &bucket.UpdatedAt)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	bucket, result := bucket.Take(limit, now)
+
+	_, err = tx.Exec(`
+	UPDATE rate_limit_buckets
+	SET tokens = $1, updated_at = $2, full_at = $3
+	WHERE key = $4
+	`, bucket.Tokens, bucket.UpdatedAt, now.Add(result.ResetAfter), key)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return result, nil
+}
+
+// PurgeFullBuckets deletes the buckets that have refilled completely by
+// now. They behave exactly like a missing bucket, so purging them only
+// keeps the table from growing with every key ever seen.
+func (pg *PostgresRateLimitStore) PurgeFullBuckets(now time.Time) (int64, error) {
+	result, err := pg.DBConn.Exec(`
+	DELETE FROM rate_limit_buckets
+	WHERE full_at <= $1
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
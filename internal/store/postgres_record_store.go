@@ -0,0 +1,180 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/strength"
+)
+
+type PostgresRecordStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresRecordStore(DBConn *sql.DB) *PostgresRecordStore {
+	return &PostgresRecordStore{DBConn: DBConn}
+}
+
+const recordColumns = `pr.id, pr.exercise_id, pr.exercise_name, pr.record_type, pr.weight, pr.value, pr.workout_id, pr.entry_id, pr.achieved_at`
+
+func scanRecord(row rowScanner) (*PersonalRecord, error) {
+	record := &PersonalRecord{}
+	err := row.Scan(
+		&record.ID,
+		&record.ExerciseID,
+		&record.ExerciseName,
+		&record.Type,
+		&record.Weight,
+		&record.Value,
+		&record.WorkoutID,
+		&record.EntryID,
+		&record.AchievedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ReplaceWorkoutRecords stores the bests of a workout whether or not they are
+// records: records are derived from the bests of the live workouts when they
+// are read, so editing, trashing or restoring any workout updates them.
+// Trashed workouts keep their bests so that restoring them brings them back.
+func (pg *PostgresRecordStore) ReplaceWorkoutRecords(userID int, workoutID int64, achievedAt time.Time, candidates []strength.Record) ([]PersonalRecord, error) {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM personal_records WHERE workout_id = $1`, workoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []PersonalRecord{}
+	if len(candidates) > 0 {
+		var (
+			keys, names, types []string
+			exerciseIDs        []*int64
+			weights            []*float64
+			values             []float64
+			entryIDs           []int64
+		)
+		for _, c := range candidates {
+			keys = append(keys, c.ExerciseKey)
+			exerciseIDs = append(exerciseIDs, c.ExerciseID)
+			names = append(names, c.ExerciseName)
+			types = append(types, string(c.Type))
+			weights = append(weights, c.Weight)
+			values = append(values, c.Value)
+			entryIDs = append(entryIDs, int64(c.EntryID))
+		}
+
+		insert := `
+		INSERT INTO personal_records (user_id, exercise_key, exercise_id, exercise_name, record_type, weight, value, workout_id, entry_id, achieved_at)
+		SELECT $1, v.exercise_key, v.exercise_id, v.exercise_name, v.record_type, v.weight, v.value, $2, v.entry_id, $3
+		FROM unnest($4::text[], $5::bigint[], $6::text[], $7::text[], $8::numeric[], $9::numeric[], $10::bigint[])
+			AS v(exercise_key, exercise_id, exercise_name, record_type, weight, value, entry_id)
+		`
+
+		_, err = tx.Exec(insert, userID, workoutID, achievedAt, keys, exerciseIDs, names, types, weights, values, entryIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		// the workout's PRs are its bests that beat every earlier workout
+		query := `
+		SELECT ` + recordColumns + `
+		FROM personal_records pr
+		WHERE pr.workout_id = $2 AND pr.value > COALESCE((
+			SELECT MAX(earlier.value)
+			FROM personal_records earlier
+			JOIN workouts w ON w.id = earlier.workout_id AND w.deleted_at IS NULL
+			WHERE earlier.user_id = $1
+				AND earlier.exercise_key = pr.exercise_key
+				AND earlier.record_type = pr.record_type
+				AND earlier.weight IS NOT DISTINCT FROM pr.weight
+				AND (earlier.achieved_at, earlier.workout_id) < ($3, $2)
+		), 0)
+		ORDER BY pr.exercise_name, pr.record_type, pr.weight
+		`
+
+		rows, err := tx.Query(query, userID, workoutID, achievedAt)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			record, err := scanRecord(rows)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, *record)
+		}
+		err = rows.Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return records, tx.Commit()
+}
+
+func (pg *PostgresRecordStore) ListRecords(userID int) ([]PersonalRecord, error) {
+	query := `
+	SELECT * FROM (
+		SELECT DISTINCT ON (pr.exercise_key, pr.record_type, pr.weight) ` + recordColumns + `
+		FROM personal_records pr
+		JOIN workouts w ON w.id = pr.workout_id AND w.deleted_at IS NULL
+		WHERE pr.user_id = $1
+		ORDER BY pr.exercise_key, pr.record_type, pr.weight, pr.value DESC, pr.achieved_at
+	) AS best
+	ORDER BY best.exercise_name, best.record_type, best.weight
+	`
+
+	return pg.queryRecords(query, userID)
+}
+
+// ListRecordHistory lists the bests that beat every earlier one, as of the
+// workouts that are live now.
+func (pg *PostgresRecordStore) ListRecordHistory(userID int, exerciseKey string) ([]PersonalRecord, error) {
+	query := `
+	SELECT ` + recordColumns + `
+	FROM (
+		SELECT pr.*, pr.value > COALESCE(MAX(pr.value) OVER (
+			PARTITION BY pr.record_type, pr.weight
+			ORDER BY pr.achieved_at, pr.workout_id
+			ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+		), 0) AS is_record
+		FROM personal_records pr
+		JOIN workouts w ON w.id = pr.workout_id AND w.deleted_at IS NULL
+		WHERE pr.user_id = $1 AND pr.exercise_key = $2
+	) AS pr
+	WHERE pr.is_record
+	ORDER BY pr.achieved_at, pr.id
+	`
+
+	return pg.queryRecords(query, userID, exerciseKey)
+}
+
+func (pg *PostgresRecordStore) queryRecords(query string, args ...any) ([]PersonalRecord, error) {
+	rows, err := pg.DBConn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []PersonalRecord{}
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+
+	return records, rows.Err()
+}
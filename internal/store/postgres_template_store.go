@@ -0,0 +1,273 @@
+package store
+
+import "database/sql"
+
+type PostgresTemplateStore struct {
+	DBConn *sql.DB
+}
+
+func NewPostgresTemplateStore(DBConn *sql.DB) *PostgresTemplateStore {
+	return &PostgresTemplateStore{DBConn: DBConn}
+}
+
+const templateColumns = `t.id, t.user_id, t.title, t.description, t.created_at, t.updated_at`
+
+func scanTemplate(row rowScanner) (*WorkoutTemplate, error) {
+	template := &WorkoutTemplate{Entries: []WorkoutEntry{}}
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Title,
+		&template.Description,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (pg *PostgresTemplateStore) ListTemplates(userID int, limit, offset int) ([]WorkoutTemplate, error) {
+	query := `
+	SELECT ` + templateColumns + `
+	FROM workout_templates t
+	WHERE t.user_id = $1
+	ORDER BY t.title, t.id
+	LIMIT $2 OFFSET $3
+	`
+
+	rows, err := pg.DBConn.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []WorkoutTemplate{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+
+	return templates, rows.Err()
+}
+
+func (pg *PostgresTemplateStore) GetTemplateByID(id int64) (*WorkoutTemplate, error) {
+	query := `
+	SELECT ` + templateColumns + `
+	FROM workout_templates t
+	WHERE t.id = $1
+	`
+
+	template, err := scanTemplate(pg.DBConn.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT ` + workoutEntryColumns + `
+	FROM workout_template_entries e
+	WHERE e.template_id = $1
+	ORDER BY e.order_index, e.id
+	`
+
+	rows, err := pg.DBConn.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanWorkoutEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		template.Entries = append(template.Entries, *entry)
+	}
+
+	return template, rows.Err()
+}
+
+func (pg *PostgresTemplateStore) CreateTemplate(template *WorkoutTemplate) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO workout_templates (user_id, title, description)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(query, template.UserID, template.Title, template.Description).
+		Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertTemplateEntries(tx, template)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresTemplateStore) UpdateTemplate(template *WorkoutTemplate) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE workout_templates
+	SET title = $1, description = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3
+	RETURNING updated_at
+	`
+
+	err = tx.QueryRow(query, template.Title, template.Description, template.ID).Scan(&template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM workout_template_entries WHERE template_id = $1`, template.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertTemplateEntries(tx, template)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresTemplateStore) DeleteTemplate(id int64) error {
+	result, err := pg.DBConn.Exec(`DELETE FROM workout_templates WHERE id = $1`, id)
+	if err != nil {
+		return foreignKeyViolation(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// insertTemplateEntries links the entries of template to the exercise
+// catalog, inserts them and writes their IDs back.
+func insertTemplateEntries(tx *sql.Tx, template *WorkoutTemplate) error {
+	if len(template.Entries) == 0 {
+		return nil
+	}
+
+	err := ValidateEntryGroups(template.Entries)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*WorkoutEntry, len(template.Entries))
+	for i := range template.Entries {
+		entries[i] = &template.Entries[i]
+		err = SummarizeSets(entries[i])
+		if err != nil {
+			return err
+		}
+		entries[i].SetDetails = nil
+	}
+
+	err = resolveExercises(tx, template.UserID, entries)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO workout_template_entries (template_id, exercise_id, exercise_name, sets, reps, duration_seconds, weight, weight_unit, notes, order_index,
+		group_id, group_type, group_rounds, group_rest_seconds)
+	SELECT $1, v.exercise_id, v.exercise_name, v.sets, v.reps, v.duration_seconds, v.weight, v.weight_unit, v.notes, v.order_index,
+		v.group_id, v.group_type, v.group_rounds, v.group_rest_seconds
+	FROM unnest($2::bigint[], $3::text[], $4::int[], $5::int[], $6::int[], $7::numeric[], $8::text[], $9::text[], $10::int[],
+		$11::int[], $12::text[], $13::int[], $14::int[])
+		WITH ORDINALITY AS v(exercise_id, exercise_name, sets, reps, duration_seconds, weight, weight_unit, notes, order_index,
+			group_id, group_type, group_rounds, group_rest_seconds, position)
+	ORDER BY v.position
+	RETURNING id
+	`
+
+	a := newEntryArrays(entries)
+	rows, err := tx.Query(query, template.ID, a.exerciseIDs, a.exerciseNames, a.sets, a.reps, a.durationsSeconds, a.weights, a.weightUnits, a.notes, a.orderIndexes,
+		a.groupIDs, a.groupTypes, a.groupRounds, a.groupRests)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// IDs are drawn from the sequence in input order
+	for i := 0; rows.Next(); i++ {
+		err = rows.Scan(&entries[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// resolveExercises links entries that aren't stored yet to the exercise
+// catalog, like resolveEntryExercises does for stored workout entries.
+func resolveExercises(tx *sql.Tx, userID int, entries []*WorkoutEntry) error {
+	query := `
+	SELECT x.id, x.name
+	FROM unnest($2::bigint[], $3::text[]) WITH ORDINALITY AS v(exercise_id, exercise_name, position)
+	LEFT JOIN LATERAL (
+		SELECT x.id, x.name
+		FROM exercises x
+		WHERE (x.owner_id IS NULL OR x.owner_id = $1)
+			AND CASE
+				WHEN v.exercise_id IS NOT NULL THEN x.id = v.exercise_id
+				ELSE normalize_exercise_name(x.name) = normalize_exercise_name(v.exercise_name)
+					OR normalize_exercise_name(v.exercise_name) IN (SELECT normalize_exercise_name(a) FROM unnest(x.aliases) AS a)
+			END
+		ORDER BY x.owner_id IS NULL, normalize_exercise_name(x.name) = normalize_exercise_name(v.exercise_name) DESC, x.id
+		LIMIT 1
+	) AS x ON TRUE
+	ORDER BY v.position
+	`
+
+	a := newEntryArrays(entries)
+	rows, err := tx.Query(query, userID, a.exerciseIDs, a.exerciseNames)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		var exerciseID *int64
+		var exerciseName *string
+		err = rows.Scan(&exerciseID, &exerciseName)
+		if err != nil {
+			return err
+		}
+
+		entries[i].ExerciseID = exerciseID
+		if exerciseName != nil && entries[i].ExerciseName == "" {
+			entries[i].ExerciseName = *exerciseName
+		}
+	}
+
+	return rows.Err()
+}
@@ -14,56 +14,99 @@ func NewPostgresUserStore(DBConn *sql.DB) *PostgresUserStore {
 	return &PostgresUserStore{DBConn: DBConn}
 }
 
-func (pg *PostgresUserStore) CreateUser(user *User) error {
-	query := `
-	INSERT INTO users (username, email, password_hash, bio)
-	VALUES ($1, $2, $3, $4)
-	RETURNING id, created_at, updated_at
-	`
-	err := pg.DBConn.QueryRow(query, user.Username, user.Email, user.PasswordHash.hash, user.Bio).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return err
-	}
+// userColumns lists the users columns in the order scanUser expects them.
+const userColumns = `u.id, u.username, u.email, u.password_hash, u.bio, u.role, u.timezone, u.preferred_unit, u.suspended_at, u.created_at, u.updated_at`
 
-	return nil
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (pg *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
+func scanUser(row rowScanner) (*User, error) {
 	user := &User{
 		PasswordHash: password{},
 	}
 
-	query := `
-	SELECT id, username, email, password_hash, bio, created_at, updated_at
-	FROM users
-	WHERE username = $1
-	`
-	err := pg.DBConn.QueryRow(query, username).Scan(
+	var bio sql.NullString
+	err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash.hash,
-		&user.Bio,
+		&bio,
+		&user.Role,
+		&user.Timezone,
+		&user.PreferredUnit,
+		&user.SuspendedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, err
-	} else if err != nil {
+	if err != nil {
 		return nil, err
 	}
+	user.Bio = bio.String
 
 	return user, nil
 }
 
+func (pg *PostgresUserStore) CreateUser(user *User) error {
+	query := `
+	INSERT INTO users (username, email, password_hash, bio)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, role, timezone, preferred_unit, created_at, updated_at
+	`
+	err := pg.DBConn.QueryRow(query, user.Username, user.Email, user.PasswordHash.hash, user.Bio).
+		Scan(&user.ID, &user.Role, &user.Timezone, &user.PreferredUnit, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (pg *PostgresUserStore) GetUserByID(id int64) (*User, error) {
+	query := `
+	SELECT ` + userColumns + `
+	FROM users u
+	WHERE u.id = $1
+	`
+	return scanUser(pg.DBConn.QueryRow(query, id))
+}
+
+func (pg *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
+	query := `
+	SELECT ` + userColumns + `
+	FROM users u
+	WHERE u.username = $1
+	`
+	return scanUser(pg.DBConn.QueryRow(query, username))
+}
+
+func (pg *PostgresUserStore) GetUserByEmail(email string) (*User, error) {
+	query := `
+	SELECT ` + userColumns + `
+	FROM users u
+	WHERE lower(u.email) = lower($1)
+	`
+	return scanUser(pg.DBConn.QueryRow(query, email))
+}
+
 func (pg *PostgresUserStore) UpdateUser(user *User) error {
 	query := `
 	UPDATE users
-	SET username = $1, email = $2, bio = $3, updated_at = CURRENT_TIMESTAMP()
-	WHERE id = $4
-	RETURNING id, created_at, updated_at
+	SET username = $1, email = $2, bio = $3, timezone = $4, preferred_unit = $5, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $6
+	RETURNING updated_at
+	`
+	return pg.DBConn.QueryRow(query, user.Username, user.Email, user.Bio, user.Timezone, user.PreferredUnit, user.ID).Scan(&user.UpdatedAt)
+}
+
+func (pg *PostgresUserStore) UpdatePasswordHash(user *User) error {
+	query := `
+	UPDATE users
+	SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2
 	`
-	result, err := pg.DBConn.Exec(query, user.Username, user.Email, user.Bio, user.ID)
+	result, err := pg.DBConn.Exec(query, user.PasswordHash.hash, user.ID)
 	if err != nil {
 		return err
 	}
@@ -82,25 +125,13 @@ func (pg *PostgresUserStore) GetUserToken(scope, plaintextPassword string) (*Use
 	tokenHash := sha256.Sum256([]byte(plaintextPassword))
 
 	query := `
-	SELECT u.id, u.username, u.email, u.password_hash, u.bio, u.created_at, u.updated_at
+	SELECT ` + userColumns + `
 	FROM users u
 	INNER JOIN tokens t ON t.user_id = u.id
 	WHERE t.hash = $1 AND t.scope = $2 and t.expiry > $3
 	`
 
-	user := &User{
-		PasswordHash: password{},
-	}
-
-	err := pg.DBConn.QueryRow(query, tokenHash[:], scope, time.Now()).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash.hash,
-		&user.Bio,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(pg.DBConn.QueryRow(query, tokenHash[:], scope, time.Now()))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -109,3 +140,87 @@ func (pg *PostgresUserStore) GetUserToken(scope, plaintextPassword string) (*Use
 
 	return user, nil
 }
+
+func (pg *PostgresUserStore) ListUsers(limit, offset int) ([]User, error) {
+	query := `
+	SELECT ` + userColumns + `
+	FROM users u
+	ORDER BY u.id
+	LIMIT $1 OFFSET $2
+	`
+
+	rows, err := pg.DBConn.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+func (pg *PostgresUserStore) SetUserRole(id int64, role string) error {
+	query := `
+	UPDATE users
+	SET role = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	`
+
+	result, err := pg.DBConn.Exec(query, role, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pg *PostgresUserStore) SetUserSuspended(id int64, suspended bool) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE users
+	SET suspended_at = CASE WHEN $1 THEN COALESCE(suspended_at, CURRENT_TIMESTAMP) END,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	`
+
+	result, err := tx.Exec(query, suspended, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if suspended {
+		_, err = tx.Exec(`DELETE FROM tokens WHERE user_id = $1`, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -1,6 +1,14 @@
 package store
 
-import "database/sql"
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/units"
+)
 
 type PostgresWorkoutStore struct {
 	DBConn *sql.DB
@@ -18,28 +26,52 @@ func (pg *PostgresWorkoutStore) CreateWorkout(workout *Workout) (*Workout, error
 	defer tx.Rollback()
 
 	query := `
-	INSERT INTO workouts (user_id, title, description, duration_minutes, calories_burned)
-	VALUES ($1, $2, $3, $4, $5)
-	RETURNING ID
+	INSERT INTO workouts (user_id, title, description, duration_minutes, calories_burned, performed_at, timezone, started_at, ended_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, version, created_at, updated_at
 	`
 
-	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned).Scan(&workout.ID)
+	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned,
+		workout.PerformedAt, workout.Timezone, workout.StartedAt, workout.EndedAt,
+	).Scan(&workout.ID, &workout.Version, &workout.CreatedAt, &workout.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateEntryGroups(workout.Entries)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, entry := range workout.Entries {
-		query := `
-		INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING ID
-		`
-		err = tx.QueryRow(query, workout.ID, entry.ExerciseName, entry.Sets, entry.Reps, entry.DurationSeconds, entry.Weight, entry.Notes, entry.OrderIndex).Scan(&entry.ID)
+	entries := make([]*WorkoutEntry, len(workout.Entries))
+	for i := range workout.Entries {
+		entries[i] = &workout.Entries[i]
+		err = SummarizeSets(entries[i])
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	err = insertWorkoutEntries(tx, int64(workout.ID), entries)
+	if err != nil {
+		return nil, err
+	}
+
+	err = replaceEntrySets(tx, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resolveEntryExercises(tx, int64(workout.ID), entries)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordRevision(tx, int64(workout.ID), workout.UserID)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -49,57 +81,96 @@ func (pg *PostgresWorkoutStore) CreateWorkout(workout *Workout) (*Workout, error
 }
 
 func (pg *PostgresWorkoutStore) GetWorkoutByID(id int64) (*Workout, error) {
+	return getWorkout(pg.DBConn, id)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// workoutColumns lists the workouts columns in the order scanWorkout
+// expects them.
+const workoutColumns = `w.id, w.user_id, w.title, w.description, w.duration_minutes, w.calories_burned, w.version,
+	w.performed_at, w.timezone, w.started_at, w.ended_at, w.created_at, w.updated_at, w.deleted_at`
+
+func scanWorkout(row rowScanner) (*Workout, error) {
 	workout := &Workout{}
+	err := row.Scan(
+		&workout.ID,
+		&workout.UserID,
+		&workout.Title,
+		&workout.Description,
+		&workout.DurationMinutes,
+		&workout.CaloriesBurned,
+		&workout.Version,
+		&workout.PerformedAt,
+		&workout.Timezone,
+		&workout.StartedAt,
+		&workout.EndedAt,
+		&workout.CreatedAt,
+		&workout.UpdatedAt,
+		&workout.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return workout, nil
+}
 
+func getWorkout(q querier, id int64) (*Workout, error) {
 	// gets workout
 	query := `
-	SELECT id, title, description, duration_minutes, calories_burned
-	FROM workouts
-	WHERE id = $1
+	SELECT ` + workoutColumns + `
+	FROM workouts w
+	WHERE w.id = $1 AND w.deleted_at IS NULL
 	`
-	err := pg.DBConn.QueryRow(query, id).Scan(&workout.ID, &workout.Title, &workout.Description, &workout.DurationMinutes, &workout.CaloriesBurned)
-	if err == sql.ErrNoRows {
-		return nil, err
-	} else if err != nil {
+	workout, err := scanWorkout(q.QueryRow(query, id))
+	if err != nil {
 		return nil, err
 	}
 
 	// gets workout entries
 	entryQuery := `
-	SELECT id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index
-	FROM workout_entries
-	WHERE workout_id = $1
-	ORDER BY order_index
+	SELECT ` + workoutEntryColumns + `
+	FROM workout_entries e
+	WHERE e.workout_id = $1
+	ORDER BY e.order_index, e.id
 	`
 
-	rows, err := pg.DBConn.Query(entryQuery, id)
+	rows, err := q.Query(entryQuery, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var entry WorkoutEntry
-		err = rows.Scan(
-			&entry.ID,
-			&entry.ExerciseName,
-			&entry.Sets,
-			&entry.Reps,
-			&entry.DurationSeconds,
-			&entry.Weight,
-			&entry.Notes,
-			&entry.OrderIndex,
-		)
+		entry, err := scanWorkoutEntry(rows)
 		if err != nil {
 			return nil, err
 		}
-		workout.Entries = append(workout.Entries, entry)
+		workout.Entries = append(workout.Entries, *entry)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachSets(q, id, workout.Entries)
+	if err != nil {
+		return nil, err
 	}
 
 	return workout, nil
 }
 
-func (pg *PostgresWorkoutStore) UpdateWorkoutByID(workout *Workout) error {
+// UpdateWorkoutByID updates the workout and diffs its entries against the
+// stored ones: entries with an ID are updated in place, entries without one
+// are inserted and get their new ID written back, and stored entries that
+// are no longer listed are deleted.
+func (pg *PostgresWorkoutStore) UpdateWorkoutByID(workout *Workout, authorID int) error {
 	tx, err := pg.DBConn.Begin()
 	if err != nil {
 		return err
@@ -108,46 +179,63 @@ func (pg *PostgresWorkoutStore) UpdateWorkoutByID(workout *Workout) error {
 
 	query := `
 	UPDATE workouts
-	SET title = $1, description = $2, duration_minutes = $3, calories_burned = $4
-	WHERE id = $5
+	SET title = $1, description = $2, duration_minutes = $3, calories_burned = $4,
+		performed_at = $5, timezone = $6, started_at = $7, ended_at = $8,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $9 AND version = $10 AND deleted_at IS NULL
+	RETURNING version, updated_at
 	`
 
-	results, err := tx.Exec(query, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned, workout.ID)
+	entries := make([]*WorkoutEntry, len(workout.Entries))
+	for i := range workout.Entries {
+		entries[i] = &workout.Entries[i]
+	}
+
+	err = keepStoredSets(tx, int64(workout.ID), entries)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := results.RowsAffected()
+	for _, entry := range entries {
+		err = SummarizeSets(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	var version int
+	err = tx.QueryRow(query, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned,
+		workout.PerformedAt, workout.Timezone, workout.StartedAt, workout.EndedAt, workout.ID, workout.Version,
+	).Scan(&version, &workout.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return pg.missingOrConflict(tx, int64(workout.ID), false)
+	} else if err != nil {
+		return err
+	}
+
+	err = syncWorkoutEntries(tx, int64(workout.ID), workout.Entries)
 	if err != nil {
 		return err
-	} else if rowsAffected == 0 {
-		return sql.ErrNoRows
 	}
 
-	_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1`, workout.ID)
+	err = ValidateEntryGroups(workout.Entries)
 	if err != nil {
 		return err
 	}
 
-	for _, entry := range workout.Entries {
-		query := `
-		INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	err = replaceEntrySets(tx, entries)
+	if err != nil {
+		return err
+	}
 
-		`
-		_, err := tx.Exec(query,
-			workout.ID,
-			entry.ExerciseName,
-			entry.Sets,
-			entry.Reps,
-			entry.DurationSeconds,
-			entry.Weight,
-			entry.Notes,
-			entry.OrderIndex,
-		)
-		if err != nil {
-			return err
-		}
+	err = resolveEntryExercises(tx, int64(workout.ID), entries)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(tx, int64(workout.ID), authorID)
+	if err != nil {
+		return err
 	}
 
 	err = tx.Commit()
@@ -155,16 +243,26 @@ func (pg *PostgresWorkoutStore) UpdateWorkoutByID(workout *Workout) error {
 		return err
 	}
 
+	workout.Version = version
 	return nil
 }
 
-func (pg *PostgresWorkoutStore) DeleteWorkoutByID(id int64) error {
+// DeleteWorkoutByID moves the workout to the trash. It can be restored until
+// it is purged.
+func (pg *PostgresWorkoutStore) DeleteWorkoutByID(id int64, version int) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-	DELETE from workouts
-	WHERE id = $1
+	UPDATE workouts
+	SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL
 	`
 
-	result, err := pg.DBConn.Exec(query, id)
+	result, err := tx.Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -173,10 +271,26 @@ func (pg *PostgresWorkoutStore) DeleteWorkoutByID(id int64) error {
 	if err != nil {
 		return err
 	} else if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return pg.missingOrConflict(tx, id, false)
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// missingOrConflict tells apart why a versioned write matched no row:
+// either the workout doesn't exist or its version moved on. Trashed workouts
+// count as missing unless includeTrashed is set, for writes that reach them.
+func (pg *PostgresWorkoutStore) missingOrConflict(tx *sql.Tx, id int64, includeTrashed bool) error {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM workouts WHERE id = $1 AND ($2 OR deleted_at IS NULL))`, id, includeTrashed).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return ErrVersionConflict
 }
 
 func (pg *PostgresWorkoutStore) GetWorkoutOwner(id int64) (int, error) {
@@ -185,7 +299,68 @@ func (pg *PostgresWorkoutStore) GetWorkoutOwner(id int64) (int, error) {
 	query := `
 	SELECT user_id
 	FROM workouts
-	WHERE id = $1
+	WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	err := pg.DBConn.QueryRow(query, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (pg *PostgresWorkoutStore) ListDeletedWorkouts(userID int, limit, offset int) ([]Workout, error) {
+	query := `
+	SELECT ` + workoutColumns + `
+	FROM workouts w
+	WHERE w.user_id = $1 AND w.deleted_at IS NOT NULL
+	ORDER BY w.deleted_at DESC, w.id
+	LIMIT $2 OFFSET $3
+	`
+
+	return pg.queryWorkouts(query, userID, limit, offset)
+}
+
+func (pg *PostgresWorkoutStore) ListWorkouts(userID int, from, to time.Time, limit, offset int) ([]Workout, error) {
+	query := `
+	SELECT ` + workoutColumns + `
+	FROM workouts w
+	WHERE w.user_id = $1 AND w.deleted_at IS NULL
+		AND w.performed_at >= $2 AND w.performed_at < $3
+	ORDER BY w.performed_at DESC, w.id DESC
+	LIMIT $4 OFFSET $5
+	`
+
+	return pg.queryWorkouts(query, userID, from, to, limit, offset)
+}
+
+func (pg *PostgresWorkoutStore) queryWorkouts(query string, args ...any) ([]Workout, error) {
+	rows, err := pg.DBConn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workouts := []Workout{}
+	for rows.Next() {
+		workout, err := scanWorkout(rows)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, *workout)
+	}
+
+	return workouts, rows.Err()
+}
+
+func (pg *PostgresWorkoutStore) GetDeletedWorkoutOwner(id int64) (int, error) {
+	var userID int
+
+	query := `
+	SELECT user_id
+	FROM workouts
+	WHERE id = $1 AND deleted_at IS NOT NULL
 	`
 
 	err := pg.DBConn.QueryRow(query, id).Scan(&userID)
@@ -195,3 +370,948 @@ func (pg *PostgresWorkoutStore) GetWorkoutOwner(id int64) (int, error) {
 
 	return userID, nil
 }
+
+// RestoreWorkoutByID takes a workout out of the trash and returns its new
+// version.
+func (pg *PostgresWorkoutStore) RestoreWorkoutByID(id int64) (int, error) {
+	query := `
+	UPDATE workouts
+	SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND deleted_at IS NOT NULL
+	RETURNING version
+	`
+
+	var version int
+	err := pg.DBConn.QueryRow(query, id).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// PurgeWorkoutByID permanently deletes a workout, trashed or not, along with
+// its entries.
+func (pg *PostgresWorkoutStore) PurgeWorkoutByID(id int64, version int) error {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM workouts WHERE id = $1 AND version = $2`, id, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return pg.missingOrConflict(tx, id, true)
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedWorkouts permanently deletes the workouts trashed before
+// deletedBefore and returns how many there were.
+func (pg *PostgresWorkoutStore) PurgeDeletedWorkouts(deletedBefore time.Time) (int64, error) {
+	result, err := pg.DBConn.Exec(`DELETE FROM workouts WHERE deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+const workoutEntryColumns = `e.id, e.exercise_id, e.exercise_name, e.sets, e.reps, e.duration_seconds, e.weight, e.weight_unit, e.notes, e.order_index,
+	e.group_id, e.group_type, e.group_rounds, e.group_rest_seconds`
+
+func scanWorkoutEntry(row rowScanner) (*WorkoutEntry, error) {
+	entry := &WorkoutEntry{}
+	var notes, groupType sql.NullString
+	var groupID sql.NullInt64
+	var groupRounds, groupRestSeconds *int
+	err := row.Scan(
+		&entry.ID,
+		&entry.ExerciseID,
+		&entry.ExerciseName,
+		&entry.Sets,
+		&entry.Reps,
+		&entry.DurationSeconds,
+		&entry.Weight,
+		&entry.WeightUnit,
+		&notes,
+		&entry.OrderIndex,
+		&groupID,
+		&groupType,
+		&groupRounds,
+		&groupRestSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Notes = notes.String
+	if groupID.Valid {
+		entry.Group = &EntryGroup{
+			ID:          int(groupID.Int64),
+			Type:        groupType.String,
+			Rounds:      groupRounds,
+			RestSeconds: groupRestSeconds,
+		}
+	}
+	return entry, nil
+}
+
+// checkEntryGroups validates the groups of the workout's entries as written
+// so far in tx.
+func checkEntryGroups(tx *sql.Tx, workoutID int64) error {
+	rows, err := tx.Query(`SELECT `+workoutEntryColumns+` FROM workout_entries e WHERE e.workout_id = $1`, workoutID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var entries []WorkoutEntry
+	for rows.Next() {
+		entry, err := scanWorkoutEntry(rows)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, *entry)
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	return ValidateEntryGroups(entries)
+}
+
+// attachSets loads the set logs of the workout's entries into entries.
+func attachSets(q querier, workoutID int64, entries []WorkoutEntry) error {
+	query := `
+	SELECT s.entry_id, s.set_type, s.reps, s.duration_seconds, s.weight, s.rpe, s.rir, s.rest_seconds, s.completed
+	FROM workout_sets s
+	JOIN workout_entries e ON e.id = s.entry_id
+	WHERE e.workout_id = $1
+	ORDER BY s.entry_id, s.set_index
+	`
+
+	rows, err := q.Query(query, workoutID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*WorkoutEntry, len(entries))
+	for i := range entries {
+		byID[entries[i].ID] = &entries[i]
+	}
+
+	for rows.Next() {
+		var entryID int
+		set := WorkoutSet{Completed: new(bool)}
+		err = rows.Scan(&entryID, &set.Type, &set.Reps, &set.DurationSeconds, &set.Weight, &set.RPE, &set.RIR, &set.RestSeconds, set.Completed)
+		if err != nil {
+			return err
+		}
+
+		if entry, ok := byID[entryID]; ok {
+			entry.SetDetails = append(entry.SetDetails, set)
+		}
+	}
+
+	return rows.Err()
+}
+
+// keepStoredSets gives the entries that have an ID but no SetDetails the set
+// log stored for them, so that writing an entry without set_details keeps
+// its log and the fields derived from it. An empty SetDetails clears it.
+func keepStoredSets(tx *sql.Tx, workoutID int64, entries []*WorkoutEntry) error {
+	var kept []*WorkoutEntry
+	var stored []WorkoutEntry
+	for _, entry := range entries {
+		if entry.ID != 0 && entry.SetDetails == nil {
+			kept = append(kept, entry)
+			stored = append(stored, WorkoutEntry{ID: entry.ID})
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+
+	err := attachSets(tx, workoutID, stored)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range kept {
+		entry.SetDetails = stored[i].SetDetails
+	}
+	return nil
+}
+
+// replaceEntrySets replaces the stored set logs of entries, which must have
+// their IDs, with their SetDetails in one statement.
+func replaceEntrySets(tx *sql.Tx, entries []*WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var entryIDs, setEntryIDs []int64
+	var indexes []int
+	var types []string
+	var reps, durationsSeconds, rirs, restsSeconds []*int
+	var weights, rpes []*float64
+	var completed []*bool
+	for _, entry := range entries {
+		entryIDs = append(entryIDs, int64(entry.ID))
+		for i, set := range entry.SetDetails {
+			setEntryIDs = append(setEntryIDs, int64(entry.ID))
+			indexes = append(indexes, i+1)
+			types = append(types, set.Type)
+			reps = append(reps, set.Reps)
+			durationsSeconds = append(durationsSeconds, set.DurationSeconds)
+			weights = append(weights, set.Weight)
+			rpes = append(rpes, set.RPE)
+			rirs = append(rirs, set.RIR)
+			restsSeconds = append(restsSeconds, set.RestSeconds)
+			completed = append(completed, set.Completed)
+		}
+	}
+
+	_, err := tx.Exec(`DELETE FROM workout_sets WHERE entry_id = ANY($1)`, entryIDs)
+	if err != nil {
+		return err
+	}
+	if len(setEntryIDs) == 0 {
+		return nil
+	}
+
+	query := `
+	INSERT INTO workout_sets (entry_id, set_index, set_type, reps, duration_seconds, weight, rpe, rir, rest_seconds, completed)
+	SELECT v.entry_id, v.set_index, v.set_type, v.reps, v.duration_seconds, v.weight, v.rpe, v.rir, v.rest_seconds, COALESCE(v.completed, TRUE)
+	FROM unnest($1::bigint[], $2::int[], $3::text[], $4::int[], $5::int[], $6::numeric[], $7::numeric[], $8::int[], $9::int[], $10::boolean[])
+		AS v(entry_id, set_index, set_type, reps, duration_seconds, weight, rpe, rir, rest_seconds, completed)
+	`
+
+	_, err = tx.Exec(query, setEntryIDs, indexes, types, reps, durationsSeconds, weights, rpes, rirs, restsSeconds, completed)
+	return err
+}
+
+func (pg *PostgresWorkoutStore) ListWorkoutEntries(workoutID int64) ([]WorkoutEntry, error) {
+	query := `
+	SELECT ` + workoutEntryColumns + `
+	FROM workout_entries e
+	JOIN workouts w ON w.id = e.workout_id AND w.deleted_at IS NULL
+	WHERE e.workout_id = $1
+	ORDER BY e.order_index, e.id
+	`
+
+	rows, err := pg.DBConn.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []WorkoutEntry{}
+	for rows.Next() {
+		entry, err := scanWorkoutEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachSets(pg.DBConn, workoutID, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (pg *PostgresWorkoutStore) GetWorkoutEntry(workoutID, entryID int64) (*WorkoutEntry, error) {
+	query := `
+	SELECT ` + workoutEntryColumns + `
+	FROM workout_entries e
+	JOIN workouts w ON w.id = e.workout_id AND w.deleted_at IS NULL
+	WHERE e.workout_id = $1 AND e.id = $2
+	`
+
+	entry, err := scanWorkoutEntry(pg.DBConn.QueryRow(query, workoutID, entryID))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []WorkoutEntry{*entry}
+	err = attachSets(pg.DBConn, workoutID, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entries[0], nil
+}
+
+// CreateWorkoutEntry inserts entry at its OrderIndex, shifting the entries
+// after it down. An OrderIndex of 0 or past the end appends the entry.
+func (pg *PostgresWorkoutStore) CreateWorkoutEntry(workoutID int64, version int, entry *WorkoutEntry, authorID int) (int, error) {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = SummarizeSets(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	newVersion, err := pg.bumpWorkoutVersion(tx, workoutID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	order, err := entryOrder(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+	INSERT INTO workout_entries (workout_id, exercise_id, exercise_name, sets, reps, duration_seconds, weight, weight_unit, notes, order_index,
+		group_id, group_type, group_rounds, group_rest_seconds)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING id
+	`
+
+	groupID, groupType, groupRounds, groupRestSeconds := entry.groupColumns()
+	var entryID int64
+	err = tx.QueryRow(query, workoutID, entry.ExerciseID, entry.ExerciseName, entry.Sets, entry.Reps, entry.DurationSeconds, entry.Weight, entry.weightUnit(), entry.Notes, len(order)+1,
+		groupID, groupType, groupRounds, groupRestSeconds,
+	).Scan(&entryID)
+	if err != nil {
+		return 0, err
+	}
+
+	order = moveEntry(append(order, entryID), entryID, entry.OrderIndex)
+	err = setEntryOrder(tx, workoutID, order)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkEntryGroups(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	entry.ID = int(entryID)
+	err = replaceEntrySets(tx, []*WorkoutEntry{entry})
+	if err != nil {
+		return 0, err
+	}
+
+	err = resolveEntryExercises(tx, workoutID, []*WorkoutEntry{entry})
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordRevision(tx, workoutID, authorID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	entry.OrderIndex = positionOf(order, entryID)
+	return newVersion, nil
+}
+
+// UpdateWorkoutEntry replaces the fields of an existing entry. A changed
+// OrderIndex moves the entry there; 0 keeps its current position.
+func (pg *PostgresWorkoutStore) UpdateWorkoutEntry(workoutID int64, version int, entry *WorkoutEntry, authorID int) (int, error) {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = keepStoredSets(tx, workoutID, []*WorkoutEntry{entry})
+	if err != nil {
+		return 0, err
+	}
+
+	err = SummarizeSets(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	newVersion, err := pg.bumpWorkoutVersion(tx, workoutID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+	UPDATE workout_entries
+	SET exercise_id = $1, exercise_name = $2, sets = $3, reps = $4, duration_seconds = $5, weight = $6, weight_unit = $7, notes = $8,
+		group_id = $9, group_type = $10, group_rounds = $11, group_rest_seconds = $12
+	WHERE workout_id = $13 AND id = $14
+	`
+
+	groupID, groupType, groupRounds, groupRestSeconds := entry.groupColumns()
+	result, err := tx.Exec(query, entry.ExerciseID, entry.ExerciseName, entry.Sets, entry.Reps, entry.DurationSeconds, entry.Weight, entry.weightUnit(), entry.Notes,
+		groupID, groupType, groupRounds, groupRestSeconds, workoutID, entry.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	} else if rowsAffected == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	order, err := entryOrder(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	order = moveEntry(order, int64(entry.ID), entry.OrderIndex)
+	err = setEntryOrder(tx, workoutID, order)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkEntryGroups(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = replaceEntrySets(tx, []*WorkoutEntry{entry})
+	if err != nil {
+		return 0, err
+	}
+
+	err = resolveEntryExercises(tx, workoutID, []*WorkoutEntry{entry})
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordRevision(tx, workoutID, authorID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	entry.OrderIndex = positionOf(order, int64(entry.ID))
+	return newVersion, nil
+}
+
+func (pg *PostgresWorkoutStore) DeleteWorkoutEntry(workoutID int64, version int, entryID int64, authorID int) (int, error) {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	newVersion, err := pg.bumpWorkoutVersion(tx, workoutID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1 AND id = $2`, workoutID, entryID)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	} else if rowsAffected == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	order, err := entryOrder(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = setEntryOrder(tx, workoutID, order)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordRevision(tx, workoutID, authorID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newVersion, tx.Commit()
+}
+
+// ReorderWorkoutEntries sets the order of the entries to the order of
+// entryIDs, which must name every entry of the workout exactly once.
+func (pg *PostgresWorkoutStore) ReorderWorkoutEntries(workoutID int64, version int, entryIDs []int64, authorID int) (int, error) {
+	tx, err := pg.DBConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	newVersion, err := pg.bumpWorkoutVersion(tx, workoutID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	order, err := entryOrder(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(order) != len(entryIDs) {
+		return 0, ErrInvalidEntryOrder
+	}
+	seen := make(map[int64]bool, len(entryIDs))
+	for _, id := range entryIDs {
+		if seen[id] || positionOf(order, id) == 0 {
+			return 0, ErrInvalidEntryOrder
+		}
+		seen[id] = true
+	}
+
+	err = setEntryOrder(tx, workoutID, entryIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkEntryGroups(tx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordRevision(tx, workoutID, authorID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newVersion, tx.Commit()
+}
+
+// bumpWorkoutVersion increments the workout version inside tx, which also
+// locks the workout row so concurrent entry changes are serialized.
+func (pg *PostgresWorkoutStore) bumpWorkoutVersion(tx *sql.Tx, workoutID int64, version int) (int, error) {
+	query := `
+	UPDATE workouts
+	SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+	RETURNING version
+	`
+
+	var newVersion int
+	err := tx.QueryRow(query, workoutID, version).Scan(&newVersion)
+	if err == sql.ErrNoRows {
+		return 0, pg.missingOrConflict(tx, workoutID, false)
+	}
+	return newVersion, err
+}
+
+// entryOrder returns the IDs of the workout's entries in their current order.
+func entryOrder(tx *sql.Tx, workoutID int64) ([]int64, error) {
+	rows, err := tx.Query(`SELECT id FROM workout_entries WHERE workout_id = $1 ORDER BY order_index, id`, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var order []int64
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, id)
+	}
+
+	return order, rows.Err()
+}
+
+// setEntryOrder numbers the entries 1..n following the order of ids, which
+// keeps order_index contiguous whatever it was before.
+func setEntryOrder(tx *sql.Tx, workoutID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+	UPDATE workout_entries AS e
+	SET order_index = o.position
+	FROM unnest($2::bigint[]) WITH ORDINALITY AS o(id, position)
+	WHERE e.workout_id = $1 AND e.id = o.id AND e.order_index <> o.position
+	`
+
+	_, err := tx.Exec(query, workoutID, ids)
+	return err
+}
+
+// moveEntry moves id to the 1-based position in order. Positions out of
+// range leave it where it is, except past the end which moves it last.
+func moveEntry(order []int64, id int64, position int) []int64 {
+	current := positionOf(order, id)
+	if position <= 0 || current == 0 || position == current {
+		return order
+	}
+	if position > len(order) {
+		position = len(order)
+	}
+
+	moved := make([]int64, 0, len(order))
+	for _, other := range order {
+		if other != id {
+			moved = append(moved, other)
+		}
+	}
+	moved = append(moved[:position-1], append([]int64{id}, moved[position-1:]...)...)
+	return moved
+}
+
+// positionOf returns the 1-based position of id in order, or 0.
+func positionOf(order []int64, id int64) int {
+	for i, other := range order {
+		if other == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// syncWorkoutEntries makes the stored entries of a workout match entries
+// with one batched statement per kind of change, so IDs and created_at of
+// unchanged entries survive the update.
+func syncWorkoutEntries(tx *sql.Tx, workoutID int64, entries []WorkoutEntry) error {
+	storedIDs, err := entryOrder(tx, workoutID)
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[int64]bool, len(entries))
+	var updates, inserts []*WorkoutEntry
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ID == 0 {
+			inserts = append(inserts, entry)
+			continue
+		}
+
+		id := int64(entry.ID)
+		if listed[id] || !slices.Contains(storedIDs, id) {
+			return fmt.Errorf("%w: %d", ErrInvalidEntryID, id)
+		}
+		listed[id] = true
+		updates = append(updates, entry)
+	}
+
+	var removed []int64
+	for _, id := range storedIDs {
+		if !listed[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	if len(removed) > 0 {
+		_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1 AND id = ANY($2)`, workoutID, removed)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = updateWorkoutEntries(tx, workoutID, updates)
+	if err != nil {
+		return err
+	}
+
+	return insertWorkoutEntries(tx, workoutID, inserts)
+}
+
+// entryArrays holds entry fields column by column, to be passed to unnest.
+type entryArrays struct {
+	ids              []int64
+	exerciseIDs      []*int64
+	exerciseNames    []string
+	sets             []int
+	reps             []*int
+	durationsSeconds []*int
+	weights          []*float64
+	weightUnits      []string
+	notes            []string
+	orderIndexes     []int
+	groupIDs         []*int
+	groupTypes       []*string
+	groupRounds      []*int
+	groupRests       []*int
+}
+
+func newEntryArrays(entries []*WorkoutEntry) entryArrays {
+	var a entryArrays
+	for _, entry := range entries {
+		a.ids = append(a.ids, int64(entry.ID))
+		a.exerciseIDs = append(a.exerciseIDs, entry.ExerciseID)
+		a.exerciseNames = append(a.exerciseNames, entry.ExerciseName)
+		a.sets = append(a.sets, entry.Sets)
+		a.reps = append(a.reps, entry.Reps)
+		a.durationsSeconds = append(a.durationsSeconds, entry.DurationSeconds)
+		a.weights = append(a.weights, entry.Weight)
+		a.weightUnits = append(a.weightUnits, entry.weightUnit())
+		a.notes = append(a.notes, entry.Notes)
+		a.orderIndexes = append(a.orderIndexes, entry.OrderIndex)
+
+		groupID, groupType, groupRounds, groupRestSeconds := entry.groupColumns()
+		a.groupIDs = append(a.groupIDs, groupID)
+		a.groupTypes = append(a.groupTypes, groupType)
+		a.groupRounds = append(a.groupRounds, groupRounds)
+		a.groupRests = append(a.groupRests, groupRestSeconds)
+	}
+	return a
+}
+
+// weightUnit returns the weight_unit column value of entry, kilograms when
+// it doesn't say.
+func (entry *WorkoutEntry) weightUnit() string {
+	if entry.WeightUnit == "" {
+		return string(units.Kilograms)
+	}
+	return string(entry.WeightUnit)
+}
+
+// groupColumns returns the group_* column values of entry, all nil when it
+// isn't grouped.
+func (entry *WorkoutEntry) groupColumns() (*int, *string, *int, *int) {
+	if entry.Group == nil {
+		return nil, nil, nil, nil
+	}
+	return &entry.Group.ID, &entry.Group.Type, entry.Group.Rounds, entry.Group.RestSeconds
+}
+
+func updateWorkoutEntries(tx *sql.Tx, workoutID int64, entries []*WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `
+	UPDATE workout_entries AS e
+	SET exercise_id = v.exercise_id, exercise_name = v.exercise_name, sets = v.sets, reps = v.reps,
+		duration_seconds = v.duration_seconds, weight = v.weight, weight_unit = v.weight_unit, notes = v.notes, order_index = v.order_index,
+		group_id = v.group_id, group_type = v.group_type, group_rounds = v.group_rounds, group_rest_seconds = v.group_rest_seconds
+	FROM unnest($2::bigint[], $3::bigint[], $4::text[], $5::int[], $6::int[], $7::int[], $8::numeric[], $9::text[], $10::text[], $11::int[],
+		$12::int[], $13::text[], $14::int[], $15::int[])
+		AS v(id, exercise_id, exercise_name, sets, reps, duration_seconds, weight, weight_unit, notes, order_index,
+			group_id, group_type, group_rounds, group_rest_seconds)
+	WHERE e.workout_id = $1 AND e.id = v.id
+	`
+
+	a := newEntryArrays(entries)
+	_, err := tx.Exec(query, workoutID, a.ids, a.exerciseIDs, a.exerciseNames, a.sets, a.reps, a.durationsSeconds, a.weights, a.weightUnits, a.notes, a.orderIndexes,
+		a.groupIDs, a.groupTypes, a.groupRounds, a.groupRests)
+	return err
+}
+
+// insertWorkoutEntries inserts entries in a single statement and writes the
+// new IDs back. The IDs are drawn before the insert, next to the position of
+// their entry, so they are matched to entries without relying on the order
+// rows are inserted or returned in.
+func insertWorkoutEntries(tx *sql.Tx, workoutID int64, entries []*WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `
+	WITH v AS (
+		SELECT nextval('workout_entries_id_seq') AS id, u.*
+		FROM unnest($2::bigint[], $3::text[], $4::int[], $5::int[], $6::int[], $7::numeric[], $8::text[], $9::text[], $10::int[],
+			$11::int[], $12::text[], $13::int[], $14::int[])
+			WITH ORDINALITY AS u(exercise_id, exercise_name, sets, reps, duration_seconds, weight, weight_unit, notes, order_index,
+				group_id, group_type, group_rounds, group_rest_seconds, position)
+	), inserted AS (
+		INSERT INTO workout_entries (id, workout_id, exercise_id, exercise_name, sets, reps, duration_seconds, weight, weight_unit, notes, order_index,
+			group_id, group_type, group_rounds, group_rest_seconds)
+		SELECT v.id, $1, v.exercise_id, v.exercise_name, v.sets, v.reps, v.duration_seconds, v.weight, v.weight_unit, v.notes, v.order_index,
+			v.group_id, v.group_type, v.group_rounds, v.group_rest_seconds
+		FROM v
+		RETURNING id
+	)
+	SELECT v.position, v.id
+	FROM v
+	JOIN inserted ON inserted.id = v.id
+	`
+
+	a := newEntryArrays(entries)
+	rows, err := tx.Query(query, workoutID, a.exerciseIDs, a.exerciseNames, a.sets, a.reps, a.durationsSeconds, a.weights, a.weightUnits, a.notes, a.orderIndexes,
+		a.groupIDs, a.groupTypes, a.groupRounds, a.groupRests)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var position, id int64
+		err = rows.Scan(&position, &id)
+		if err != nil {
+			return err
+		}
+		entries[position-1].ID = int(id)
+	}
+	return rows.Err()
+}
+
+// recordRevision snapshots the workout as written so far in tx, so the
+// revision commits or rolls back together with the change it records.
+func recordRevision(tx *sql.Tx, workoutID int64, authorID int) error {
+	workout, err := getWorkout(tx, workoutID)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(workout)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO workout_revisions (workout_id, version, author_id, snapshot)
+	VALUES ($1, $2, $3, $4)
+	`
+
+	_, err = tx.Exec(query, workoutID, workout.Version, authorID, snapshot)
+	return err
+}
+
+func (pg *PostgresWorkoutStore) ListWorkoutRevisions(workoutID int64) ([]WorkoutRevision, error) {
+	query := `
+	SELECT r.version, r.author_id, r.created_at
+	FROM workout_revisions r
+	JOIN workouts w ON w.id = r.workout_id AND w.deleted_at IS NULL
+	WHERE r.workout_id = $1
+	ORDER BY r.version DESC
+	`
+
+	rows, err := pg.DBConn.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []WorkoutRevision{}
+	for rows.Next() {
+		revision := WorkoutRevision{WorkoutID: workoutID}
+		err = rows.Scan(&revision.Version, &revision.AuthorID, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (pg *PostgresWorkoutStore) GetWorkoutRevision(workoutID int64, version int) (*WorkoutRevision, error) {
+	query := `
+	SELECT r.version, r.author_id, r.created_at, r.snapshot
+	FROM workout_revisions r
+	JOIN workouts w ON w.id = r.workout_id AND w.deleted_at IS NULL
+	WHERE r.workout_id = $1 AND r.version = $2
+	`
+
+	revision := &WorkoutRevision{WorkoutID: workoutID}
+	var snapshot []byte
+	err := pg.DBConn.QueryRow(query, workoutID, version).Scan(&revision.Version, &revision.AuthorID, &revision.CreatedAt, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(snapshot, &revision.Workout)
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// resolveEntryExercises links the entries of a workout to the exercise
+// catalog. Entries that name an exercise ID keep it if the workout owner can
+// see that exercise, the others are matched by name or alias, preferring the
+// owner's custom exercises and exact names. The links are written back to
+// entries.
+func resolveEntryExercises(tx *sql.Tx, workoutID int64, entries []*WorkoutEntry) error {
+	query := `
+	UPDATE workout_entries e
+	SET exercise_id = CASE WHEN visible THEN e.exercise_id END,
+		exercise_name = CASE WHEN visible AND e.exercise_name = '' THEN x.name ELSE e.exercise_name END
+	FROM workouts w, exercises x, LATERAL (SELECT x.owner_id IS NULL OR x.owner_id = w.user_id AS visible) AS v
+	WHERE e.workout_id = $1 AND w.id = e.workout_id AND x.id = e.exercise_id
+	`
+	_, err := tx.Exec(query, workoutID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+	UPDATE workout_entries e
+	SET exercise_id = (
+		SELECT x.id
+		FROM exercises x
+		WHERE (x.owner_id IS NULL OR x.owner_id = w.user_id)
+			AND (normalize_exercise_name(x.name) = normalize_exercise_name(e.exercise_name)
+				OR normalize_exercise_name(e.exercise_name) IN (SELECT normalize_exercise_name(a) FROM unnest(x.aliases) AS a))
+		ORDER BY x.owner_id IS NULL, normalize_exercise_name(x.name) = normalize_exercise_name(e.exercise_name) DESC, x.id
+		LIMIT 1
+	)
+	FROM workouts w
+	WHERE e.workout_id = $1 AND w.id = e.workout_id AND e.exercise_id IS NULL
+	`
+	_, err = tx.Exec(query, workoutID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, exercise_id, exercise_name FROM workout_entries WHERE workout_id = $1`, workoutID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*WorkoutEntry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID] = entry
+	}
+
+	for rows.Next() {
+		var id int
+		var exerciseID *int64
+		var exerciseName string
+		err = rows.Scan(&id, &exerciseID, &exerciseName)
+		if err != nil {
+			return err
+		}
+
+		if entry, ok := byID[id]; ok {
+			entry.ExerciseID = exerciseID
+			entry.ExerciseName = exerciseName
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/units"
+)
+
+var (
+	// ErrUnknownTemplate is returned when a program day references a
+	// template that doesn't exist or doesn't belong to the program owner.
+	ErrUnknownTemplate = errors.New("store: unknown template")
+	// ErrUnknownExercise is returned when a program references an exercise
+	// the program owner can't see.
+	ErrUnknownExercise = errors.New("store: unknown exercise")
+	// ErrInUse is returned when deleting a row others still reference: a
+	// template used by a program, a program someone is enrolled in.
+	ErrInUse = errors.New("store: still in use")
+)
+
+const (
+	EnrollmentActive    = "active"
+	EnrollmentCompleted = "completed"
+)
+
+// Program is a multi-week plan of sessions, each performed from a template.
+// Rounding and the progression increments are in Unit.
+type Program struct {
+	ID           int64             `json:"id"`
+	OwnerID      int               `json:"owner_id"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	Weeks        int               `json:"weeks"`
+	Public       bool              `json:"public"`
+	Rounding     float64           `json:"rounding"`
+	Unit         units.Unit        `json:"unit"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Days         []ProgramDay      `json:"days"`
+	Progressions []ProgressionRule `json:"progressions"`
+}
+
+// ProgramDay is the session of a program performed on a day of a week.
+// Prescriptions replace the sets, reps and weight of the template entries
+// of the same exercise.
+type ProgramDay struct {
+	Week          int            `json:"week"`
+	Day           int            `json:"day"`
+	TemplateID    int64          `json:"template_id"`
+	Prescriptions []Prescription `json:"prescriptions"`
+}
+
+// Prescription sets the work of an exercise, either at a percentage of the
+// training max or at an RPE. AMRAP means the last set goes for as many reps
+// as possible, Reps being the minimum.
+type Prescription struct {
+	ExerciseID       int64    `json:"exercise_id"`
+	Sets             int      `json:"sets"`
+	Reps             int      `json:"reps"`
+	PercentOneRepMax *float64 `json:"percent_1rm,omitempty"`
+	RPE              *float64 `json:"rpe,omitempty"`
+	AMRAP            bool     `json:"amrap"`
+}
+
+// ProgressionRule raises the training max of an exercise by Increment each
+// time a session meets all its prescriptions for it.
+type ProgressionRule struct {
+	ExerciseID int64   `json:"exercise_id"`
+	Increment  float64 `json:"increment"`
+}
+
+// TrainingMax weights are in kilograms.
+type TrainingMax struct {
+	ExerciseID int64   `json:"exercise_id"`
+	Weight     float64 `json:"weight"`
+}
+
+// Enrollment is a user following a program. Week and Day point to the next
+// session to perform.
+type Enrollment struct {
+	ID            int64         `json:"id"`
+	UserID        int           `json:"user_id"`
+	ProgramID     int64         `json:"program_id"`
+	Status        string        `json:"status"`
+	Week          int           `json:"week"`
+	Day           int           `json:"day"`
+	StartedAt     time.Time     `json:"started_at"`
+	CompletedAt   *time.Time    `json:"completed_at"`
+	TrainingMaxes []TrainingMax `json:"training_maxes"`
+}
+
+type ProgramStore interface {
+	// ListPrograms returns the programs of userID and the public ones,
+	// without their days and progressions.
+	ListPrograms(userID int, limit, offset int) ([]Program, error)
+	GetProgramByID(id int64) (*Program, error)
+	CreateProgram(program *Program) error
+	// UpdateProgram replaces the program, its days and its progressions.
+	UpdateProgram(program *Program) error
+	DeleteProgram(id int64) error
+
+	// CreateEnrollment stores the training maxes given and defaults the
+	// missing ones to 90% of the user's estimated 1RM record.
+	CreateEnrollment(enrollment *Enrollment, exerciseIDs []int64) error
+	ListEnrollments(userID int) ([]Enrollment, error)
+	GetEnrollment(id int64) (*Enrollment, error)
+	DeleteEnrollment(id int64) error
+	// CompleteSession records that workoutID performed the current session
+	// of the enrollment, raises the training maxes of increments, and moves
+	// the enrollment to next, or completes it when next is nil. It returns
+	// ErrVersionConflict when the session was completed meanwhile.
+	CompleteSession(enrollment *Enrollment, workoutID int64, increments []TrainingMax, next *ProgramDay) error
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/strength"
+)
+
+// PersonalRecord weights are in kilograms, as are the values of weight, 1RM
+// and volume records.
+type PersonalRecord struct {
+	ID           int64               `json:"id"`
+	ExerciseID   *int64              `json:"exercise_id"`
+	ExerciseName string              `json:"exercise_name"`
+	Type         strength.RecordType `json:"type"`
+	Weight       *float64            `json:"weight,omitempty"`
+	Value        float64             `json:"value"`
+	WorkoutID    int64               `json:"workout_id"`
+	EntryID      *int64              `json:"entry_id"`
+	AchievedAt   time.Time           `json:"achieved_at"`
+}
+
+type RecordStore interface {
+	// ReplaceWorkoutRecords replaces the bests stored for a workout with
+	// candidates. It returns the workout's PRs, the bests that beat every
+	// workout performed before it.
+	ReplaceWorkoutRecords(userID int, workoutID int64, achievedAt time.Time, candidates []strength.Record) ([]PersonalRecord, error)
+	// ListRecords returns the current best of every record of the user.
+	ListRecords(userID int) ([]PersonalRecord, error)
+	// ListRecordHistory returns every record the user set for an exercise,
+	// oldest first: the bests that beat every earlier workout.
+	ListRecordHistory(userID int, exerciseKey string) ([]PersonalRecord, error)
+}
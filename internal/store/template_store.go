@@ -0,0 +1,59 @@
+package store
+
+import "time"
+
+// WorkoutTemplate is a session users repeat: the entries to perform, without
+// the times, duration or calories of a performed workout.
+type WorkoutTemplate struct {
+	ID          int64          `json:"id"`
+	UserID      int            `json:"user_id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Entries     []WorkoutEntry `json:"entries"`
+}
+
+// NewWorkout returns a new workout of userID pre-filled with the template.
+func (t *WorkoutTemplate) NewWorkout(userID int) *Workout {
+	return &Workout{
+		UserID:      userID,
+		Title:       t.Title,
+		Description: t.Description,
+		Entries:     copyEntries(t.Entries),
+	}
+}
+
+// NewTemplateFromWorkout returns a new template of userID with the title,
+// description and entries of workout.
+func NewTemplateFromWorkout(workout *Workout, userID int) *WorkoutTemplate {
+	return &WorkoutTemplate{
+		UserID:      userID,
+		Title:       workout.Title,
+		Description: workout.Description,
+		Entries:     copyEntries(workout.Entries),
+	}
+}
+
+// copyEntries copies entries without their IDs, so that they can be inserted
+// elsewhere. Templates don't keep set logs, only their summary.
+func copyEntries(entries []WorkoutEntry) []WorkoutEntry {
+	copied := make([]WorkoutEntry, len(entries))
+	for i, entry := range entries {
+		entry.ID = 0
+		entry.SetDetails = nil
+		copied[i] = entry
+	}
+	return copied
+}
+
+type TemplateStore interface {
+	// ListTemplates returns the templates of a user without their entries.
+	ListTemplates(userID int, limit, offset int) ([]WorkoutTemplate, error)
+	GetTemplateByID(id int64) (*WorkoutTemplate, error)
+	CreateTemplate(template *WorkoutTemplate) error
+	// UpdateTemplate replaces the title, description and entries.
+	UpdateTemplate(template *WorkoutTemplate) error
+	// DeleteTemplate returns ErrInUse while a program uses the template.
+	DeleteTemplate(id int64) error
+}
@@ -1,27 +1,45 @@
 package store
 
 import (
-	"errors"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/gbuenodev/goProject/internal/passwords"
+	"github.com/gbuenodev/goProject/internal/units"
+)
+
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
 )
 
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash password  `json:"-"`
-	Bio          string    `json:"bio"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int      `json:"id"`
+	Username     string   `json:"username"`
+	Email        string   `json:"email"`
+	PasswordHash password `json:"-"`
+	Bio          string   `json:"bio"`
+	Role         string   `json:"role"`
+	Timezone     string   `json:"timezone"`
+	// PreferredUnit is the unit weights are logged in when a request doesn't
+	// say, and the unit records and analytics are reported in.
+	PreferredUnit units.Unit `json:"preferred_unit"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 type UserStore interface {
 	CreateUser(user *User) error
+	GetUserByID(id int64) (*User, error)
 	GetUserByUsername(username string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
 	UpdateUser(user *User) error
+	UpdatePasswordHash(user *User) error
 	GetUserToken(scope, tokenPlainText string) (*User, error)
+	ListUsers(limit, offset int) ([]User, error)
+	SetUserRole(id int64, role string) error
+	SetUserSuspended(id int64, suspended bool) error
 }
 
 var AnonymousUser = &User{}
@@ -30,13 +48,44 @@ func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+func (u *User) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role can be assigned to a user. RoleCoach is
+// left out until coaches can be linked to athletes, as it grants nothing a
+// user doesn't have.
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// SimulatePasswordCheck does the same amount of work as checking a password.
+// It is used when a login names a user that does not exist, so the response
+// takes as long as a wrong password does.
+func SimulatePasswordCheck(plainText string) {
+	passwords.Default().SimulateMatches(plainText)
+}
+
 type password struct {
 	plainText *string
 	hash      []byte
 }
 
 func (p *password) Set(plainText string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plainText), bcrypt.DefaultCost)
+	hash, err := passwords.Default().Hash(plainText)
 	if err != nil {
 		return err
 	}
@@ -47,14 +96,11 @@ func (p *password) Set(plainText string) error {
 }
 
 func (p *password) Matches(plainText string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plainText))
-	if err != nil {
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, nil
-		default:
-			return false, err
-		}
-	}
-	return true, nil
+	return passwords.Default().Matches(plainText, p.hash)
+}
+
+// NeedsRehash reports whether the stored hash uses an outdated algorithm or
+// parameters and should be replaced after the next successful login.
+func (p *password) NeedsRehash() bool {
+	return passwords.Default().NeedsRehash(p.hash)
 }
@@ -1,5 +1,55 @@
 package store
 
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/units"
+)
+
+// ErrVersionConflict is returned when a workout was modified since the
+// version the caller based its change on.
+var ErrVersionConflict = errors.New("store: version conflict")
+
+// ErrInvalidEntryOrder is returned when a reorder doesn't list every entry
+// of the workout exactly once.
+var ErrInvalidEntryOrder = errors.New("store: entry order must list every entry exactly once")
+
+// ErrInvalidEntryID is returned when an update lists an entry ID that
+// doesn't belong to the workout, or lists it more than once.
+var ErrInvalidEntryID = errors.New("store: entry id is unknown or repeated")
+
+// ErrInvalidEntryGroup is returned when entry groups are malformed, aren't
+// contiguous or disagree on their settings.
+var ErrInvalidEntryGroup = errors.New("store: invalid entry group")
+
+// Entry group types.
+const (
+	GroupSuperset = "superset"
+	GroupCircuit  = "circuit"
+	GroupGiantSet = "giant_set"
+	GroupEMOM     = "emom"
+	GroupAMRAP    = "amrap"
+)
+
+var groupTypes = []string{GroupSuperset, GroupCircuit, GroupGiantSet, GroupEMOM, GroupAMRAP}
+
+// ErrInvalidSet is returned when a set of an entry's set log is malformed.
+var ErrInvalidSet = errors.New("store: invalid set")
+
+// Set types.
+const (
+	SetWarmUp  = "warm_up"
+	SetWorking = "working"
+	SetDrop    = "drop"
+	SetFailure = "failure"
+)
+
+var setTypes = []string{SetWarmUp, SetWorking, SetDrop, SetFailure}
+
 type Workout struct {
 	ID              int            `json:"id"`
 	UserID          int            `json:"user_id"`
@@ -7,24 +57,242 @@ type Workout struct {
 	Description     string         `json:"description"`
 	DurationMinutes int            `json:"duration_minutes"`
 	CaloriesBurned  int            `json:"calories_burned"`
+	Version         int            `json:"version"`
+	PerformedAt     time.Time      `json:"performed_at"`
+	Timezone        string         `json:"timezone"`
+	StartedAt       *time.Time     `json:"started_at"`
+	EndedAt         *time.Time     `json:"ended_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       *time.Time     `json:"deleted_at,omitempty"`
 	Entries         []WorkoutEntry `json:"entries"`
 }
 
+// WorkoutEntry weights, including those of its set log, are in kilograms.
+// WeightUnit records the unit the entry was logged in.
 type WorkoutEntry struct {
-	ID              int      `json:"id"`
-	ExerciseName    string   `json:"exercise_name"`
-	Sets            int      `json:"sets"`
+	ID              int         `json:"id"`
+	ExerciseID      *int64      `json:"exercise_id"`
+	ExerciseName    string      `json:"exercise_name"`
+	Sets            int         `json:"sets"`
+	Reps            *int        `json:"reps"`
+	DurationSeconds *int        `json:"duration_seconds"`
+	Weight          *float64    `json:"weight"`
+	WeightUnit      units.Unit  `json:"weight_unit"`
+	// LoggedUnit is only used by the API, which reads weights in a unit
+	// other than WeightUnit: it then reports the unit the entry was logged
+	// in, and keeps it when the entry is written back.
+	LoggedUnit units.Unit `json:"logged_unit,omitempty"`
+	Notes           string      `json:"notes"`
+	OrderIndex      int         `json:"order_index"`
+	Group           *EntryGroup `json:"group"`
+	// SetDetails logs the entry set by set. When present, Sets, Reps,
+	// DurationSeconds and Weight are derived from it by SummarizeSets.
+	SetDetails []WorkoutSet `json:"set_details,omitempty"`
+}
+
+// WorkoutSet is one set of an entry's set log. Sets are identified by their
+// position in the log.
+type WorkoutSet struct {
+	Type            string   `json:"type"`
 	Reps            *int     `json:"reps"`
 	DurationSeconds *int     `json:"duration_seconds"`
 	Weight          *float64 `json:"weight"`
-	Notes           string   `json:"notes"`
-	OrderIndex      int      `json:"order_index"`
+	RPE             *float64 `json:"rpe"`
+	RIR             *int     `json:"rir"`
+	RestSeconds     *int     `json:"rest_seconds"`
+	Completed       *bool    `json:"completed"`
+}
+
+// Counts reports whether the set counts as performed work: a completed set
+// that isn't a warm-up.
+func (set *WorkoutSet) Counts() bool {
+	return set.Type != SetWarmUp && (set.Completed == nil || *set.Completed)
+}
+
+// SummarizeSets validates the set log of entry, defaults the type of its
+// sets to working and completed to true, and derives the legacy fields for
+// clients that don't read the log: Sets counts the sets that count as
+// performed, and Reps, DurationSeconds and Weight are those of the top set
+// among them, the heaviest then the longest. An entry with no performed set
+// yet is summarized from its planned sets instead, warm-ups last. Entries
+// without a set log are left as they are.
+func SummarizeSets(entry *WorkoutEntry) error {
+	if len(entry.SetDetails) == 0 {
+		return nil
+	}
+
+	timed := entry.SetDetails[0].DurationSeconds != nil
+	for i := range entry.SetDetails {
+		set := &entry.SetDetails[i]
+		if set.Type == "" {
+			set.Type = SetWorking
+		}
+		if set.Completed == nil {
+			completed := true
+			set.Completed = &completed
+		}
+
+		switch {
+		case !slices.Contains(setTypes, set.Type):
+			return fmt.Errorf("%w: unknown type %q in set %d", ErrInvalidSet, set.Type, i+1)
+		case (set.Reps == nil) == (set.DurationSeconds == nil):
+			return fmt.Errorf("%w: set %d needs exactly one of reps or duration_seconds", ErrInvalidSet, i+1)
+		case (set.DurationSeconds != nil) != timed:
+			return fmt.Errorf("%w: sets of an entry must all have reps or all have duration_seconds", ErrInvalidSet)
+		case set.Reps != nil && *set.Reps < 0, set.DurationSeconds != nil && *set.DurationSeconds < 0,
+			set.Weight != nil && *set.Weight < 0, set.RIR != nil && *set.RIR < 0, set.RestSeconds != nil && *set.RestSeconds < 0:
+			return fmt.Errorf("%w: set %d has a negative value", ErrInvalidSet, i+1)
+		case set.RPE != nil && (*set.RPE < 1 || *set.RPE > 10):
+			return fmt.Errorf("%w: rpe must be between 1 and 10 in set %d", ErrInvalidSet, i+1)
+		}
+	}
+
+	pick := func(keep func(set *WorkoutSet) bool) []*WorkoutSet {
+		var picked []*WorkoutSet
+		for i := range entry.SetDetails {
+			if keep(&entry.SetDetails[i]) {
+				picked = append(picked, &entry.SetDetails[i])
+			}
+		}
+		return picked
+	}
+
+	summarized := pick((*WorkoutSet).Counts)
+	if len(summarized) == 0 {
+		summarized = pick(func(set *WorkoutSet) bool { return set.Type != SetWarmUp })
+	}
+	if len(summarized) == 0 {
+		summarized = pick(func(*WorkoutSet) bool { return true })
+	}
+
+	top := slices.MaxFunc(summarized, func(a, b *WorkoutSet) int {
+		return cmp.Or(
+			cmp.Compare(valueOr(a.Weight), valueOr(b.Weight)),
+			cmp.Compare(valueOr(a.Reps), valueOr(b.Reps)),
+			cmp.Compare(valueOr(a.DurationSeconds), valueOr(b.DurationSeconds)),
+		)
+	})
+
+	entry.Sets = len(summarized)
+	entry.Reps = top.Reps
+	entry.DurationSeconds = top.DurationSeconds
+	entry.Weight = top.Weight
+	return nil
+}
+
+func valueOr[T int | float64](value *T) T {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// EntryGroup puts an entry in a superset, circuit or other group with the
+// entries next to it. ID only identifies the group within its workout, and
+// every entry of the group repeats the same settings.
+type EntryGroup struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	Rounds      *int   `json:"rounds"`
+	RestSeconds *int   `json:"rest_seconds"`
+}
+
+// ValidateEntryGroups checks the groups of entries, taken in order_index
+// order: each group must have a known type, positive rounds, non-negative
+// rest, occupy consecutive positions and have the same settings on all of
+// its entries. Ties in order_index are broken by ID, as they are when the
+// entries are read back.
+func ValidateEntryGroups(entries []WorkoutEntry) error {
+	ordered := slices.Clone(entries)
+	slices.SortStableFunc(ordered, func(a, b WorkoutEntry) int {
+		return cmp.Or(cmp.Compare(a.OrderIndex, b.OrderIndex), cmp.Compare(a.ID, b.ID))
+	})
+
+	seen := make(map[int]*EntryGroup)
+	var previous *EntryGroup
+	for _, entry := range ordered {
+		group := entry.Group
+		if group == nil {
+			previous = nil
+			continue
+		}
+
+		first, ok := seen[group.ID]
+		switch {
+		case group.ID <= 0:
+			return fmt.Errorf("%w: group id must be positive", ErrInvalidEntryGroup)
+		case !slices.Contains(groupTypes, group.Type):
+			return fmt.Errorf("%w: unknown type %q in group %d", ErrInvalidEntryGroup, group.Type, group.ID)
+		case group.Rounds != nil && *group.Rounds <= 0:
+			return fmt.Errorf("%w: rounds must be positive in group %d", ErrInvalidEntryGroup, group.ID)
+		case group.RestSeconds != nil && *group.RestSeconds < 0:
+			return fmt.Errorf("%w: rest_seconds cannot be negative in group %d", ErrInvalidEntryGroup, group.ID)
+		case ok && (previous == nil || previous.ID != group.ID):
+			return fmt.Errorf("%w: entries of group %d are not contiguous", ErrInvalidEntryGroup, group.ID)
+		case ok && !sameGroupSettings(first, group):
+			return fmt.Errorf("%w: entries of group %d have different settings", ErrInvalidEntryGroup, group.ID)
+		}
+
+		if !ok {
+			seen[group.ID] = group
+		}
+		previous = group
+	}
+
+	return nil
+}
+
+func sameGroupSettings(a, b *EntryGroup) bool {
+	equal := func(x, y *int) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+	return a.Type == b.Type && equal(a.Rounds, b.Rounds) && equal(a.RestSeconds, b.RestSeconds)
+}
+
+// WorkoutRevision is an immutable snapshot of a workout and its entries as
+// they were right after a change, identified by the version it produced.
+type WorkoutRevision struct {
+	WorkoutID int64     `json:"workout_id"`
+	Version   int       `json:"version"`
+	AuthorID  *int      `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Workout   *Workout  `json:"workout,omitempty"`
 }
 
 type WorkoutStore interface {
 	CreateWorkout(*Workout) (*Workout, error)
 	GetWorkoutByID(id int64) (*Workout, error)
-	UpdateWorkoutByID(*Workout) error
-	DeleteWorkoutByID(id int64) error
+	// UpdateWorkoutByID and the entry methods record a revision on behalf
+	// of authorID. CreateWorkout attributes it to the workout's owner.
+	UpdateWorkoutByID(workout *Workout, authorID int) error
+	DeleteWorkoutByID(id int64, version int) error
 	GetWorkoutOwner(id int64) (int, error)
+	// ListWorkouts returns the workouts of a user performed in [from, to),
+	// most recent first and without their entries.
+	ListWorkouts(userID int, from, to time.Time, limit, offset int) ([]Workout, error)
+	// PurgeWorkoutByID permanently deletes a workout whether it is in the
+	// trash or not.
+	PurgeWorkoutByID(id int64, version int) error
+
+	// Trash methods only see deleted workouts, all the other methods treat
+	// them as missing.
+	ListDeletedWorkouts(userID int, limit, offset int) ([]Workout, error)
+	GetDeletedWorkoutOwner(id int64) (int, error)
+	RestoreWorkoutByID(id int64) (int, error)
+	PurgeDeletedWorkouts(deletedBefore time.Time) (int64, error)
+
+	// Entry methods change a single entry and bump the workout version,
+	// which they return. A stale version returns ErrVersionConflict.
+	ListWorkoutEntries(workoutID int64) ([]WorkoutEntry, error)
+	GetWorkoutEntry(workoutID, entryID int64) (*WorkoutEntry, error)
+	CreateWorkoutEntry(workoutID int64, version int, entry *WorkoutEntry, authorID int) (int, error)
+	UpdateWorkoutEntry(workoutID int64, version int, entry *WorkoutEntry, authorID int) (int, error)
+	DeleteWorkoutEntry(workoutID int64, version int, entryID int64, authorID int) (int, error)
+	ReorderWorkoutEntries(workoutID int64, version int, entryIDs []int64, authorID int) (int, error)
+
+	// ListWorkoutRevisions returns the revisions newest first, without
+	// their snapshots.
+	ListWorkoutRevisions(workoutID int64) ([]WorkoutRevision, error)
+	GetWorkoutRevision(workoutID int64, version int) (*WorkoutRevision, error)
 }
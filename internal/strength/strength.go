@@ -0,0 +1,168 @@
+// Package strength holds the training math: one-rep max estimates and the
+// personal records a workout sets.
+package strength
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+type Formula string
+
+const (
+	Epley   Formula = "epley"
+	Brzycki Formula = "brzycki"
+)
+
+// MaxEstimateReps is the highest rep count a one-rep max is estimated from.
+// Both formulas drift quickly past it.
+const MaxEstimateReps = 12
+
+func ParseFormula(name string) (Formula, error) {
+	switch Formula(strings.ToLower(name)) {
+	case "", Epley:
+		return Epley, nil
+	case Brzycki:
+		return Brzycki, nil
+	}
+	return "", fmt.Errorf("unknown formula %q", name)
+}
+
+// EstimateOneRepMax estimates the heaviest single a lifter could do from a
+// set of reps at weight. ok is false when reps is out of the range the
+// formulas are meaningful for.
+func EstimateOneRepMax(formula Formula, weight float64, reps int) (float64, bool) {
+	if weight <= 0 || reps < 1 || reps > MaxEstimateReps {
+		return 0, false
+	}
+	if reps == 1 {
+		return weight, true
+	}
+
+	switch formula {
+	case Brzycki:
+		return weight * 36 / float64(37-reps), true
+	default:
+		return weight * (1 + float64(reps)/30), true
+	}
+}
+
+type RecordType string
+
+const (
+	RecordHeaviestWeight  RecordType = "heaviest_weight"
+	RecordMostReps        RecordType = "most_reps"
+	RecordEstimated1RM    RecordType = "estimated_1rm"
+	RecordLongestDuration RecordType = "longest_duration"
+	RecordHighestVolume   RecordType = "highest_volume"
+)
+
+// Performance is what an entry of a workout, or one of its sets, achieved.
+type Performance struct {
+	EntryID         int
+	ExerciseID      *int64
+	ExerciseName    string
+	Sets            int
+	Reps            *int
+	DurationSeconds *int
+	Weight          *float64
+}
+
+// Record is the best value of one kind a workout reached for one exercise.
+// Most reps records are kept per weight, Weight is nil for the others.
+type Record struct {
+	ExerciseKey  string
+	ExerciseID   *int64
+	ExerciseName string
+	Type         RecordType
+	Weight       *float64
+	Value        float64
+	EntryID      int
+}
+
+// ExerciseKey identifies an exercise across workouts: its catalog ID when
+// the entry is linked, its normalized name otherwise.
+func ExerciseKey(exerciseID *int64, exerciseName string) string {
+	if exerciseID != nil {
+		return fmt.Sprintf("id:%d", *exerciseID)
+	}
+	return "name:" + NormalizeName(exerciseName)
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NormalizeName matches normalize_exercise_name in the database.
+func NormalizeName(name string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// WorkoutRecords returns the best value of each record type per exercise
+// among performances, the candidates for personal records. Volume is summed
+// over the performances of an entry.
+func WorkoutRecords(performances []Performance) []Record {
+	var records []Record
+	index := map[string]int{}
+
+	consider := func(p Performance, recordType RecordType, weight *float64, value float64) {
+		if value <= 0 {
+			return
+		}
+
+		key := ExerciseKey(p.ExerciseID, p.ExerciseName)
+		slot := fmt.Sprintf("%s|%s", key, recordType)
+		if weight != nil {
+			slot = fmt.Sprintf("%s|%g", slot, *weight)
+		}
+
+		if i, ok := index[slot]; ok {
+			if value > records[i].Value {
+				records[i].Value = value
+				records[i].EntryID = p.EntryID
+			}
+			return
+		}
+
+		index[slot] = len(records)
+		records = append(records, Record{
+			ExerciseKey:  key,
+			ExerciseID:   p.ExerciseID,
+			ExerciseName: p.ExerciseName,
+			Type:         recordType,
+			Weight:       weight,
+			Value:        value,
+			EntryID:      p.EntryID,
+		})
+	}
+
+	volumes := map[int]float64{}
+	var volumeEntries []Performance
+	for _, p := range performances {
+		if p.DurationSeconds != nil {
+			consider(p, RecordLongestDuration, nil, float64(*p.DurationSeconds))
+		}
+
+		if p.Reps == nil || *p.Reps < 1 || p.Weight == nil || *p.Weight <= 0 {
+			continue
+		}
+		reps, weight := *p.Reps, *p.Weight
+
+		consider(p, RecordHeaviestWeight, nil, weight)
+		consider(p, RecordMostReps, &weight, float64(reps))
+		if oneRepMax, ok := EstimateOneRepMax(Epley, weight, reps); ok {
+			consider(p, RecordEstimated1RM, nil, math.Round(oneRepMax*100)/100)
+		}
+
+		if _, ok := volumes[p.EntryID]; !ok {
+			volumeEntries = append(volumeEntries, p)
+		}
+		volumes[p.EntryID] += float64(max(p.Sets, 1)*reps) * weight
+	}
+
+	for _, p := range volumeEntries {
+		consider(p, RecordHighestVolume, nil, volumes[p.EntryID])
+	}
+
+	return records
+}
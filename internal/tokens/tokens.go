@@ -9,6 +9,12 @@ import (
 
 const (
 	ScopeAuth = "authentication"
+	// ScopeMFAPending tokens prove the password was checked and can only be
+	// exchanged, together with a second factor, for a ScopeAuth token.
+	ScopeMFAPending = "mfa-pending"
+	// ScopeCalendarFeed tokens only read the iCalendar feed of their user,
+	// they are meant to be pasted in calendar apps.
+	ScopeCalendarFeed = "calendar-feed"
 )
 
 type Token struct {
@@ -0,0 +1,65 @@
+// Package units converts weights between the units users log them in.
+// Weights are stored in kilograms.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type Unit string
+
+const (
+	Kilograms Unit = "kg"
+	Pounds    Unit = "lb"
+)
+
+// kilogramsPerPound is exact by definition.
+const kilogramsPerPound = 0.45359237
+
+func Parse(name string) (Unit, error) {
+	unit := Unit(strings.ToLower(strings.TrimSpace(name)))
+	if !unit.Valid() {
+		return "", fmt.Errorf("unknown unit %q, use kg or lb", name)
+	}
+	return unit, nil
+}
+
+func (u Unit) Valid() bool {
+	return u == Kilograms || u == Pounds
+}
+
+// ToKilograms converts weight from unit, rounded to the gram weights are
+// stored with.
+func ToKilograms(weight float64, unit Unit) float64 {
+	if unit == Pounds {
+		weight *= kilogramsPerPound
+	}
+	return round(weight, 3)
+}
+
+// FromKilograms converts a stored weight to unit. Pounds are rounded to the
+// hundredth, coarser than a stored gram, so weights logged to the hundredth
+// of a pound read back as they were logged.
+func FromKilograms(weight float64, unit Unit) float64 {
+	if unit == Pounds {
+		return round(weight/kilogramsPerPound, 2)
+	}
+	return weight
+}
+
+// FromKilogramsExact converts a stored weight to unit finely enough that
+// ToKilograms gives the stored weight back, for weights read in a unit other
+// than the one they were logged in and possibly written back.
+func FromKilogramsExact(weight float64, unit Unit) float64 {
+	if unit == Pounds {
+		return round(weight/kilogramsPerPound, 3)
+	}
+	return weight
+}
+
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func ETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ParseETag returns the version an ETag was built from.
+func ParseETag(etag string) (int, error) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	unquoted, err := strconv.Unquote(etag)
+	if err != nil {
+		return 0, fmt.Errorf("invalid etag %s", etag)
+	}
+
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid etag %s", etag)
+	}
+
+	return version, nil
+}
+
+// MatchesETag reports whether etag is listed in an If-Match or If-None-Match
+// header value. Weak validators compare equal to strong ones, and "*"
+// matches anything.
+func MatchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatch enforces the If-Match precondition of a write request against
+// the current ETag of the resource. It writes a 428 when the header is
+// missing, a 412 when it doesn't match, and returns false in both cases.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		WriteJSON(w, http.StatusPreconditionRequired, Envelope{"error": "If-Match header is required"})
+		return false
+	}
+
+	if !MatchesETag(ifMatch, etag) {
+		w.Header().Set("ETag", etag)
+		WriteJSON(w, http.StatusPreconditionFailed, Envelope{"error": "resource was modified, fetch it again before updating"})
+		return false
+	}
+
+	return true
+}
@@ -3,9 +3,12 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -26,13 +29,17 @@ func WriteJSON(w http.ResponseWriter, status int, data Envelope) error {
 }
 
 func ReadIDParam(r *http.Request) (int64, error) {
-	idParam := chi.URLParam(r, "id")
-	if idParam == "" {
-		return 0, errors.New("invalid id parameter")
+	return ReadInt64Param(r, "id")
+}
+
+func ReadInt64Param(r *http.Request, name string) (int64, error) {
+	param := chi.URLParam(r, name)
+	if param == "" {
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	id, err := strconv.ParseInt(param, 10, 64)
 	if err != nil {
-		return 0, errors.New("invalid id parameter type")
+		return 0, fmt.Errorf("invalid %s parameter type", name)
 	}
 
 	return id, nil
@@ -49,14 +56,56 @@ func IsValidEmail(email string) bool {
 	return reg.MatchString(email)
 }
 
-func IsValidPassword(password string) bool {
-	// Check if the password contains at least one uppercase letter, one lowercase letter, one number, one special character, and is at least 8 characters long
-	if len(password) < 8 {
-		return false
+func ReadIntQuery(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s query parameter", key)
+	}
+
+	return i, nil
+}
+
+// ReadDateRange reads the from and to query parameters as YYYY-MM-DD dates
+// in location and returns the half-open range [from, to+1 day). A missing to
+// means today and a missing from means defaultDays before to.
+func ReadDateRange(r *http.Request, location *time.Location, defaultDays int) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	now := time.Now().In(location)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if value := query.Get("to"); value != "" {
+		var err error
+		to, err = time.ParseInLocation(time.DateOnly, value, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date like 2006-01-02")
+		}
+	}
+
+	from := to.AddDate(0, 0, -defaultDays)
+	if value := query.Get("from"); value != "" {
+		var err error
+		from, err = time.ParseInLocation(time.DateOnly, value, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date like 2006-01-02")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from cannot be after to")
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasNumber := regexp.MustCompile(`\d`).MatchString(password)
-	hasSpecial := regexp.MustCompile(`[@$!%*?&]`).MatchString(password)
-	return hasUpper && hasLower && hasNumber && hasSpecial
+	return host
 }
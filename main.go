@@ -1,24 +1,57 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gbuenodev/goProject/internal/app"
+	"github.com/gbuenodev/goProject/internal/passwords"
 	"github.com/gbuenodev/goProject/internal/routes"
 )
 
 func main() {
 	var port int
 	var logLevel string
+	var oidcConfigPath string
+	var breachedPasswordsPath string
+	var argon2Memory, argon2Iterations, argon2Parallelism uint
+	var trashRetention time.Duration
+	var sharedRateLimits bool
 
 	flag.IntVar(&port, "port", 8080, "GO backend server port")
 	flag.StringVar(&logLevel, "level", "info", "Log Level for the app")
+	flag.StringVar(&oidcConfigPath, "oidc-config", "", "JSON file with the OIDC identity providers")
+	flag.StringVar(&breachedPasswordsPath, "breached-passwords", "", "Pwned Passwords SHA-1 file or range directory")
+	flag.UintVar(&argon2Memory, "argon2-memory", uint(passwords.DefaultArgon2idParams.Memory), "Argon2id memory in KiB")
+	flag.UintVar(&argon2Iterations, "argon2-iterations", uint(passwords.DefaultArgon2idParams.Iterations), "Argon2id iterations")
+	flag.UintVar(&argon2Parallelism, "argon2-parallelism", uint(passwords.DefaultArgon2idParams.Parallelism), "Argon2id parallelism")
+	flag.DurationVar(&trashRetention, "trash-retention", 30*24*time.Hour, "How long deleted workouts are kept in the trash, 0 to keep them forever")
+	flag.BoolVar(&sharedRateLimits, "shared-rate-limits", false, "Keep rate limit buckets in the database to share them between instances")
 	flag.Parse()
 
-	app, err := app.NewApp(logLevel)
+	argon2Params := passwords.DefaultArgon2idParams
+	argon2Params.Memory = uint32(argon2Memory)
+	argon2Params.Iterations = uint32(argon2Iterations)
+	argon2Params.Parallelism = uint8(argon2Parallelism)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app, err := app.NewApp(ctx, app.Config{
+		LogLevel:              logLevel,
+		OIDCConfigPath:        oidcConfigPath,
+		Argon2id:              argon2Params,
+		BreachedPasswordsPath: breachedPasswordsPath,
+		TrashRetention:        trashRetention,
+		SharedRateLimits:      sharedRateLimits,
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -41,8 +74,26 @@ Log level: %s
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// stop accepting requests on SIGINT or SIGTERM and let the in-flight
+	// ones finish
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			app.Logger.Error("Shutdown", "err", err)
+		}
+	}()
+
 	err = server.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		panic(err)
 	}
+
+	<-shutdownDone
 }
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user',
+ADD COLUMN suspended_at TIMESTAMPTZ,
+ADD CONSTRAINT valid_user_role CHECK (role IN ('user', 'coach', 'admin'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+DROP CONSTRAINT valid_user_role,
+DROP COLUMN suspended_at,
+DROP COLUMN role;
+-- +goose StatementEnd
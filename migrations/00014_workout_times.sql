@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workouts
+ADD COLUMN performed_at TIMESTAMPTZ,
+ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+ADD COLUMN started_at TIMESTAMPTZ,
+ADD COLUMN ended_at TIMESTAMPTZ,
+ADD CONSTRAINT valid_workout_span CHECK (started_at IS NULL OR ended_at IS NULL OR ended_at >= started_at);
+
+-- workouts logged so far were performed, as far as we know, when they were created
+UPDATE workouts SET performed_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+
+ALTER TABLE workouts
+ALTER COLUMN performed_at SET NOT NULL,
+ALTER COLUMN performed_at SET DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_workouts_user_performed_at ON workouts (user_id, performed_at) WHERE deleted_at IS NULL;
+
+ALTER TABLE users
+ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN timezone;
+
+DROP INDEX IF EXISTS idx_workouts_user_performed_at;
+
+ALTER TABLE workouts
+DROP CONSTRAINT valid_workout_span,
+DROP COLUMN ended_at,
+DROP COLUMN started_at,
+DROP COLUMN timezone,
+DROP COLUMN performed_at;
+-- +goose StatementEnd
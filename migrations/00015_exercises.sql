@@ -0,0 +1,90 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS exercises (
+  id BIGSERIAL PRIMARY KEY,
+  -- NULL for the built-in library, the creator for custom exercises
+  owner_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  aliases TEXT[] NOT NULL DEFAULT '{}',
+  primary_muscles TEXT[] NOT NULL DEFAULT '{}',
+  secondary_muscles TEXT[] NOT NULL DEFAULT '{}',
+  equipment VARCHAR(50) NOT NULL DEFAULT 'other',
+  movement_pattern VARCHAR(50) NOT NULL DEFAULT 'other',
+  unilateral BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- "Bench Press", "bench press" and "bench-press" are the same exercise
+CREATE OR REPLACE FUNCTION normalize_exercise_name(name TEXT) RETURNS TEXT AS $$
+  SELECT regexp_replace(lower(name), '[^a-z0-9]+', '', 'g')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_builtin_name ON exercises (normalize_exercise_name(name)) WHERE owner_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_custom_name ON exercises (owner_id, normalize_exercise_name(name)) WHERE owner_id IS NOT NULL;
+
+INSERT INTO exercises (name, aliases, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral) VALUES
+  ('Bench Press', '{"BP","Barbell Bench Press","Flat Bench","Flat Bench Press"}', '{chest}', '{triceps,shoulders}', 'barbell', 'horizontal_push', FALSE),
+  ('Incline Bench Press', '{"Incline Barbell Press","Incline Press"}', '{chest}', '{shoulders,triceps}', 'barbell', 'horizontal_push', FALSE),
+  ('Dumbbell Bench Press', '{"DB Bench","DB Bench Press"}', '{chest}', '{triceps,shoulders}', 'dumbbell', 'horizontal_push', FALSE),
+  ('Push-up', '{"Pushup","Press-up"}', '{chest}', '{triceps,shoulders,abs}', 'bodyweight', 'horizontal_push', FALSE),
+  ('Dips', '{"Dip","Parallel Bar Dip"}', '{chest,triceps}', '{shoulders}', 'bodyweight', 'vertical_push', FALSE),
+  ('Overhead Press', '{"OHP","Military Press","Shoulder Press","Standing Press"}', '{shoulders}', '{triceps,traps}', 'barbell', 'vertical_push', FALSE),
+  ('Dumbbell Shoulder Press', '{"DB Shoulder Press","Seated Dumbbell Press"}', '{shoulders}', '{triceps}', 'dumbbell', 'vertical_push', FALSE),
+  ('Lateral Raise', '{"Side Raise","Dumbbell Lateral Raise"}', '{shoulders}', '{}', 'dumbbell', 'isolation', FALSE),
+  ('Triceps Pushdown', '{"Tricep Pushdown","Cable Pushdown"}', '{triceps}', '{}', 'cable', 'isolation', FALSE),
+  ('Skull Crusher', '{"Lying Triceps Extension"}', '{triceps}', '{}', 'barbell', 'isolation', FALSE),
+  ('Pull-up', '{"Pullup","Pull Up"}', '{lats}', '{biceps,back}', 'bodyweight', 'vertical_pull', FALSE),
+  ('Chin-up', '{"Chinup","Chin Up"}', '{lats,biceps}', '{back}', 'bodyweight', 'vertical_pull', FALSE),
+  ('Lat Pulldown', '{"Pulldown","Cable Pulldown"}', '{lats}', '{biceps,back}', 'cable', 'vertical_pull', FALSE),
+  ('Barbell Row', '{"Bent Over Row","BB Row","Pendlay Row"}', '{back,lats}', '{biceps,lower_back}', 'barbell', 'horizontal_pull', FALSE),
+  ('Dumbbell Row', '{"DB Row","One Arm Row","Single Arm Dumbbell Row"}', '{back,lats}', '{biceps}', 'dumbbell', 'horizontal_pull', TRUE),
+  ('Seated Cable Row', '{"Cable Row","Seated Row"}', '{back,lats}', '{biceps}', 'cable', 'horizontal_pull', FALSE),
+  ('Face Pull', '{"Cable Face Pull"}', '{shoulders,traps}', '{back}', 'cable', 'horizontal_pull', FALSE),
+  ('Barbell Curl', '{"Curl","BB Curl","Biceps Curl"}', '{biceps}', '{forearms}', 'barbell', 'isolation', FALSE),
+  ('Dumbbell Curl', '{"DB Curl"}', '{biceps}', '{forearms}', 'dumbbell', 'isolation', FALSE),
+  ('Hammer Curl', '{}', '{biceps,forearms}', '{}', 'dumbbell', 'isolation', FALSE),
+  ('Shrug', '{"Barbell Shrug","Shrugs"}', '{traps}', '{forearms}', 'barbell', 'isolation', FALSE),
+  ('Squat', '{"Back Squat","Barbell Squat","Squats"}', '{quads,glutes}', '{adductors,lower_back}', 'barbell', 'squat', FALSE),
+  ('Front Squat', '{}', '{quads}', '{glutes,abs}', 'barbell', 'squat', FALSE),
+  ('Goblet Squat', '{}', '{quads,glutes}', '{abs}', 'kettlebell', 'squat', FALSE),
+  ('Leg Press', '{}', '{quads,glutes}', '{hamstrings}', 'machine', 'squat', FALSE),
+  ('Bulgarian Split Squat', '{"Split Squat","BSS","Rear Foot Elevated Split Squat"}', '{quads,glutes}', '{hamstrings}', 'dumbbell', 'lunge', TRUE),
+  ('Lunge', '{"Lunges","Walking Lunge"}', '{quads,glutes}', '{hamstrings}', 'dumbbell', 'lunge', TRUE),
+  ('Leg Extension', '{}', '{quads}', '{}', 'machine', 'isolation', FALSE),
+  ('Deadlift', '{"DL","Conventional Deadlift"}', '{hamstrings,glutes,lower_back}', '{quads,traps,forearms}', 'barbell', 'hinge', FALSE),
+  ('Romanian Deadlift', '{"RDL","Stiff Leg Deadlift"}', '{hamstrings,glutes}', '{lower_back}', 'barbell', 'hinge', FALSE),
+  ('Hip Thrust', '{"Barbell Hip Thrust","Glute Bridge"}', '{glutes}', '{hamstrings}', 'barbell', 'hinge', FALSE),
+  ('Kettlebell Swing', '{"KB Swing","Swing"}', '{glutes,hamstrings}', '{lower_back,shoulders}', 'kettlebell', 'hinge', FALSE),
+  ('Leg Curl', '{"Hamstring Curl","Lying Leg Curl"}', '{hamstrings}', '{}', 'machine', 'isolation', FALSE),
+  ('Calf Raise', '{"Standing Calf Raise","Calf Raises"}', '{calves}', '{}', 'machine', 'isolation', FALSE),
+  ('Plank', '{"Front Plank"}', '{abs}', '{obliques,shoulders}', 'bodyweight', 'core', FALSE),
+  ('Hanging Leg Raise', '{"Leg Raise"}', '{abs}', '{obliques,forearms}', 'bodyweight', 'core', FALSE),
+  ('Crunch', '{"Crunches","Sit-up","Situp"}', '{abs}', '{}', 'bodyweight', 'core', FALSE),
+  ('Farmer''s Carry', '{"Farmer Walk","Farmers Walk","Farmer Carry"}', '{forearms,traps}', '{abs,quads}', 'dumbbell', 'carry', FALSE),
+  ('Running', '{"Run","Jog","Treadmill"}', '{full_body}', '{}', 'other', 'cardio', FALSE),
+  ('Rowing', '{"Row Erg","Rowing Machine","Erg"}', '{full_body}', '{back,quads}', 'machine', 'cardio', FALSE),
+  ('Cycling', '{"Bike","Stationary Bike","Spin"}', '{quads}', '{hamstrings,calves}', 'machine', 'cardio', FALSE),
+  ('Jump Rope', '{"Skipping","Skipping Rope"}', '{calves}', '{shoulders}', 'other', 'cardio', FALSE)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE workout_entries
+ADD COLUMN exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_workout_entries_exercise_id ON workout_entries (exercise_id);
+
+-- link the free-text entries logged so far to the built-in library
+UPDATE workout_entries e
+SET exercise_id = x.id
+FROM exercises x
+WHERE x.owner_id IS NULL
+  AND (normalize_exercise_name(x.name) = normalize_exercise_name(e.exercise_name)
+    OR normalize_exercise_name(e.exercise_name) IN (SELECT normalize_exercise_name(a) FROM unnest(x.aliases) AS a));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP COLUMN exercise_id;
+DROP TABLE exercises;
+DROP FUNCTION IF EXISTS normalize_exercise_name(TEXT);
+-- +goose StatementEnd
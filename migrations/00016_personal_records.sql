@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS personal_records (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- the exercise_id when the entry is linked to the catalog, its normalized name otherwise
+  exercise_key VARCHAR(255) NOT NULL,
+  exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL,
+  exercise_name VARCHAR(255) NOT NULL,
+  record_type VARCHAR(30) NOT NULL,
+  -- only set for most_reps records, which are kept per weight
+  weight NUMERIC(8,3),
+  value NUMERIC(12,3) NOT NULL,
+  workout_id BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+  entry_id BIGINT REFERENCES workout_entries(id) ON DELETE SET NULL,
+  achieved_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT valid_record_type CHECK (record_type IN ('heaviest_weight', 'most_reps', 'estimated_1rm', 'longest_duration', 'highest_volume'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_records_user_key ON personal_records (user_id, exercise_key, record_type);
+CREATE INDEX IF NOT EXISTS idx_personal_records_workout_id ON personal_records (workout_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE personal_records;
+-- +goose StatementEnd
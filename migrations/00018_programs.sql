@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS programs (
+  id BIGSERIAL PRIMARY KEY,
+  owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  title VARCHAR(255) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  weeks INTEGER NOT NULL CHECK (weeks BETWEEN 1 AND 52),
+  -- public programs can be read and followed by every user
+  public BOOLEAN NOT NULL DEFAULT FALSE,
+  -- prescribed weights are rounded to a multiple of it
+  rounding NUMERIC(5,2) NOT NULL DEFAULT 2.5 CHECK (rounding > 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_programs_owner_id ON programs (owner_id);
+
+-- the sessions of a program, performed in (week, day) order
+CREATE TABLE IF NOT EXISTS program_days (
+  id BIGSERIAL PRIMARY KEY,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  week INTEGER NOT NULL CHECK (week >= 1),
+  day INTEGER NOT NULL CHECK (day BETWEEN 1 AND 7),
+  template_id BIGINT NOT NULL REFERENCES workout_templates(id) ON DELETE RESTRICT,
+  prescriptions JSONB NOT NULL DEFAULT '[]',
+  UNIQUE (program_id, week, day)
+);
+
+CREATE TABLE IF NOT EXISTS program_progressions (
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  exercise_id BIGINT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+  increment NUMERIC(6,2) NOT NULL CHECK (increment > 0),
+  PRIMARY KEY (program_id, exercise_id)
+);
+
+CREATE TABLE IF NOT EXISTS program_enrollments (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE RESTRICT,
+  status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed')),
+  -- the next session to perform
+  week INTEGER NOT NULL,
+  day INTEGER NOT NULL,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_enrollments_user_id ON program_enrollments (user_id);
+
+-- the weights percentage prescriptions are computed from, raised by the
+-- progression rules
+CREATE TABLE IF NOT EXISTS enrollment_training_maxes (
+  enrollment_id BIGINT NOT NULL REFERENCES program_enrollments(id) ON DELETE CASCADE,
+  exercise_id BIGINT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+  weight NUMERIC(8,3) NOT NULL CHECK (weight > 0),
+  PRIMARY KEY (enrollment_id, exercise_id)
+);
+
+CREATE TABLE IF NOT EXISTS enrollment_sessions (
+  id BIGSERIAL PRIMARY KEY,
+  enrollment_id BIGINT NOT NULL REFERENCES program_enrollments(id) ON DELETE CASCADE,
+  week INTEGER NOT NULL,
+  day INTEGER NOT NULL,
+  workout_id BIGINT REFERENCES workouts(id) ON DELETE SET NULL,
+  completed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (enrollment_id, week, day)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE enrollment_sessions;
+DROP TABLE enrollment_training_maxes;
+DROP TABLE program_enrollments;
+DROP TABLE program_progressions;
+DROP TABLE program_days;
+DROP TABLE programs;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS planned_workouts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  title VARCHAR(255) NOT NULL,
+  notes TEXT NOT NULL DEFAULT '',
+  template_id BIGINT REFERENCES workout_templates(id) ON DELETE SET NULL,
+  -- the first occurrence, repeated at the same wall clock time in timezone
+  scheduled_at TIMESTAMPTZ NOT NULL,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  duration_minutes INTEGER NOT NULL DEFAULT 60 CHECK (duration_minutes > 0),
+  rrule TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_planned_workouts_user_id ON planned_workouts (user_id);
+
+-- occurrences done, identified by their local date
+CREATE TABLE IF NOT EXISTS planned_workout_completions (
+  planned_workout_id BIGINT NOT NULL REFERENCES planned_workouts(id) ON DELETE CASCADE,
+  occurrence_date DATE NOT NULL,
+  workout_id BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+  PRIMARY KEY (planned_workout_id, occurrence_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE planned_workout_completions;
+DROP TABLE planned_workouts;
+-- +goose StatementEnd
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+-- group_id only identifies a group within its workout or template; the other
+-- group columns are repeated on every entry of the group
+ALTER TABLE workout_entries
+ADD COLUMN group_id INTEGER,
+ADD COLUMN group_type VARCHAR(20),
+ADD COLUMN group_rounds INTEGER,
+ADD COLUMN group_rest_seconds INTEGER,
+ADD CONSTRAINT valid_workout_entry_group CHECK (
+  (group_id IS NULL) = (group_type IS NULL) AND
+  (group_id IS NOT NULL OR (group_rounds IS NULL AND group_rest_seconds IS NULL)) AND
+  group_id > 0 AND
+  group_type IN ('superset', 'circuit', 'giant_set', 'emom', 'amrap') AND
+  group_rounds > 0 AND
+  group_rest_seconds >= 0
+);
+
+ALTER TABLE workout_template_entries
+ADD COLUMN group_id INTEGER,
+ADD COLUMN group_type VARCHAR(20),
+ADD COLUMN group_rounds INTEGER,
+ADD COLUMN group_rest_seconds INTEGER,
+ADD CONSTRAINT valid_template_entry_group CHECK (
+  (group_id IS NULL) = (group_type IS NULL) AND
+  (group_id IS NOT NULL OR (group_rounds IS NULL AND group_rest_seconds IS NULL)) AND
+  group_id > 0 AND
+  group_type IN ('superset', 'circuit', 'giant_set', 'emom', 'amrap') AND
+  group_rounds > 0 AND
+  group_rest_seconds >= 0
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_template_entries
+DROP CONSTRAINT valid_template_entry_group,
+DROP COLUMN group_rest_seconds,
+DROP COLUMN group_rounds,
+DROP COLUMN group_type,
+DROP COLUMN group_id;
+
+ALTER TABLE workout_entries
+DROP CONSTRAINT valid_workout_entry_group,
+DROP COLUMN group_rest_seconds,
+DROP COLUMN group_rounds,
+DROP COLUMN group_type,
+DROP COLUMN group_id;
+-- +goose StatementEnd
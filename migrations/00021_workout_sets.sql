@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_sets (
+  entry_id BIGINT NOT NULL REFERENCES workout_entries(id) ON DELETE CASCADE,
+  set_index INTEGER NOT NULL,
+  set_type VARCHAR(20) NOT NULL DEFAULT 'working',
+  reps INTEGER,
+  duration_seconds INTEGER,
+  weight NUMERIC(8,3),
+  rpe NUMERIC(3,1),
+  rir INTEGER,
+  rest_seconds INTEGER,
+  completed BOOLEAN NOT NULL DEFAULT TRUE,
+  PRIMARY KEY (entry_id, set_index),
+  CONSTRAINT valid_set_type CHECK (set_type IN ('warm_up', 'working', 'drop', 'failure')),
+  CONSTRAINT valid_workout_set CHECK (
+    (reps IS NOT NULL OR duration_seconds IS NOT NULL) AND
+    (reps IS NULL OR duration_seconds IS NULL) AND
+    reps >= 0 AND duration_seconds >= 0 AND weight >= 0 AND
+    rpe BETWEEN 1 AND 10 AND rir >= 0 AND rest_seconds >= 0
+  )
+);
+
+-- performed_sets is what analytics count: the completed working sets of
+-- entries with a set log, and the entries without one as logged
+CREATE VIEW performed_sets AS
+SELECT e.id AS entry_id, e.workout_id, e.exercise_id, e.exercise_name, e.sets, e.reps, e.duration_seconds, e.weight
+FROM workout_entries e
+WHERE NOT EXISTS (SELECT 1 FROM workout_sets s WHERE s.entry_id = e.id)
+UNION ALL
+SELECT e.id, e.workout_id, e.exercise_id, e.exercise_name, 1, s.reps, s.duration_seconds, s.weight
+FROM workout_entries e
+JOIN workout_sets s ON s.entry_id = e.id
+WHERE s.completed AND s.set_type <> 'warm_up';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW performed_sets;
+DROP TABLE workout_sets;
+-- +goose StatementEnd
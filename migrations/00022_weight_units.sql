@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- weights are stored in kg, weight_unit records the unit they were logged in
+DROP VIEW performed_sets;
+
+ALTER TABLE workout_entries
+ALTER COLUMN weight TYPE NUMERIC(8,3),
+ADD COLUMN weight_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (weight_unit IN ('kg', 'lb'));
+
+ALTER TABLE workout_template_entries
+ALTER COLUMN weight TYPE NUMERIC(8,3),
+ADD COLUMN weight_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (weight_unit IN ('kg', 'lb'));
+
+CREATE VIEW performed_sets AS
+SELECT e.id AS entry_id, e.workout_id, e.exercise_id, e.exercise_name, e.sets, e.reps, e.duration_seconds, e.weight
+FROM workout_entries e
+WHERE NOT EXISTS (SELECT 1 FROM workout_sets s WHERE s.entry_id = e.id)
+UNION ALL
+SELECT e.id, e.workout_id, e.exercise_id, e.exercise_name, 1, s.reps, s.duration_seconds, s.weight
+FROM workout_entries e
+JOIN workout_sets s ON s.entry_id = e.id
+WHERE s.completed AND s.set_type <> 'warm_up';
+
+ALTER TABLE users
+ADD COLUMN preferred_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (preferred_unit IN ('kg', 'lb'));
+
+-- the unit rounding and progression increments are written in
+ALTER TABLE programs
+ADD COLUMN unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (unit IN ('kg', 'lb'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE programs DROP COLUMN unit;
+
+ALTER TABLE users DROP COLUMN preferred_unit;
+
+DROP VIEW performed_sets;
+
+ALTER TABLE workout_template_entries
+DROP COLUMN weight_unit,
+ALTER COLUMN weight TYPE DECIMAL(5,2);
+
+ALTER TABLE workout_entries
+DROP COLUMN weight_unit,
+ALTER COLUMN weight TYPE DECIMAL(5,2);
+
+CREATE VIEW performed_sets AS
+SELECT e.id AS entry_id, e.workout_id, e.exercise_id, e.exercise_name, e.sets, e.reps, e.duration_seconds, e.weight
+FROM workout_entries e
+WHERE NOT EXISTS (SELECT 1 FROM workout_sets s WHERE s.entry_id = e.id)
+UNION ALL
+SELECT e.id, e.workout_id, e.exercise_id, e.exercise_name, 1, s.reps, s.duration_seconds, s.weight
+FROM workout_entries e
+JOIN workout_sets s ON s.entry_id = e.id
+WHERE s.completed AND s.set_type <> 'warm_up';
+-- +goose StatementEnd
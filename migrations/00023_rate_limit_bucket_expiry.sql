@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- full_at is when a bucket has refilled completely. From then on it behaves
+-- like a missing bucket and can be purged.
+ALTER TABLE rate_limit_buckets
+  ADD COLUMN full_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_full_at ON rate_limit_buckets (full_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_rate_limit_buckets_full_at;
+ALTER TABLE rate_limit_buckets DROP COLUMN full_at;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- set_type lets stats tell hard sets from drop sets, entries without a set
+-- log count as working sets
+DROP VIEW performed_sets;
+
+CREATE VIEW performed_sets AS
+SELECT e.id AS entry_id, e.workout_id, e.exercise_id, e.exercise_name, e.sets, e.reps, e.duration_seconds, e.weight, 'working' AS set_type
+FROM workout_entries e
+WHERE NOT EXISTS (SELECT 1 FROM workout_sets s WHERE s.entry_id = e.id)
+UNION ALL
+SELECT e.id, e.workout_id, e.exercise_id, e.exercise_name, 1, s.reps, s.duration_seconds, s.weight, s.set_type
+FROM workout_entries e
+JOIN workout_sets s ON s.entry_id = e.id
+WHERE s.completed AND s.set_type <> 'warm_up';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW performed_sets;
+
+CREATE VIEW performed_sets AS
+SELECT e.id AS entry_id, e.workout_id, e.exercise_id, e.exercise_name, e.sets, e.reps, e.duration_seconds, e.weight
+FROM workout_entries e
+WHERE NOT EXISTS (SELECT 1 FROM workout_sets s WHERE s.entry_id = e.id)
+UNION ALL
+SELECT e.id, e.workout_id, e.exercise_id, e.exercise_name, 1, s.reps, s.duration_seconds, s.weight
+FROM workout_entries e
+JOIN workout_sets s ON s.entry_id = e.id
+WHERE s.completed AND s.set_type <> 'warm_up';
+-- +goose StatementEnd
@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/api"
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePlannedWorkoutStore struct {
+	store.PlannedWorkoutStore
+}
+
+func (s *fakePlannedWorkoutStore) ListPlannedWorkouts(userID int) ([]store.PlannedWorkout, error) {
+	return []store.PlannedWorkout{}, nil
+}
+
+// fakeWorkoutList pages through workouts like ListWorkouts does.
+type fakeWorkoutList struct {
+	store.WorkoutStore
+	workouts []store.Workout
+}
+
+func (s *fakeWorkoutList) ListWorkouts(userID int, from, to time.Time, limit, offset int) ([]store.Workout, error) {
+	start := min(offset, len(s.workouts))
+	end := min(offset+limit, len(s.workouts))
+	return s.workouts[start:end], nil
+}
+
+func TestCalendarListsEveryWorkout(t *testing.T) {
+	performedAt := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+	workoutStore := &fakeWorkoutList{}
+	for i := 1; i <= 1201; i++ {
+		workoutStore.workouts = append(workoutStore.workouts, store.Workout{ID: i, UserID: 1, Title: "Workout", PerformedAt: performedAt})
+	}
+
+	handler := api.NewCalendarHandler(&fakePlannedWorkoutStore{}, workoutStore, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	user := &store.User{ID: 1, Role: store.RoleUser, Timezone: "UTC"}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar?from=2026-01-01&to=2026-01-31", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleGetCalendar(rr, middleware.SetUser(req, user))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var body struct {
+		Events []struct {
+			WorkoutID *int64 `json:"workout_id"`
+		} `json:"events"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	require.Len(t, body.Events, 1201)
+
+	seen := map[int64]bool{}
+	for _, event := range body.Events {
+		require.NotNil(t, event.WorkoutID)
+		seen[*event.WorkoutID] = true
+	}
+	assert.Len(t, seen, 1201)
+}
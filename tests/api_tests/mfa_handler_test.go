@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/api"
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEnrolledMFAStore holds a confirmed TOTP secret and a single recovery
+// code.
+type fakeEnrolledMFAStore struct {
+	store.MFAStore
+	secret       *store.TOTPSecret
+	recoveryCode string
+}
+
+func (s *fakeEnrolledMFAStore) GetTOTPSecret(userID int) (*store.TOTPSecret, error) {
+	return s.secret, nil
+}
+
+func (s *fakeEnrolledMFAStore) UseRecoveryCode(userID int, codeHash []byte) (bool, error) {
+	return bytes.Equal(codeHash, totp.HashRecoveryCode(s.recoveryCode)), nil
+}
+
+func (s *fakeEnrolledMFAStore) DeleteTOTPSecret(userID int) error {
+	s.secret = nil
+	return nil
+}
+
+func disableTOTP(t *testing.T, handler *api.MFAHandler, user *store.User, recoveryCode string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body := `{"recovery_code":"` + recoveryCode + `"}`
+	req := httptest.NewRequest(http.MethodDelete, "/users/me/mfa/totp", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	handler.HandleDisableTOTP(rr, middleware.SetUser(req, user))
+	return rr
+}
+
+func TestDisableTOTPIsThrottled(t *testing.T) {
+	confirmedAt := time.Now()
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	mfaStore := &fakeEnrolledMFAStore{
+		secret:       &store.TOTPSecret{UserID: 1, Secret: secret, ConfirmedAt: &confirmedAt},
+		recoveryCode: "abcd-efgh",
+	}
+	attempts := newFakeLoginAttemptStore()
+	handler := api.NewMFAHandler(mfaStore, attempts, testThrottle(), "WorkoutAPI", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	user := &store.User{ID: 1, Username: "alice", Role: store.RoleUser}
+
+	rr := disableTOTP(t, handler, user, "wrong")
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = disableTOTP(t, handler, user, "abcd-efgh")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	require.NotNil(t, mfaStore.secret)
+
+	attempts.age("username:alice", time.Minute)
+	attempts.age("ip:192.0.2.1", time.Minute)
+
+	rr = disableTOTP(t, handler, user, "abcd-efgh")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, mfaStore.secret)
+	assert.Equal(t, 0, attempts.failures("username:alice"))
+}
@@ -0,0 +1,337 @@
+package api_test
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/api"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoginAttemptStore keeps login attempts in memory and applies the same
+// backoff as the Postgres store does when taking an attempt.
+type fakeLoginAttemptStore struct {
+	store.LoginAttemptStore
+	mu       sync.Mutex
+	attempts map[string]*store.LoginAttempt
+	events   []store.LockoutEvent
+}
+
+func newFakeLoginAttemptStore() *fakeLoginAttemptStore {
+	return &fakeLoginAttemptStore{attempts: map[string]*store.LoginAttempt{}}
+}
+
+func (s *fakeLoginAttemptStore) GetLoginAttempts(keys ...string) ([]store.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := []store.LoginAttempt{}
+	for _, key := range keys {
+		if attempt, ok := s.attempts[key]; ok {
+			attempts = append(attempts, *attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (s *fakeLoginAttemptStore) TakeLoginAttempts(keys []string, backoff store.LoginBackoff) ([]store.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	taken := []store.LoginAttempt{}
+	for _, key := range keys {
+		attempt, ok := s.attempts[key]
+		if !ok {
+			attempt = &store.LoginAttempt{Key: key}
+			s.attempts[key] = attempt
+		}
+
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			continue
+		}
+
+		expired := now.Sub(attempt.LastFailureAt) > backoff.Window
+		if attempt.Failures > 0 && !expired {
+			delay := backoff.BaseDelay << min(attempt.Failures-1, 30)
+			if attempt.LastFailureAt.Add(min(delay, backoff.MaxDelay)).After(now) {
+				continue
+			}
+		}
+
+		if expired {
+			attempt.Failures = 0
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		taken = append(taken, *attempt)
+	}
+	return taken, nil
+}
+
+func (s *fakeLoginAttemptStore) ReleaseLoginAttempts(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if attempt, ok := s.attempts[key]; ok && attempt.Failures > 0 {
+			attempt.Failures--
+		}
+	}
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) LockLogin(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[key].LockedUntil = &until
+	s.attempts[key].Failures = 0
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) ResetLoginAttempts(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.attempts, key)
+	}
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) CreateLockoutEvent(event *store.LockoutEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *fakeLoginAttemptStore) failures(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		return attempt.Failures
+	}
+	return 0
+}
+
+// age moves the last failure of key back by d, as if d had passed.
+func (s *fakeLoginAttemptStore) age(key string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[key].LastFailureAt = s.attempts[key].LastFailureAt.Add(-d)
+}
+
+type fakeLoginUserStore struct {
+	store.UserStore
+	users map[string]*store.User
+}
+
+func (s *fakeLoginUserStore) GetUserByUsername(username string) (*store.User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+type fakeTokenStore struct {
+	store.TokenStore
+}
+
+func (s *fakeTokenStore) CreateNewToken(userID int, ttl time.Duration, scope string) (*tokens.Token, error) {
+	return tokens.GenerateToken(userID, ttl, scope)
+}
+
+type fakeMFAStore struct {
+	store.MFAStore
+}
+
+func (s *fakeMFAStore) GetTOTPSecret(userID int) (*store.TOTPSecret, error) {
+	return nil, sql.ErrNoRows
+}
+
+func newTokenServer(t *testing.T, attempts *fakeLoginAttemptStore, throttle api.LoginThrottleConfig) *httptest.Server {
+	t.Helper()
+
+	user := &store.User{ID: 1, Username: "alice", Role: store.RoleUser}
+	require.NoError(t, user.PasswordHash.Set("correct horse"))
+	users := &fakeLoginUserStore{users: map[string]*store.User{"alice": user}}
+
+	handler := api.NewTokenHandler(&fakeTokenStore{}, users, attempts, &fakeMFAStore{}, throttle, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleCreateToken))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func login(t *testing.T, server *httptest.Server, username, password string) *http.Response {
+	t.Helper()
+
+	body := `{"username":"` + username + `","password":"` + password + `"}`
+	res, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func testThrottle() api.LoginThrottleConfig {
+	return api.LoginThrottleConfig{
+		MaxUsernameFailures: 100,
+		MaxIPFailures:       100,
+		BaseDelay:           time.Second,
+		MaxDelay:            4 * time.Second,
+		LockoutDuration:     time.Minute,
+		FailureWindow:       time.Hour,
+	}
+}
+
+func TestLoginBackoffDoublesUpToMaxDelay(t *testing.T) {
+	attempts := newFakeLoginAttemptStore()
+	server := newTokenServer(t, attempts, testThrottle())
+
+	for _, want := range []string{"1", "2", "4", "4"} {
+		res := login(t, server, "alice", "wrong")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		res = login(t, server, "alice", "wrong")
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, want, res.Header.Get("Retry-After"))
+
+		attempts.age("username:alice", time.Minute)
+		attempts.age("ip:127.0.0.1", time.Minute)
+	}
+
+	// the rejected requests were not counted
+	assert.Equal(t, 4, attempts.failures("username:alice"))
+}
+
+func TestLoginLocksUsernameAfterMaxFailures(t *testing.T) {
+	attempts := newFakeLoginAttemptStore()
+	throttle := testThrottle()
+	throttle.MaxUsernameFailures = 3
+	server := newTokenServer(t, attempts, throttle)
+
+	for range 3 {
+		res := login(t, server, "alice", "wrong")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		attempts.age("username:alice", time.Minute)
+		attempts.age("ip:127.0.0.1", time.Minute)
+	}
+
+	res := login(t, server, "alice", "correct horse")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+
+	require.Len(t, attempts.events, 1)
+	event := attempts.events[0]
+	assert.Equal(t, "username:alice", event.Key)
+	assert.Equal(t, "alice", event.Username)
+	assert.Equal(t, "127.0.0.1", event.IPAddress)
+	assert.Equal(t, 3, event.Failures)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), event.LockedUntil, 5*time.Second)
+}
+
+func TestLoginLocksIPAfterMaxFailures(t *testing.T) {
+	attempts := newFakeLoginAttemptStore()
+	throttle := testThrottle()
+	throttle.MaxIPFailures = 3
+	server := newTokenServer(t, attempts, throttle)
+
+	// a different username every time, so only the IP key adds up
+	for _, username := range []string{"bob", "carol", "dave"} {
+		res := login(t, server, username, "wrong")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		attempts.age("ip:127.0.0.1", time.Minute)
+	}
+
+	res := login(t, server, "alice", "correct horse")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	require.Len(t, attempts.events, 1)
+	assert.Equal(t, "ip:127.0.0.1", attempts.events[0].Key)
+	assert.Equal(t, "dave", attempts.events[0].Username)
+
+	// the throttled IP must not count the attempt against alice either
+	assert.Equal(t, 0, attempts.failures("username:alice"))
+}
+
+func TestLoginUnknownUserAndWrongPasswordLookAlike(t *testing.T) {
+	attempts := newFakeLoginAttemptStore()
+	server := newTokenServer(t, attempts, testThrottle())
+
+	wrongPassword := login(t, server, "alice", "wrong")
+	body, err := io.ReadAll(wrongPassword.Body)
+	require.NoError(t, err)
+	attempts.age("ip:127.0.0.1", time.Minute)
+
+	unknownUser := login(t, server, "mallory", "wrong")
+	unknownBody, err := io.ReadAll(unknownUser.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, unknownUser.StatusCode)
+	assert.JSONEq(t, string(body), string(unknownBody))
+	assert.Equal(t, 1, attempts.failures("username:mallory"))
+}
+
+func TestLoginSuccessResetsOnlyUsername(t *testing.T) {
+	attempts := newFakeLoginAttemptStore()
+	server := newTokenServer(t, attempts, testThrottle())
+
+	for _, username := range []string{"alice", "bob"} {
+		res := login(t, server, username, "wrong")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		attempts.age("username:"+username, time.Minute)
+		attempts.age("ip:127.0.0.1", time.Minute)
+	}
+
+	res := login(t, server, "alice", "correct horse")
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	assert.Equal(t, 0, attempts.failures("username:alice"))
+	assert.Equal(t, 1, attempts.failures("username:bob"))
+	assert.Equal(t, 2, attempts.failures("ip:127.0.0.1"))
+}
+
+func TestLoginParallelGuessesAreThrottled(t *testing.T) {
+	attempts := newFakeLoginAttemptStore()
+	server := newTokenServer(t, attempts, testThrottle())
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := http.Post(server.URL, "application/json", strings.NewReader(`{"username":"alice","password":"wrong"}`))
+			if err != nil {
+				return
+			}
+			res.Body.Close()
+			statuses <- res.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	assert.Equal(t, 1, counts[http.StatusUnauthorized])
+	assert.Equal(t, 9, counts[http.StatusTooManyRequests])
+}
@@ -0,0 +1,179 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/api"
+	"github.com/gbuenodev/goProject/internal/jsonpatch"
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/gbuenodev/goProject/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWorkoutStore keeps a single workout in memory. Methods the tests don't
+// use panic through the nil embedded interface.
+type fakeWorkoutStore struct {
+	store.WorkoutStore
+	workout store.Workout
+}
+
+func (s *fakeWorkoutStore) GetWorkoutOwner(id int64) (int, error) {
+	return s.workout.UserID, nil
+}
+
+func (s *fakeWorkoutStore) GetWorkoutByID(id int64) (*store.Workout, error) {
+	workout := s.workout
+	workout.Entries = slices.Clone(s.workout.Entries)
+	slices.SortFunc(workout.Entries, func(a, b store.WorkoutEntry) int {
+		return a.OrderIndex - b.OrderIndex
+	})
+	return &workout, nil
+}
+
+func (s *fakeWorkoutStore) UpdateWorkoutByID(workout *store.Workout, authorID int) error {
+	if workout.Version != s.workout.Version {
+		return store.ErrVersionConflict
+	}
+	workout.Version++
+	s.workout = *workout
+	s.workout.Entries = slices.Clone(workout.Entries)
+	return nil
+}
+
+type fakeRecordStore struct {
+	store.RecordStore
+}
+
+func (s *fakeRecordStore) ReplaceWorkoutRecords(userID int, workoutID int64, performedAt time.Time, records []strength.Record) ([]store.PersonalRecord, error) {
+	return []store.PersonalRecord{}, nil
+}
+
+func newPatchServer(t *testing.T, workoutStore *fakeWorkoutStore) *httptest.Server {
+	return newWorkoutServer(t, workoutStore, units.Kilograms)
+}
+
+// newWorkoutServer serves the workout routes the tests use to a user who
+// prefers preferredUnit.
+func newWorkoutServer(t *testing.T, workoutStore *fakeWorkoutStore, preferredUnit units.Unit) *httptest.Server {
+	t.Helper()
+	user := &store.User{ID: 1, Role: store.RoleUser, Timezone: "UTC", PreferredUnit: preferredUnit}
+	handler := api.NewWorkoutHandler(workoutStore, &fakeRecordStore{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, middleware.SetUser(r, user))
+		})
+	})
+	r.Get("/workouts/{id}", handler.HandleGetWorkoutByID)
+	r.Patch("/workouts/{id}", handler.HandlePatchWorkoutByID)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func patchWorkout(t *testing.T, server *httptest.Server, version int, patch string) *http.Response {
+	return patchWorkoutURL(t, server.URL+"/workouts/1", version, patch)
+}
+
+func patchWorkoutURL(t *testing.T, url string, version int, patch string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(patch))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", jsonpatch.PatchContentType)
+	req.Header.Set("If-Match", utils.ETag(version))
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func entryIDs(workout *store.Workout) []int {
+	ids := make([]int, len(workout.Entries))
+	for i, entry := range workout.Entries {
+		ids[i] = entry.ID
+	}
+	return ids
+}
+
+func newFakeWorkout() store.Workout {
+	return store.Workout{
+		ID:          1,
+		UserID:      1,
+		Title:       "Push Day",
+		Version:     1,
+		PerformedAt: time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC),
+		Timezone:    "UTC",
+		Entries: []store.WorkoutEntry{
+			{ID: 11, ExerciseName: "Bench Press", Sets: 3, WeightUnit: units.Kilograms, OrderIndex: 1},
+			{ID: 12, ExerciseName: "Overhead Press", Sets: 3, WeightUnit: units.Kilograms, OrderIndex: 2},
+			{ID: 13, ExerciseName: "Dips", Sets: 3, WeightUnit: units.Kilograms, OrderIndex: 3},
+		},
+	}
+}
+
+func TestPatchWorkoutMoveEntry(t *testing.T) {
+	workoutStore := &fakeWorkoutStore{workout: newFakeWorkout()}
+	server := newPatchServer(t, workoutStore)
+
+	res := patchWorkout(t, server, 1, `[{"op":"move","from":"/entries/id:13","path":"/entries/0"}]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var body struct {
+		Workout store.Workout `json:"workout"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	assert.Equal(t, []int{13, 11, 12}, entryIDs(&body.Workout))
+
+	saved, err := workoutStore.GetWorkoutByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{13, 11, 12}, entryIDs(saved))
+	for i, entry := range saved.Entries {
+		assert.Equal(t, i+1, entry.OrderIndex)
+	}
+}
+
+func TestPatchWorkoutAddEntry(t *testing.T) {
+	workoutStore := &fakeWorkoutStore{workout: newFakeWorkout()}
+	server := newPatchServer(t, workoutStore)
+
+	res := patchWorkout(t, server, 1, `[{"op":"add","path":"/entries/1","value":{"exercise_name":"Incline Press","sets":3}}]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	saved, err := workoutStore.GetWorkoutByID(1)
+	require.NoError(t, err)
+	require.Len(t, saved.Entries, 4)
+	assert.Equal(t, "Incline Press", saved.Entries[1].ExerciseName)
+	assert.Equal(t, []int{11, 0, 12, 13}, entryIDs(saved))
+}
+
+func TestPatchWorkoutEditOrderIndex(t *testing.T) {
+	workoutStore := &fakeWorkoutStore{workout: newFakeWorkout()}
+	server := newPatchServer(t, workoutStore)
+
+	// order_index edited directly wins over the array position
+	res := patchWorkout(t, server, 1, `[
+		{"op":"replace","path":"/entries/id:11/order_index","value":3},
+		{"op":"replace","path":"/entries/id:13/order_index","value":1}
+	]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	saved, err := workoutStore.GetWorkoutByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{13, 12, 11}, entryIDs(saved))
+}
@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type workoutResponse struct {
+	Unit    units.Unit    `json:"unit"`
+	Workout store.Workout `json:"workout"`
+}
+
+func getWorkout(t *testing.T, url string) workoutResponse {
+	t.Helper()
+	res, err := http.Get(url)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var body workoutResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	return body
+}
+
+// newMixedUnitWorkout has a bench press logged in kg and a squat logged in
+// lb, stored in kg.
+func newMixedUnitWorkout() store.Workout {
+	workout := newFakeWorkout()
+	workout.Entries = []store.WorkoutEntry{
+		{ID: 11, ExerciseName: "Bench Press", Sets: 3, Weight: FloatPtr(100), WeightUnit: units.Kilograms, OrderIndex: 1},
+		{ID: 12, ExerciseName: "Squat", Sets: 3, Weight: FloatPtr(units.ToKilograms(225, units.Pounds)), WeightUnit: units.Pounds, OrderIndex: 2},
+	}
+	return workout
+}
+
+func FloatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestGetWorkoutInPreferredUnit(t *testing.T) {
+	server := newWorkoutServer(t, &fakeWorkoutStore{workout: newMixedUnitWorkout()}, units.Pounds)
+
+	body := getWorkout(t, server.URL+"/workouts/1")
+	assert.Equal(t, units.Pounds, body.Unit)
+	require.Len(t, body.Workout.Entries, 2)
+	assert.Equal(t, 220.462, *body.Workout.Entries[0].Weight)
+	assert.Equal(t, units.Pounds, body.Workout.Entries[0].WeightUnit, "weight_unit is the unit of the weights read")
+	assert.Equal(t, units.Kilograms, body.Workout.Entries[0].LoggedUnit)
+	assert.InDelta(t, 225, *body.Workout.Entries[1].Weight, 0.001)
+	assert.Equal(t, units.Pounds, body.Workout.Entries[1].WeightUnit)
+	assert.Equal(t, units.Pounds, body.Workout.Entries[1].LoggedUnit)
+
+	body = getWorkout(t, server.URL+"/workouts/1?unit=kg")
+	assert.Equal(t, units.Kilograms, body.Unit)
+	assert.InDelta(t, 100, *body.Workout.Entries[0].Weight, 0.001)
+	assert.Equal(t, units.Kilograms, body.Workout.Entries[0].WeightUnit)
+	assert.InDelta(t, units.ToKilograms(225, units.Pounds), *body.Workout.Entries[1].Weight, 0.001)
+	assert.Equal(t, units.Kilograms, body.Workout.Entries[1].WeightUnit)
+	assert.Equal(t, units.Pounds, body.Workout.Entries[1].LoggedUnit)
+
+	res, err := http.Get(server.URL + "/workouts/1?unit=stone")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestPatchWorkoutKeepsWeightsOfOtherUnits(t *testing.T) {
+	workoutStore := &fakeWorkoutStore{workout: newMixedUnitWorkout()}
+	server := newWorkoutServer(t, workoutStore, units.Pounds)
+
+	// weights in the patch are in the preferred unit, like those read
+	res := patchWorkoutURL(t, server.URL+"/workouts/1", 1, `[{"op":"replace","path":"/entries/id:12/weight","value":235}]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	saved, err := workoutStore.GetWorkoutByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, *saved.Entries[0].Weight, "untouched entries logged in another unit keep their weight")
+	assert.Equal(t, units.Kilograms, saved.Entries[0].WeightUnit)
+	assert.InDelta(t, units.ToKilograms(235, units.Pounds), *saved.Entries[1].Weight, 0.001)
+	assert.Equal(t, units.Pounds, saved.Entries[1].WeightUnit)
+}
+
+func TestPatchWorkoutConvertsEachEntryFromItsWeightUnit(t *testing.T) {
+	workoutStore := &fakeWorkoutStore{workout: newMixedUnitWorkout()}
+	server := newWorkoutServer(t, workoutStore, units.Kilograms)
+
+	// the bench press is rewritten in pounds, the new entry in the request unit
+	res := patchWorkoutURL(t, server.URL+"/workouts/1", 1, `[
+		{"op":"replace","path":"/entries/id:11/weight","value":225},
+		{"op":"replace","path":"/entries/id:11/weight_unit","value":"lb"},
+		{"op":"remove","path":"/entries/id:11/logged_unit"},
+		{"op":"add","path":"/entries/-","value":{"exercise_name":"Row","sets":3,"weight":60,"order_index":3}}
+	]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	saved, err := workoutStore.GetWorkoutByID(1)
+	require.NoError(t, err)
+	require.Len(t, saved.Entries, 3)
+	assert.InDelta(t, 102.058, *saved.Entries[0].Weight, 0.001)
+	assert.Equal(t, units.Pounds, saved.Entries[0].WeightUnit)
+	assert.InDelta(t, units.ToKilograms(225, units.Pounds), *saved.Entries[1].Weight, 0.001, "untouched entries keep their weight")
+	assert.Equal(t, units.Pounds, saved.Entries[1].WeightUnit, "and the unit they were logged in")
+	assert.Equal(t, 60.0, *saved.Entries[2].Weight)
+	assert.Equal(t, units.Kilograms, saved.Entries[2].WeightUnit)
+	for _, entry := range saved.Entries {
+		assert.Empty(t, entry.LoggedUnit)
+	}
+
+	// the response pairs every weight with its unit
+	var body workoutResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	require.Len(t, body.Workout.Entries, 3)
+	assert.InDelta(t, 102.058, *body.Workout.Entries[0].Weight, 0.001)
+	assert.Equal(t, units.Kilograms, body.Workout.Entries[0].WeightUnit)
+	assert.Equal(t, units.Pounds, body.Workout.Entries[0].LoggedUnit)
+
+	res = patchWorkoutURL(t, server.URL+"/workouts/1", 2, `[{"op":"replace","path":"/entries/id:11/weight_unit","value":"stone"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+}
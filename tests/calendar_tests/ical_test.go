@@ -0,0 +1,41 @@
+package calendar_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFeed(t *testing.T) {
+	start := time.Date(2026, 3, 2, 18, 30, 0, 0, time.UTC)
+	events := []calendar.Event{{
+		UID:         "planned-1-20260302@example",
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Summary:     "Legs; heavy, then core",
+		Description: strings.Repeat("Squat ", 20) + "\nPlank",
+		Status:      "TENTATIVE",
+	}}
+
+	var buf bytes.Buffer
+	err := calendar.WriteFeed(&buf, "Workouts", events, start)
+	require.NoError(t, err)
+	feed := buf.String()
+
+	assert.True(t, strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(feed, "END:VCALENDAR\r\n"))
+	assert.Contains(t, feed, "DTSTART:20260302T183000Z\r\n")
+	assert.Contains(t, feed, "DTEND:20260302T193000Z\r\n")
+	assert.Contains(t, feed, `SUMMARY:Legs\; heavy\, then core`)
+
+	for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+	unfolded := strings.ReplaceAll(feed, "\r\n ", "")
+	assert.Contains(t, unfolded, `Squat \nPlank`)
+}
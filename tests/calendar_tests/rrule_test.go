@@ -0,0 +1,111 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dates(times []time.Time) []string {
+	formatted := make([]string, len(times))
+	for i, t := range times {
+		formatted[i] = t.Format("2006-01-02 15:04")
+	}
+	return formatted
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := calendar.ParseRule("FREQ=WEEKLY;BYDAY=TH,MO;INTERVAL=2;COUNT=6", time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, calendar.Weekly, rule.Freq)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, rule.ByDay)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=6", rule.String())
+
+	for _, invalid := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260101",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		_, err := calendar.ParseRule(invalid, time.UTC)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	require.NoError(t, err)
+
+	// a Monday
+	start := time.Date(2026, 3, 2, 18, 30, 0, 0, lisbon)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, lisbon)
+	to := time.Date(2027, 1, 1, 0, 0, 0, 0, lisbon)
+
+	tests := []struct {
+		name  string
+		rule  string
+		from  time.Time
+		to    time.Time
+		wants []string
+	}{
+		{
+			name:  "weekly on two days",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4",
+			wants: []string{"2026-03-02 18:30", "2026-03-05 18:30", "2026-03-09 18:30", "2026-03-12 18:30"},
+		},
+		{
+			name:  "keeps the wall clock time across DST",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;UNTIL=20260413",
+			wants: []string{"2026-03-02 18:30", "2026-03-16 18:30", "2026-03-30 18:30", "2026-04-13 18:30"},
+		},
+		{
+			name:  "daily within the window only",
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			from:  time.Date(2026, 3, 6, 0, 0, 0, 0, lisbon),
+			to:    time.Date(2026, 3, 15, 0, 0, 0, 0, lisbon),
+			wants: []string{"2026-03-08 18:30", "2026-03-11 18:30", "2026-03-14 18:30"},
+		},
+		{
+			name:  "count includes occurrences before the window",
+			rule:  "FREQ=DAILY;COUNT=3",
+			from:  time.Date(2026, 3, 3, 0, 0, 0, 0, lisbon),
+			wants: []string{"2026-03-03 18:30", "2026-03-04 18:30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := calendar.ParseRule(tt.rule, lisbon)
+			require.NoError(t, err)
+
+			windowFrom, windowTo := from, to
+			if !tt.from.IsZero() {
+				windowFrom = tt.from
+			}
+			if !tt.to.IsZero() {
+				windowTo = tt.to
+			}
+
+			assert.Equal(t, tt.wants, dates(calendar.Expand(rule, start, windowFrom, windowTo)))
+		})
+	}
+
+	t.Run("monthly skips short months", func(t *testing.T) {
+		rule, err := calendar.ParseRule("FREQ=MONTHLY;COUNT=3", lisbon)
+		require.NoError(t, err)
+
+		start := time.Date(2026, 1, 31, 7, 0, 0, 0, lisbon)
+		assert.Equal(t, []string{"2026-01-31 07:00", "2026-03-31 07:00", "2026-05-31 07:00"}, dates(calendar.Expand(rule, start, from, to)))
+	})
+
+	t.Run("no rule", func(t *testing.T) {
+		assert.Len(t, calendar.Expand(nil, start, from, to), 1)
+		assert.Empty(t, calendar.Expand(nil, start, to, to.AddDate(1, 0, 0)))
+	})
+}
@@ -0,0 +1,83 @@
+package jsonpatch_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/jsonpatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const workoutDoc = `{
+	"id": 1,
+	"title": "Push day",
+	"entries": [
+		{"id": 10, "exercise_name": "Bench Press", "reps": 10},
+		{"id": 11, "exercise_name": "Dips", "reps": 12}
+	]
+}`
+
+func TestMergePatch(t *testing.T) {
+	patched, err := jsonpatch.MergePatch([]byte(`{"a": "b", "c": {"d": "e", "f": "g"}}`), []byte(`{"a": "z", "c": {"f": null}}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "z", "c": {"d": "e"}}`, string(patched))
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "replace entry field by id",
+			patch: `[{"op": "replace", "path": "/entries/id:11/reps", "value": 8}]`,
+			want: `{"id": 1, "title": "Push day", "entries": [
+				{"id": 10, "exercise_name": "Bench Press", "reps": 10},
+				{"id": 11, "exercise_name": "Dips", "reps": 8}]}`,
+		},
+		{
+			name:  "remove entry by id and append a new one",
+			patch: `[{"op": "remove", "path": "/entries/id:10"}, {"op": "add", "path": "/entries/-", "value": {"exercise_name": "Plank"}}]`,
+			want: `{"id": 1, "title": "Push day", "entries": [
+				{"id": 11, "exercise_name": "Dips", "reps": 12},
+				{"exercise_name": "Plank"}]}`,
+		},
+		{
+			name:  "move entry to the front",
+			patch: `[{"op": "move", "from": "/entries/id:11", "path": "/entries/0"}]`,
+			want: `{"id": 1, "title": "Push day", "entries": [
+				{"id": 11, "exercise_name": "Dips", "reps": 12},
+				{"id": 10, "exercise_name": "Bench Press", "reps": 10}]}`,
+		},
+		{
+			name:    "unknown entry id",
+			patch:   `[{"op": "remove", "path": "/entries/id:99"}]`,
+			wantErr: jsonpatch.ErrPathNotFound,
+		},
+		{
+			name:    "failing test aborts the patch",
+			patch:   `[{"op": "test", "path": "/title", "value": "Pull day"}, {"op": "remove", "path": "/title"}]`,
+			wantErr: jsonpatch.ErrTestFailed,
+		},
+		{
+			name:    "unknown operation",
+			patch:   `[{"op": "frobnicate", "path": "/title"}]`,
+			wantErr: jsonpatch.ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := jsonpatch.Apply([]byte(workoutDoc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(patched))
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package oidc_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID = "workout-api"
+	testKeyID    = "test-key"
+	testCode     = "auth-code"
+)
+
+// mockProvider is a minimal OpenID provider: discovery, keys and a token
+// endpoint that checks the PKCE verifier against the challenge it was given.
+type mockProvider struct {
+	t             *testing.T
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	codeChallenge string
+	nonce         string
+	claims        map[string]any
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		if r.PostForm.Get("code") != testCode || oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != m.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.sign(m.idTokenClaims()),
+		})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// authorize plays the user logging in at the provider.
+func (m *mockProvider) authorize(authURL string) {
+	u, err := url.Parse(authURL)
+	require.NoError(m.t, err)
+	require.Equal(m.t, "S256", u.Query().Get("code_challenge_method"))
+
+	m.codeChallenge = u.Query().Get("code_challenge")
+	m.nonce = u.Query().Get("nonce")
+}
+
+func (m *mockProvider) idTokenClaims() map[string]any {
+	claims := map[string]any{
+		"iss":            m.server.URL,
+		"sub":            "external-123",
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          m.nonce,
+		"email":          "test@email.com",
+		"email_verified": true,
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	return claims
+}
+
+func (m *mockProvider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": testKeyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	require.NoError(m.t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (m *mockProvider) provider() *oidc.Provider {
+	return oidc.NewProvider(oidc.ProviderConfig{
+		Name:        "mock",
+		Issuer:      m.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:8080/auth/oidc/mock/callback",
+	}, m.server.Client())
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL("state", "nonce-1", verifier)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, mock.server.URL+"/authorize?"))
+	mock.authorize(authURL)
+
+	claims, err := provider.Exchange(testCode, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "external-123", claims.Subject)
+	assert.Equal(t, "test@email.com", claims.Email)
+	assert.True(t, bool(claims.EmailVerified))
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+
+	authURL, err := provider.AuthCodeURL("state", "nonce-1", "the-real-verifier")
+	require.NoError(t, err)
+	mock.authorize(authURL)
+
+	_, err = provider.Exchange(testCode, "a-stolen-code-without-verifier", "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	mock.nonce = "nonce-1"
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		nonce   string
+		wantErr error
+	}{
+		{name: "valid", nonce: "nonce-1"},
+		{name: "email_verified as string", claims: map[string]any{"email_verified": "true"}, nonce: "nonce-1"},
+		{name: "wrong nonce", nonce: "other", wantErr: oidc.ErrNonceMismatch},
+		{name: "expired", claims: map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, nonce: "nonce-1", wantErr: oidc.ErrTokenExpired},
+		{name: "other issuer", claims: map[string]any{"iss": "https://evil.example"}, nonce: "nonce-1", wantErr: oidc.ErrIssuerMismatch},
+		{name: "other audience", claims: map[string]any{"aud": []string{"someone-else"}}, nonce: "nonce-1", wantErr: oidc.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.claims = tt.claims
+			_, err := provider.VerifyIDToken(mock.sign(mock.idTokenClaims()), tt.nonce)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("tampered signature", func(t *testing.T) {
+		mock.claims = nil
+		token := mock.sign(mock.idTokenClaims())
+		parts := strings.Split(token, ".")
+		payload, _ := json.Marshal(map[string]any{"sub": "admin"})
+		tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+
+		_, err := provider.VerifyIDToken(tampered, "nonce-1")
+		assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+	})
+}
@@ -0,0 +1,111 @@
+package passwords_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/passwords"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters, the tests are about the format, not the cost
+var testParams = passwords.Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := passwords.NewArgon2idHasher(testParams)
+
+	hash, err := hasher.Hash("Sup3rSecr3tPass#!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, hasher.Identifies(hash))
+	assert.True(t, hasher.IsCurrent(hash))
+
+	ok, err := hasher.Matches("Sup3rSecr3tPass#!", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = hasher.Matches("wrong password", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	stronger := testParams
+	stronger.Iterations = 2
+	assert.False(t, passwords.NewArgon2idHasher(stronger).IsCurrent(hash))
+}
+
+func TestArgon2idHasherLongPasswords(t *testing.T) {
+	hasher := passwords.NewArgon2idHasher(testParams)
+	long := strings.Repeat("a", 100)
+
+	hash, err := hasher.Hash(long + "1")
+	require.NoError(t, err)
+
+	// bcrypt would ignore everything after the 72nd byte
+	ok, err := hasher.Matches(long+"2", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestManagerUpgradesLegacyHashes(t *testing.T) {
+	manager := passwords.NewManager(
+		passwords.NewArgon2idHasher(testParams),
+		passwords.NewBcryptHasher(bcrypt.MinCost),
+	)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Sup3rSecr3tPass#!"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ok, err := manager.Matches("Sup3rSecr3tPass#!", legacy)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, manager.NeedsRehash(legacy))
+
+	upgraded, err := manager.Hash("Sup3rSecr3tPass#!")
+	require.NoError(t, err)
+	assert.False(t, manager.NeedsRehash(upgraded))
+
+	ok, err = manager.Matches("Sup3rSecr3tPass#!", upgraded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = manager.Matches("Sup3rSecr3tPass#!", []byte("plaintext"))
+	assert.ErrorIs(t, err, passwords.ErrUnknownHashFormat)
+}
+
+// countingHasher records the work asked of it.
+type countingHasher struct {
+	*passwords.Argon2idHasher
+	hashes  int
+	matches int
+}
+
+func (h *countingHasher) Hash(plainText string) ([]byte, error) {
+	h.hashes++
+	return h.Argon2idHasher.Hash(plainText)
+}
+
+func (h *countingHasher) Matches(plainText string, hash []byte) (bool, error) {
+	h.matches++
+	return h.Argon2idHasher.Matches(plainText, hash)
+}
+
+func TestManagerSimulateMatches(t *testing.T) {
+	hasher := &countingHasher{Argon2idHasher: passwords.NewArgon2idHasher(testParams)}
+	manager := passwords.NewManager(hasher)
+
+	manager.SimulateMatches("Sup3rSecr3tPass#!")
+	manager.SimulateMatches("another guess")
+
+	// a login for a missing user verifies like a real one, the dummy hash
+	// is only made once
+	assert.Equal(t, 1, hasher.hashes)
+	assert.Equal(t, 2, hasher.matches)
+}
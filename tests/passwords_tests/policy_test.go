@@ -0,0 +1,100 @@
+package passwords_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/passwords"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := passwords.DefaultPolicy
+	inputs := passwords.UserInputs{Username: "test_user", Email: "lifter@email.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "passphrase without symbols or digits", password: "correct horse battery staple", wantErr: false},
+		{name: "mixed characters", password: "Sup3rSecr3tPass#!", wantErr: false},
+		{name: "symbols outside the old set", password: "Gr8^Lift~Day_2024", wantErr: false},
+		{name: "too short", password: "Ab1#", wantErr: true},
+		{name: "common password with substitutions", password: "P@ssw0rd1!", wantErr: true},
+		{name: "sequence", password: "abcdefghij", wantErr: true},
+		{name: "repetition", password: "aaaaaaaaaaaaaaa", wantErr: true},
+		{name: "contains username", password: "my test_user is great 42", wantErr: true},
+		{name: "contains email local part", password: "Lifter-rocks-the-gym-99", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, inputs)
+			if tt.wantErr {
+				assert.True(t, passwords.IsValidationError(err), "got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStrengthOrdering(t *testing.T) {
+	assert.Less(t, passwords.Strength("password"), passwords.Strength("Tr0ub4dor&3"))
+	assert.Less(t, passwords.Strength("hunter22"), passwords.Strength("correct horse battery staple"))
+	assert.Equal(t, 4, passwords.Strength("correct horse battery staple"))
+}
+
+func TestBreachedListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	content := sha1Hex("correct horse battery staple") + ":3645804\n" + strings.ToLower(sha1Hex("Sup3rSecr3tPass#!")) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	list, err := passwords.NewBreachedList(path)
+	require.NoError(t, err)
+
+	breached, err := list.IsBreached("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = list.IsBreached("Sup3rSecr3tPass#!")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = list.IsBreached("Gr8^Lift~Day_2024")
+	require.NoError(t, err)
+	assert.False(t, breached)
+
+	policy := passwords.DefaultPolicy
+	policy.Breached = list
+	err = policy.Validate("correct horse battery staple", passwords.UserInputs{})
+	assert.True(t, passwords.IsValidationError(err))
+}
+
+func TestBreachedListRangeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("correct horse battery staple")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(hash[5:]+":3645804\r\n"), 0o600))
+
+	list, err := passwords.NewBreachedList(dir)
+	require.NoError(t, err)
+
+	breached, err := list.IsBreached("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = list.IsBreached("Gr8^Lift~Day_2024")
+	require.NoError(t, err)
+	assert.False(t, breached)
+}
@@ -0,0 +1,89 @@
+package policy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/middleware"
+	"github.com/gbuenodev/goProject/internal/policy"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCan(t *testing.T) {
+	suspendedAt := time.Now()
+	owner := &store.User{ID: 1, Role: store.RoleUser}
+	other := &store.User{ID: 2, Role: store.RoleUser}
+	coach := &store.User{ID: 3, Role: store.RoleCoach}
+	admin := &store.User{ID: 4, Role: store.RoleAdmin}
+	suspendedOwner := &store.User{ID: 1, Role: store.RoleUser, SuspendedAt: &suspendedAt}
+	suspendedAdmin := &store.User{ID: 4, Role: store.RoleAdmin, SuspendedAt: &suspendedAt}
+
+	owned := policy.Resource{OwnerID: 1}
+	unowned := policy.Resource{}
+
+	tests := []struct {
+		name     string
+		user     *store.User
+		action   policy.Action
+		resource policy.Resource
+		want     bool
+	}{
+		{name: "nil user", user: nil, action: policy.ActionReadWorkout, resource: owned},
+		{name: "anonymous", user: store.AnonymousUser, action: policy.ActionReadWorkout, resource: owned},
+		{name: "owner reads", user: owner, action: policy.ActionReadWorkout, resource: owned, want: true},
+		{name: "owner updates", user: owner, action: policy.ActionUpdateWorkout, resource: owned, want: true},
+		{name: "owner deletes", user: owner, action: policy.ActionDeleteWorkout, resource: owned, want: true},
+		{name: "owner manages a template", user: owner, action: policy.ActionManageTemplate, resource: owned, want: true},
+		{name: "owner moderates", user: owner, action: policy.ActionModerateWorkout, resource: owned},
+		{name: "owner lists users", user: owner, action: policy.ActionListUsers, resource: unowned},
+		{name: "other user reads", user: other, action: policy.ActionReadWorkout, resource: owned},
+		{name: "other user updates", user: other, action: policy.ActionUpdateWorkout, resource: owned},
+		{name: "coach reads another user's workout", user: coach, action: policy.ActionReadWorkout, resource: owned},
+		{name: "coach updates another user's workout", user: coach, action: policy.ActionUpdateWorkout, resource: owned},
+		{name: "user manages a built-in exercise", user: owner, action: policy.ActionManageExercise, resource: unowned},
+		{name: "admin reads", user: admin, action: policy.ActionReadWorkout, resource: owned, want: true},
+		{name: "admin moderates", user: admin, action: policy.ActionModerateWorkout, resource: owned, want: true},
+		{name: "admin manages users", user: admin, action: policy.ActionManageUser, resource: unowned, want: true},
+		{name: "admin manages a built-in exercise", user: admin, action: policy.ActionManageExercise, resource: unowned, want: true},
+		{name: "suspended owner", user: suspendedOwner, action: policy.ActionReadWorkout, resource: owned},
+		{name: "suspended admin", user: suspendedAdmin, action: policy.ActionManageUser, resource: unowned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Can(tt.user, tt.action, tt.resource))
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	um := &middleware.UserMiddleware{}
+	handler := um.RequireRole(store.RoleAdmin, store.RoleCoach)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name string
+		user *store.User
+		want int
+	}{
+		{name: "anonymous", user: store.AnonymousUser, want: http.StatusUnauthorized},
+		{name: "user", user: &store.User{ID: 1, Role: store.RoleUser}, want: http.StatusForbidden},
+		{name: "coach", user: &store.User{ID: 2, Role: store.RoleCoach}, want: http.StatusOK},
+		{name: "admin", user: &store.User{ID: 3, Role: store.RoleAdmin}, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := middleware.SetUser(httptest.NewRequest(http.MethodGet, "/admin/users", nil), tt.user)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
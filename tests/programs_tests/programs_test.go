@@ -0,0 +1,205 @@
+package programs_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/programs"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+const (
+	squat int64 = 1
+	plank int64 = 2
+)
+
+func TestWeight(t *testing.T) {
+	weight, ok := programs.Weight(store.Prescription{Reps: 5, PercentOneRepMax: floatPtr(85)}, 141, 2.5)
+	require.True(t, ok)
+	assert.Equal(t, 120.0, weight, "119.85 rounds to 120")
+
+	// 8 reps at RPE 8 is 10 reps to failure
+	weight, ok = programs.Weight(store.Prescription{Reps: 8, RPE: floatPtr(8)}, 100, 0.5)
+	require.True(t, ok)
+	assert.Equal(t, 75.0, weight)
+
+	_, ok = programs.Weight(store.Prescription{Reps: 5, PercentOneRepMax: floatPtr(85)}, 0, 2.5)
+	assert.False(t, ok, "no training max")
+}
+
+func TestNextDay(t *testing.T) {
+	days := []store.ProgramDay{{Week: 1, Day: 1}, {Week: 1, Day: 3}, {Week: 2, Day: 1}}
+
+	next, ok := programs.NextDay(days, 1, 1)
+	require.True(t, ok)
+	assert.Equal(t, 3, next.Day)
+
+	next, ok = programs.NextDay(days, 1, 3)
+	require.True(t, ok)
+	assert.Equal(t, 2, next.Week)
+
+	_, ok = programs.NextDay(days, 2, 1)
+	assert.False(t, ok)
+}
+
+func fiveThreeOneDay() *store.ProgramDay {
+	return &store.ProgramDay{
+		Week: 1,
+		Day:  1,
+		Prescriptions: []store.Prescription{
+			{ExerciseID: squat, Sets: 1, Reps: 5, PercentOneRepMax: floatPtr(65)},
+			{ExerciseID: squat, Sets: 1, Reps: 5, PercentOneRepMax: floatPtr(75)},
+			{ExerciseID: squat, Sets: 1, Reps: 5, PercentOneRepMax: floatPtr(85), AMRAP: true},
+		},
+	}
+}
+
+func TestPrescribe(t *testing.T) {
+	template := &store.WorkoutTemplate{
+		Title: "Squat day",
+		Entries: []store.WorkoutEntry{
+			{ID: 1, ExerciseName: "Warm-up", Sets: 1, DurationSeconds: intPtr(300), OrderIndex: 1},
+			{ID: 2, ExerciseID: int64Ptr(squat), ExerciseName: "Squat", Sets: 3, Reps: intPtr(5), OrderIndex: 2},
+			{ID: 3, ExerciseID: int64Ptr(plank), ExerciseName: "Plank", Sets: 3, DurationSeconds: intPtr(60), OrderIndex: 3},
+		},
+	}
+
+	session := programs.Prescribe(fiveThreeOneDay(), template, []store.TrainingMax{{ExerciseID: squat, Weight: 100}}, 2.5, units.Kilograms, 7)
+
+	assert.Equal(t, 7, session.Workout.UserID)
+	assert.Equal(t, "Squat day", session.Workout.Title)
+	require.Len(t, session.Workout.Entries, 5)
+
+	squats := session.Workout.Entries[1:4]
+	for i, want := range []float64{65, 75, 85} {
+		assert.Equal(t, "Squat", squats[i].ExerciseName)
+		assert.Equal(t, 0, squats[i].ID)
+		require.NotNil(t, squats[i].Weight)
+		assert.Equal(t, want, *squats[i].Weight)
+		assert.Equal(t, 5, *squats[i].Reps)
+	}
+	assert.Contains(t, squats[2].Notes, "AMRAP")
+	assert.Equal(t, "Plank", session.Workout.Entries[4].ExerciseName)
+	assert.Equal(t, 5, session.Workout.Entries[4].OrderIndex)
+
+	require.Len(t, session.Targets, 3)
+	assert.Equal(t, 85.0, *session.Targets[2].Weight)
+}
+
+func TestPrescribeInPounds(t *testing.T) {
+	template := &store.WorkoutTemplate{Entries: []store.WorkoutEntry{{ExerciseID: int64Ptr(squat), ExerciseName: "Squat", Sets: 3, Reps: intPtr(5)}}}
+
+	// a 100 kg training max is 220.46 lb, rounded to 5 lb plates
+	session := programs.Prescribe(fiveThreeOneDay(), template, []store.TrainingMax{{ExerciseID: squat, Weight: 100}}, 5, units.Pounds, 7)
+
+	require.Len(t, session.Workout.Entries, 3)
+	for i, want := range []float64{145, 165, 185} {
+		entry := session.Workout.Entries[i]
+		assert.Equal(t, units.Pounds, entry.WeightUnit)
+		require.NotNil(t, entry.Weight)
+		assert.Equal(t, want, units.FromKilograms(*entry.Weight, units.Pounds))
+		assert.Equal(t, units.Pounds, session.Targets[i].WeightUnit)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	rules := []store.ProgressionRule{{ExerciseID: squat, Increment: 5}}
+	template := &store.WorkoutTemplate{Entries: []store.WorkoutEntry{{ExerciseID: int64Ptr(squat), ExerciseName: "Squat", Sets: 3, Reps: intPtr(5)}}}
+	session := programs.Prescribe(fiveThreeOneDay(), template, []store.TrainingMax{{ExerciseID: squat, Weight: 100}}, 2.5, units.Kilograms, 7)
+
+	tests := []struct {
+		name    string
+		entries []store.WorkoutEntry
+		want    bool
+	}{
+		{
+			name: "all targets met",
+			entries: []store.WorkoutEntry{
+				{ExerciseID: int64Ptr(squat), Sets: 1, Reps: intPtr(5), Weight: floatPtr(65)},
+				{ExerciseID: int64Ptr(squat), Sets: 1, Reps: intPtr(5), Weight: floatPtr(75)},
+				{ExerciseID: int64Ptr(squat), Sets: 1, Reps: intPtr(8), Weight: floatPtr(85)},
+			},
+			want: true,
+		},
+		{
+			name: "heavier sets cover lighter targets",
+			entries: []store.WorkoutEntry{
+				{ExerciseID: int64Ptr(squat), Sets: 3, Reps: intPtr(5), Weight: floatPtr(85)},
+			},
+			want: true,
+		},
+		{
+			name: "missed reps on the top set",
+			entries: []store.WorkoutEntry{
+				{ExerciseID: int64Ptr(squat), Sets: 1, Reps: intPtr(5), Weight: floatPtr(65)},
+				{ExerciseID: int64Ptr(squat), Sets: 1, Reps: intPtr(5), Weight: floatPtr(75)},
+				{ExerciseID: int64Ptr(squat), Sets: 1, Reps: intPtr(3), Weight: floatPtr(85)},
+			},
+			want: false,
+		},
+		{
+			name: "logged set by set",
+			entries: []store.WorkoutEntry{
+				{ExerciseID: int64Ptr(squat), Sets: 3, Reps: intPtr(8), Weight: floatPtr(85), SetDetails: []store.WorkoutSet{
+					{Type: store.SetWarmUp, Reps: intPtr(5), Weight: floatPtr(40)},
+					{Reps: intPtr(5), Weight: floatPtr(65)},
+					{Reps: intPtr(5), Weight: floatPtr(75)},
+					{Reps: intPtr(8), Weight: floatPtr(85)},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "set log lighter than its top set",
+			entries: []store.WorkoutEntry{
+				{ExerciseID: int64Ptr(squat), Sets: 3, Reps: intPtr(8), Weight: floatPtr(85), SetDetails: []store.WorkoutSet{
+					{Reps: intPtr(5), Weight: floatPtr(60)},
+					{Reps: intPtr(5), Weight: floatPtr(70)},
+					{Reps: intPtr(8), Weight: floatPtr(85)},
+				}},
+			},
+			want: false,
+		},
+		{
+			name: "set log with a skipped set",
+			entries: []store.WorkoutEntry{
+				{ExerciseID: int64Ptr(squat), Sets: 2, Reps: intPtr(8), Weight: floatPtr(85), SetDetails: []store.WorkoutSet{
+					{Reps: intPtr(5), Weight: floatPtr(65)},
+					{Reps: intPtr(5), Weight: floatPtr(75), Completed: boolPtr(false)},
+					{Reps: intPtr(8), Weight: floatPtr(85)},
+				}},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			increments := programs.Progress(session.Targets, rules, &store.Workout{Entries: tt.entries})
+			if !tt.want {
+				assert.Empty(t, increments)
+				return
+			}
+			require.Len(t, increments, 1)
+			assert.Equal(t, store.TrainingMax{ExerciseID: squat, Weight: 5}, increments[0])
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketTake(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 1, Burst: 2}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	bucket := ratelimit.NewBucket(limit, start)
+
+	bucket, result := bucket.Take(limit, start)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+
+	bucket, result = bucket.Take(limit, start)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 2*time.Second, result.ResetAfter)
+
+	bucket, result = bucket.Take(limit, start.Add(500*time.Millisecond))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	_, result = bucket.Take(limit, start.Add(time.Second))
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.PerMinute(1, 1)
+
+	result, err := store.Take("api:user:1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take("api:user:1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	result, err = store.Take("api:user:2", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
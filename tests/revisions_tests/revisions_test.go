@@ -0,0 +1,84 @@
+package revisions_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/revisions"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestCompare(t *testing.T) {
+	from := &store.Workout{
+		ID:      1,
+		Title:   "Push day",
+		Version: 2,
+		Entries: []store.WorkoutEntry{
+			{ID: 10, ExerciseName: "Bench Press", Sets: 3, Reps: intPtr(10), OrderIndex: 1},
+			{ID: 11, ExerciseName: "Dips", Sets: 3, Reps: intPtr(12), OrderIndex: 2},
+		},
+	}
+	to := &store.Workout{
+		ID:      1,
+		Title:   "Push day (heavy)",
+		Version: 5,
+		Entries: []store.WorkoutEntry{
+			{ID: 10, ExerciseName: "Bench Press", Sets: 3, Reps: intPtr(6), OrderIndex: 1},
+			{ID: 12, ExerciseName: "Push-up", Sets: 2, Reps: intPtr(20), OrderIndex: 2},
+		},
+	}
+
+	diff := revisions.Compare(from, to)
+
+	assert.Equal(t, 2, diff.FromVersion)
+	assert.Equal(t, 5, diff.ToVersion)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "title", diff.Changes[0].Field)
+
+	require.Len(t, diff.ChangedEntries, 1)
+	assert.Equal(t, 10, diff.ChangedEntries[0].ID)
+	require.Len(t, diff.ChangedEntries[0].Changes, 1)
+	assert.Equal(t, "reps", diff.ChangedEntries[0].Changes[0].Field)
+
+	require.Len(t, diff.AddedEntries, 1)
+	assert.Equal(t, 12, diff.AddedEntries[0].ID)
+	require.Len(t, diff.RemovedEntries, 1)
+	assert.Equal(t, 11, diff.RemovedEntries[0].ID)
+}
+
+func TestRestore(t *testing.T) {
+	past := &store.Workout{
+		ID:      1,
+		Title:   "Push day",
+		Version: 2,
+		Entries: []store.WorkoutEntry{
+			{ID: 10, ExerciseName: "Bench Press", Sets: 3, Reps: intPtr(10), OrderIndex: 1},
+			{ID: 11, ExerciseName: "Dips", Sets: 3, Reps: intPtr(12), OrderIndex: 2},
+		},
+	}
+	current := &store.Workout{
+		ID:      1,
+		UserID:  7,
+		Title:   "Push day (heavy)",
+		Version: 5,
+		Entries: []store.WorkoutEntry{
+			{ID: 10, ExerciseName: "Bench Press", Sets: 3, Reps: intPtr(6), OrderIndex: 1},
+		},
+	}
+
+	restored := revisions.Restore(current, past)
+
+	assert.Equal(t, "Push day", restored.Title)
+	assert.Equal(t, 5, restored.Version, "restoring builds on the current version")
+	assert.Equal(t, 7, restored.UserID)
+	require.Len(t, restored.Entries, 2)
+	assert.Equal(t, 10, restored.Entries[0].ID, "surviving entries keep their ID")
+	assert.Equal(t, 0, restored.Entries[1].ID, "deleted entries are recreated")
+	assert.NotNil(t, restored.Entries[0].SetDetails, "entries logged without sets clear the set log")
+	assert.Equal(t, 11, past.Entries[1].ID, "the revision itself is left untouched")
+}
@@ -0,0 +1,231 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createWorkoutAt(t *testing.T, workoutStore store.WorkoutStore, userID int, performedAt time.Time, entries ...store.WorkoutEntry) *store.Workout {
+	t.Helper()
+
+	workout, err := workoutStore.CreateWorkout(&store.Workout{
+		UserID:          userID,
+		Title:           "Test Workout",
+		PerformedAt:     performedAt,
+		Timezone:        "UTC",
+		DurationMinutes: 45,
+		CaloriesBurned:  300,
+		Entries:         entries,
+	})
+	require.NoError(t, err)
+	return workout
+}
+
+func loggedSet(setType string, reps int, weight float64) store.WorkoutSet {
+	return store.WorkoutSet{Type: setType, Reps: IntPtr(reps), Weight: FloatPtr(weight)}
+}
+
+func TestTrainingStatsCountsHardSets(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+	analyticsStore := store.NewPostgresAnalyticsStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bob := createTestUser(t, DBConn, "bob")
+	require.NoError(t, exerciseStore.CreateExercise(&store.Exercise{
+		Name:             "Bench Press",
+		Aliases:          []string{},
+		PrimaryMuscles:   []string{"chest"},
+		SecondaryMuscles: []string{"triceps"},
+		Equipment:        "barbell",
+		MovementPattern:  "horizontal_push",
+	}))
+
+	notCompleted := loggedSet(store.SetWorking, 5, 100)
+	notCompleted.Completed = BoolPtr(false)
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC), store.WorkoutEntry{
+		ExerciseName: "Bench Press",
+		OrderIndex:   1,
+		SetDetails: []store.WorkoutSet{
+			loggedSet(store.SetWarmUp, 10, 40),
+			loggedSet(store.SetWorking, 5, 100),
+			loggedSet(store.SetFailure, 3, 100),
+			loggedSet(store.SetDrop, 8, 60),
+			notCompleted,
+		},
+	})
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 7, 18, 0, 0, 0, time.UTC),
+		store.WorkoutEntry{ExerciseName: "Row", Sets: 3, Reps: IntPtr(10), Weight: FloatPtr(50), OrderIndex: 1},
+		store.WorkoutEntry{ExerciseName: "Plank", Sets: 3, DurationSeconds: IntPtr(60), OrderIndex: 2},
+	)
+
+	// none of these count
+	trashed := createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 8, 18, 0, 0, 0, time.UTC), namedEntry("Row", 1))
+	trashTestWorkout(t, workoutStore, trashed)
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 12, 18, 0, 0, 0, time.UTC), namedEntry("Row", 1))
+	createWorkoutAt(t, workoutStore, bob.ID, time.Date(2026, 1, 6, 18, 0, 0, 0, time.UTC), namedEntry("Row", 1))
+
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	stats, err := analyticsStore.TrainingStats(alice.ID, from, from.AddDate(0, 0, 7), "UTC")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, stats.Workouts)
+	assert.Equal(t, 2, stats.TrainingDays)
+	assert.Equal(t, 90, stats.DurationMinutes)
+	assert.Equal(t, 600, stats.CaloriesBurned)
+	// working and failure bench sets, and the two entries without a set log
+	assert.Equal(t, 2+3+3, stats.Sets)
+	// the drop set still adds to the tonnage
+	assert.InDelta(t, 5*100+3*100+8*60+3*10*50, stats.Tonnage, 0.001)
+	assert.Equal(t, map[string]float64{"chest": 2, "triceps": 1}, stats.MuscleGroupSets)
+	assert.Equal(t, 2.0, stats.WorkoutsPerWeek)
+	assert.Equal(t, 45.0, stats.AvgDurationMinutes)
+	assert.Equal(t, 4.0, stats.AvgSetsPerWorkout)
+}
+
+func TestTrainingStatsTrainingDaysInTimezone(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	analyticsStore := store.NewPostgresAnalyticsStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 7, 1, 0, 0, 0, time.UTC), namedEntry("Row", 1))
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 7, 23, 0, 0, 0, time.UTC), namedEntry("Row", 1))
+
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	stats, err := analyticsStore.TrainingStats(alice.ID, from, from.AddDate(0, 0, 7), "UTC")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TrainingDays)
+
+	stats, err = analyticsStore.TrainingStats(alice.ID, from, from.AddDate(0, 0, 7), "America/New_York")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TrainingDays)
+}
+
+func TestProgression(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	analyticsStore := store.NewPostgresAnalyticsStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+
+	// week of January 5th, over two workouts
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC),
+		store.WorkoutEntry{
+			ExerciseName: "Squat",
+			OrderIndex:   1,
+			SetDetails: []store.WorkoutSet{
+				loggedSet(store.SetWarmUp, 1, 200),
+				loggedSet(store.SetWorking, 5, 100),
+				loggedSet(store.SetWorking, 3, 110),
+				loggedSet(store.SetWorking, 15, 60),
+			},
+		},
+		store.WorkoutEntry{ExerciseName: "Bench Press", Sets: 5, Reps: IntPtr(5), Weight: FloatPtr(300), OrderIndex: 2},
+	)
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 8, 18, 0, 0, 0, time.UTC),
+		store.WorkoutEntry{ExerciseName: "squat", Sets: 3, Reps: IntPtr(5), Weight: FloatPtr(105), OrderIndex: 1},
+	)
+
+	// week of January 12th, a single
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 13, 18, 0, 0, 0, time.UTC),
+		store.WorkoutEntry{
+			ExerciseName: "Squat",
+			OrderIndex:   1,
+			SetDetails:   []store.WorkoutSet{loggedSet(store.SetWorking, 1, 120), loggedSet(store.SetWorking, 15, 80)},
+		},
+	)
+
+	// week of January 19th, the 13 rep set is the top set but too long to
+	// estimate from
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 20, 18, 0, 0, 0, time.UTC),
+		store.WorkoutEntry{
+			ExerciseName: "Squat",
+			OrderIndex:   1,
+			SetDetails:   []store.WorkoutSet{loggedSet(store.SetWorking, 13, 80), loggedSet(store.SetWorking, 12, 50)},
+		},
+	)
+
+	query := store.ProgressionQuery{
+		UserID:       alice.ID,
+		ExerciseName: "SQUAT",
+		From:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		Timezone:     "UTC",
+		Bucket:       "week",
+		Formula:      strength.Epley,
+	}
+
+	points, err := analyticsStore.Progression(query)
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+
+	assert.Equal(t, "2026-01-05", points[0].Bucket)
+	require.NotNil(t, points[0].EstimatedOneRepMax)
+	assert.InDelta(t, 122.5, *points[0].EstimatedOneRepMax, 0.001)
+	assert.Equal(t, 110.0, points[0].TopSetWeight)
+	assert.Equal(t, 3, points[0].TopSetReps)
+	assert.InDelta(t, 5*100+3*110+15*60+3*5*105, points[0].Volume, 0.001)
+	assert.Equal(t, 6, points[0].Sets)
+	assert.Equal(t, 2, points[0].Workouts)
+
+	assert.Equal(t, "2026-01-12", points[1].Bucket)
+	require.NotNil(t, points[1].EstimatedOneRepMax)
+	assert.InDelta(t, 120.0, *points[1].EstimatedOneRepMax, 0.001)
+	assert.Equal(t, 120.0, points[1].TopSetWeight)
+	assert.Equal(t, 1, points[1].TopSetReps)
+	assert.Equal(t, 1, points[1].Workouts)
+
+	assert.Equal(t, "2026-01-19", points[2].Bucket)
+	require.NotNil(t, points[2].EstimatedOneRepMax)
+	assert.InDelta(t, 70.0, *points[2].EstimatedOneRepMax, 0.001)
+	assert.Equal(t, 80.0, points[2].TopSetWeight)
+	assert.Equal(t, 13, points[2].TopSetReps)
+
+	query.Formula = strength.Brzycki
+	points, err = analyticsStore.Progression(query)
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.InDelta(t, 118.13, *points[0].EstimatedOneRepMax, 0.001)
+	assert.InDelta(t, 120.0, *points[1].EstimatedOneRepMax, 0.001)
+	assert.InDelta(t, 50*36/25.0, *points[2].EstimatedOneRepMax, 0.01)
+
+	query.Bucket = "month"
+	points, err = analyticsStore.Progression(query)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "2026-01-01", points[0].Bucket)
+	assert.Equal(t, 4, points[0].Workouts)
+}
+
+func TestProgressionWithoutEstimate(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	analyticsStore := store.NewPostgresAnalyticsStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	createWorkoutAt(t, workoutStore, alice.ID, time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC),
+		store.WorkoutEntry{ExerciseName: "Squat", Sets: 2, Reps: IntPtr(20), Weight: FloatPtr(60), OrderIndex: 1},
+	)
+
+	points, err := analyticsStore.Progression(store.ProgressionQuery{
+		UserID:       alice.ID,
+		ExerciseName: "Squat",
+		From:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		Timezone:     "UTC",
+		Bucket:       "week",
+		Formula:      strength.Epley,
+	})
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Nil(t, points[0].EstimatedOneRepMax)
+	assert.Equal(t, 60.0, points[0].TopSetWeight)
+	assert.Equal(t, 20, points[0].TopSetReps)
+}
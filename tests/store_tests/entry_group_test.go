@@ -0,0 +1,102 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func groupedEntry(orderIndex int, group *store.EntryGroup) store.WorkoutEntry {
+	return store.WorkoutEntry{ExerciseName: "Push Up", Sets: 3, OrderIndex: orderIndex, Group: group}
+}
+
+func TestValidateEntryGroups(t *testing.T) {
+	superset := &store.EntryGroup{ID: 1, Type: store.GroupSuperset, Rounds: IntPtr(3), RestSeconds: IntPtr(90)}
+	emom := &store.EntryGroup{ID: 2, Type: store.GroupEMOM, Rounds: IntPtr(10)}
+
+	tests := []struct {
+		name    string
+		entries []store.WorkoutEntry
+		wantErr bool
+	}{
+		{
+			name:    "ungrouped",
+			entries: []store.WorkoutEntry{groupedEntry(1, nil), groupedEntry(2, nil)},
+		},
+		{
+			name: "contiguous groups",
+			entries: []store.WorkoutEntry{
+				groupedEntry(1, nil),
+				groupedEntry(2, superset),
+				groupedEntry(3, superset),
+				groupedEntry(4, emom),
+			},
+		},
+		{
+			name: "contiguous once ordered",
+			entries: []store.WorkoutEntry{
+				groupedEntry(3, nil),
+				groupedEntry(2, superset),
+				groupedEntry(1, superset),
+			},
+		},
+		{
+			name: "split by an ungrouped entry",
+			entries: []store.WorkoutEntry{
+				groupedEntry(1, superset),
+				groupedEntry(2, nil),
+				groupedEntry(3, superset),
+			},
+			wantErr: true,
+		},
+		{
+			name: "split by another group",
+			entries: []store.WorkoutEntry{
+				groupedEntry(1, superset),
+				groupedEntry(2, emom),
+				groupedEntry(3, superset),
+			},
+			wantErr: true,
+		},
+		{
+			name: "different settings",
+			entries: []store.WorkoutEntry{
+				groupedEntry(1, superset),
+				groupedEntry(2, &store.EntryGroup{ID: 1, Type: store.GroupSuperset, Rounds: IntPtr(4), RestSeconds: IntPtr(90)}),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			entries: []store.WorkoutEntry{groupedEntry(1, &store.EntryGroup{ID: 1, Type: "tabata"})},
+			wantErr: true,
+		},
+		{
+			name:    "non-positive id",
+			entries: []store.WorkoutEntry{groupedEntry(1, &store.EntryGroup{Type: store.GroupCircuit})},
+			wantErr: true,
+		},
+		{
+			name:    "zero rounds",
+			entries: []store.WorkoutEntry{groupedEntry(1, &store.EntryGroup{ID: 1, Type: store.GroupAMRAP, Rounds: IntPtr(0)})},
+			wantErr: true,
+		},
+		{
+			name:    "negative rest",
+			entries: []store.WorkoutEntry{groupedEntry(1, &store.EntryGroup{ID: 1, Type: store.GroupCircuit, RestSeconds: IntPtr(-30)})},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.ValidateEntryGroups(tt.entries)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, store.ErrInvalidEntryGroup)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
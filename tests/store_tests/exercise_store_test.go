@@ -0,0 +1,177 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestExercise adds an exercise to the catalog, built in when ownerID
+// is nil. Truncating users empties the catalog, so tests create the
+// built-in exercises they need too.
+func createTestExercise(t *testing.T, exerciseStore store.ExerciseStore, ownerID *int, name string, aliases ...string) *store.Exercise {
+	t.Helper()
+
+	exercise := &store.Exercise{
+		OwnerID:          ownerID,
+		Name:             name,
+		Aliases:          append([]string{}, aliases...),
+		PrimaryMuscles:   []string{"chest"},
+		SecondaryMuscles: []string{},
+		Equipment:        "barbell",
+		MovementPattern:  "horizontal_push",
+	}
+	require.NoError(t, exerciseStore.CreateExercise(exercise))
+	return exercise
+}
+
+// entryExercises returns the exercise_id the store linked each entry of the
+// workout to, in order.
+func entryExercises(t *testing.T, workoutStore store.WorkoutStore, workoutID int64) []*int64 {
+	t.Helper()
+
+	entries, err := workoutStore.ListWorkoutEntries(workoutID)
+	require.NoError(t, err)
+
+	ids := make([]*int64, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ExerciseID
+	}
+	return ids
+}
+
+func TestResolveEntryExercisesByName(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bench := createTestExercise(t, exerciseStore, nil, "Bench Press", "BP", "Flat Bench")
+
+	workout := createTestWorkout(t, workoutStore, alice.ID,
+		namedEntry("bench-press", 1),
+		namedEntry("FLAT BENCH", 2),
+		namedEntry("bp", 3),
+		namedEntry("Bench", 4),
+	)
+
+	assert.Equal(t, []*int64{&bench.ID, &bench.ID, &bench.ID, nil}, entryExercises(t, workoutStore, int64(workout.ID)))
+}
+
+func TestResolveEntryExercisesPrefersCustomExercises(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	createTestExercise(t, exerciseStore, nil, "Bench Press")
+	custom := createTestExercise(t, exerciseStore, &alice.ID, "Bench Press")
+
+	workout := createTestWorkout(t, workoutStore, alice.ID, namedEntry("Bench Press", 1))
+
+	assert.Equal(t, []*int64{&custom.ID}, entryExercises(t, workoutStore, int64(workout.ID)))
+}
+
+func TestResolveEntryExercisesHidesOtherUsersExercises(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bob := createTestUser(t, DBConn, "bob")
+	zercher := createTestExercise(t, exerciseStore, &bob.ID, "Zercher Squat", "Zercher")
+
+	byName := namedEntry("Zercher", 1)
+	byID := namedEntry("Zercher Squat", 2)
+	byID.ExerciseID = &zercher.ID
+	workout := createTestWorkout(t, workoutStore, alice.ID, byName, byID)
+
+	assert.Equal(t, []*int64{nil, nil}, entryExercises(t, workoutStore, int64(workout.ID)))
+	assert.Equal(t, []string{"Zercher", "Zercher Squat"}, entryNames(t, workoutStore, int64(workout.ID)))
+
+	// bob sees his own exercise
+	workout = createTestWorkout(t, workoutStore, bob.ID, namedEntry("Zercher", 1))
+	assert.Equal(t, []*int64{&zercher.ID}, entryExercises(t, workoutStore, int64(workout.ID)))
+}
+
+func TestResolveEntryExercisesFallsBackToName(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bob := createTestUser(t, DBConn, "bob")
+	bench := createTestExercise(t, exerciseStore, nil, "Bench Press")
+	hidden := createTestExercise(t, exerciseStore, &bob.ID, "Paused Bench")
+
+	entry := namedEntry("Bench Press", 1)
+	entry.ExerciseID = &hidden.ID
+	workout := createTestWorkout(t, workoutStore, alice.ID, entry)
+
+	assert.Equal(t, []*int64{&bench.ID}, entryExercises(t, workoutStore, int64(workout.ID)))
+}
+
+func TestResolveEntryExercisesFillsMissingName(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bench := createTestExercise(t, exerciseStore, nil, "Bench Press")
+
+	entry := namedEntry("", 1)
+	entry.ExerciseID = &bench.ID
+	workout := createTestWorkout(t, workoutStore, alice.ID, entry)
+
+	assert.Equal(t, []*int64{&bench.ID}, entryExercises(t, workoutStore, int64(workout.ID)))
+	assert.Equal(t, []string{"Bench Press"}, entryNames(t, workoutStore, int64(workout.ID)))
+}
+
+func TestListExercises(t *testing.T) {
+	DBConn := setupTestDB(t)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bob := createTestUser(t, DBConn, "bob")
+	bench := createTestExercise(t, exerciseStore, nil, "Bench Press", "Flat Bench")
+	mine := createTestExercise(t, exerciseStore, &alice.ID, "Spoto Press")
+	createTestExercise(t, exerciseStore, &bob.ID, "Larsen Press")
+
+	exercises, err := exerciseStore.ListExercises(alice.ID, store.ExerciseFilter{Limit: 10})
+	require.NoError(t, err)
+	names := []string{}
+	for _, exercise := range exercises {
+		names = append(names, exercise.Name)
+	}
+	assert.Equal(t, []string{"Bench Press", "Spoto Press"}, names)
+
+	exercises, err = exerciseStore.ListExercises(alice.ID, store.ExerciseFilter{Search: "flat-bench", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, exercises, 1)
+	assert.Equal(t, bench.ID, exercises[0].ID)
+
+	exercise, err := exerciseStore.GetExercise(mine.ID, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Spoto Press", exercise.Name)
+
+	_, err = exerciseStore.GetExercise(mine.ID, bob.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateExerciseDuplicate(t *testing.T) {
+	DBConn := setupTestDB(t)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bob := createTestUser(t, DBConn, "bob")
+	createTestExercise(t, exerciseStore, &alice.ID, "Spoto Press")
+
+	err := exerciseStore.CreateExercise(&store.Exercise{OwnerID: &alice.ID, Name: "spoto-press", Aliases: []string{}, PrimaryMuscles: []string{"chest"}, SecondaryMuscles: []string{}, Equipment: "barbell", MovementPattern: "horizontal_push"})
+	assert.ErrorIs(t, err, store.ErrDuplicateExercise)
+
+	// custom names are only unique per user
+	createTestExercise(t, exerciseStore, &bob.ID, "Spoto Press")
+}
@@ -0,0 +1,127 @@
+package store_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testBackoff = store.LoginBackoff{
+	BaseDelay: time.Second,
+	MaxDelay:  4 * time.Second,
+	Window:    time.Hour,
+}
+
+func setupLoginAttemptStore(t *testing.T) (*store.PostgresLoginAttemptStore, func(key string, d time.Duration)) {
+	t.Helper()
+
+	DBConn := setupTestDB(t)
+	_, err := DBConn.Exec("TRUNCATE TABLE login_attempts, lockout_events RESTART IDENTITY")
+	require.NoError(t, err)
+
+	age := func(key string, d time.Duration) {
+		_, err := DBConn.Exec("UPDATE login_attempts SET last_failure_at = last_failure_at - make_interval(secs => $1) WHERE key = $2", d.Seconds(), key)
+		require.NoError(t, err)
+	}
+	return store.NewPostgresLoginAttemptStore(DBConn), age
+}
+
+func TestTakeLoginAttemptsBacksOff(t *testing.T) {
+	attemptStore, age := setupLoginAttemptStore(t)
+
+	// failures and the delay they lead to: 1s, 2s, 4s, then capped at 4s
+	for i, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		taken, err := attemptStore.TakeLoginAttempts([]string{"username:alice"}, testBackoff)
+		require.NoError(t, err)
+		require.Len(t, taken, 1)
+		assert.Equal(t, i+1, taken[0].Failures)
+
+		taken, err = attemptStore.TakeLoginAttempts([]string{"username:alice"}, testBackoff)
+		require.NoError(t, err)
+		assert.Empty(t, taken, "attempt %d should back off", i+1)
+
+		age("username:alice", delay-500*time.Millisecond)
+		taken, err = attemptStore.TakeLoginAttempts([]string{"username:alice"}, testBackoff)
+		require.NoError(t, err)
+		assert.Empty(t, taken, "attempt %d should back off for %s", i+1, delay)
+
+		age("username:alice", time.Second)
+	}
+}
+
+func TestTakeLoginAttemptsRestartsAfterWindow(t *testing.T) {
+	attemptStore, age := setupLoginAttemptStore(t)
+
+	for range 3 {
+		_, err := attemptStore.TakeLoginAttempts([]string{"ip:10.0.0.1"}, testBackoff)
+		require.NoError(t, err)
+		age("ip:10.0.0.1", time.Minute)
+	}
+
+	age("ip:10.0.0.1", 2*time.Hour)
+	taken, err := attemptStore.TakeLoginAttempts([]string{"ip:10.0.0.1"}, testBackoff)
+	require.NoError(t, err)
+	require.Len(t, taken, 1)
+	assert.Equal(t, 1, taken[0].Failures)
+}
+
+func TestTakeLoginAttemptsSkipsLockedKeys(t *testing.T) {
+	attemptStore, _ := setupLoginAttemptStore(t)
+
+	keys := []string{"username:alice", "ip:10.0.0.1"}
+	_, err := attemptStore.TakeLoginAttempts(keys, testBackoff)
+	require.NoError(t, err)
+	require.NoError(t, attemptStore.LockLogin("username:alice", time.Now().Add(time.Minute)))
+	require.NoError(t, attemptStore.ResetLoginAttempts("ip:10.0.0.1"))
+
+	taken, err := attemptStore.TakeLoginAttempts(keys, testBackoff)
+	require.NoError(t, err)
+	require.Len(t, taken, 1)
+	assert.Equal(t, "ip:10.0.0.1", taken[0].Key)
+}
+
+func TestTakeLoginAttemptsLetsOneConcurrentAttemptThrough(t *testing.T) {
+	attemptStore, _ := setupLoginAttemptStore(t)
+
+	_, err := attemptStore.TakeLoginAttempts([]string{"username:alice"}, testBackoff)
+	require.NoError(t, err)
+	require.NoError(t, attemptStore.ReleaseLoginAttempts("username:alice"))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			taken, err := attemptStore.TakeLoginAttempts([]string{"username:alice"}, testBackoff)
+			assert.NoError(t, err)
+			mu.Lock()
+			passed += len(taken)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, passed)
+}
+
+func TestReleaseLoginAttempts(t *testing.T) {
+	attemptStore, age := setupLoginAttemptStore(t)
+
+	for range 2 {
+		_, err := attemptStore.TakeLoginAttempts([]string{"ip:10.0.0.1"}, testBackoff)
+		require.NoError(t, err)
+		age("ip:10.0.0.1", time.Minute)
+	}
+	require.NoError(t, attemptStore.ReleaseLoginAttempts("ip:10.0.0.1"))
+
+	attempts, err := attemptStore.GetLoginAttempts("ip:10.0.0.1")
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, 1, attempts[0].Failures)
+}
@@ -0,0 +1,49 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/ratelimit"
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeFullBuckets(t *testing.T) {
+	DBConn := setupTestDB(t)
+	_, err := DBConn.Exec("TRUNCATE TABLE rate_limit_buckets")
+	require.NoError(t, err)
+
+	rateLimitStore := store.NewPostgresRateLimitStore(DBConn)
+	limit := ratelimit.Limit{Rate: 1, Burst: 2}
+
+	result, err := rateLimitStore.Take("idle", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	for range 2 {
+		_, err = rateLimitStore.Take("busy", limit)
+		require.NoError(t, err)
+	}
+
+	// idle refills one token within a second, busy needs two
+	purged, err := rateLimitStore.PurgeFullBuckets(time.Now().Add(1500 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var keys []string
+	rows, err := DBConn.Query("SELECT key FROM rate_limit_buckets")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		require.NoError(t, rows.Scan(&key))
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"busy"}, keys)
+
+	// a purged bucket starts out full again
+	result, err = rateLimitStore.Take("idle", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Remaining)
+}
@@ -0,0 +1,104 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const benchKey = "name:benchpress"
+
+// logBench stores a workout performed daysAgo with a single bench press at
+// weight, and its heaviest weight best. It returns the workout and its PRs.
+func logBench(t *testing.T, workoutStore store.WorkoutStore, recordStore store.RecordStore, userID int, daysAgo int, weight float64) (*store.Workout, []store.PersonalRecord) {
+	workout, err := workoutStore.CreateWorkout(&store.Workout{
+		UserID:      userID,
+		Title:       "Bench Day",
+		PerformedAt: time.Now().AddDate(0, 0, -daysAgo),
+		Timezone:    "UTC",
+		Entries: []store.WorkoutEntry{
+			{ExerciseName: "Bench Press", Sets: 1, Reps: IntPtr(1), Weight: FloatPtr(weight), OrderIndex: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	return workout, setBench(t, recordStore, workout, weight)
+}
+
+// setBench replaces the heaviest weight best of workout with weight.
+func setBench(t *testing.T, recordStore store.RecordStore, workout *store.Workout, weight float64) []store.PersonalRecord {
+	records, err := recordStore.ReplaceWorkoutRecords(workout.UserID, int64(workout.ID), workout.PerformedAt, []strength.Record{{
+		ExerciseKey:  benchKey,
+		ExerciseName: "Bench Press",
+		Type:         strength.RecordHeaviestWeight,
+		Value:        weight,
+		EntryID:      workout.Entries[0].ID,
+	}})
+	require.NoError(t, err)
+	return records
+}
+
+func heaviestBench(t *testing.T, recordStore store.RecordStore, userID int) float64 {
+	records, err := recordStore.ListRecords(userID)
+	require.NoError(t, err)
+	for _, record := range records {
+		if record.Type == strength.RecordHeaviestWeight {
+			return record.Value
+		}
+	}
+	t.Fatal("no heaviest weight record")
+	return 0
+}
+
+func benchHistory(t *testing.T, recordStore store.RecordStore, userID int) []float64 {
+	records, err := recordStore.ListRecordHistory(userID, benchKey)
+	require.NoError(t, err)
+
+	values := make([]float64, len(records))
+	for i, record := range records {
+		values[i] = record.Value
+	}
+	return values
+}
+
+func TestRecordsFollowWorkoutChanges(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	recordStore := store.NewPostgresRecordStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	first, records := logBench(t, workoutStore, recordStore, user.ID, 3, 100)
+	assert.Len(t, records, 1)
+	second, records := logBench(t, workoutStore, recordStore, user.ID, 2, 110)
+	assert.Len(t, records, 1)
+	_, records = logBench(t, workoutStore, recordStore, user.ID, 1, 105)
+	assert.Empty(t, records)
+
+	assert.Equal(t, 110.0, heaviestBench(t, recordStore, user.ID))
+	assert.Equal(t, []float64{100, 110}, benchHistory(t, recordStore, user.ID))
+
+	// the 110 was a typo: the 105 logged after it becomes a record
+	records = setBench(t, recordStore, second, 90)
+	assert.Empty(t, records)
+	assert.Equal(t, 105.0, heaviestBench(t, recordStore, user.ID))
+	assert.Equal(t, []float64{100, 105}, benchHistory(t, recordStore, user.ID))
+
+	err := workoutStore.DeleteWorkoutByID(int64(first.ID), first.Version)
+	require.NoError(t, err)
+	assert.Equal(t, 105.0, heaviestBench(t, recordStore, user.ID))
+	assert.Equal(t, []float64{90, 105}, benchHistory(t, recordStore, user.ID))
+
+	_, err = workoutStore.RestoreWorkoutByID(int64(first.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []float64{100, 105}, benchHistory(t, recordStore, user.ID))
+
+	// a back-dated workout beating everything supersedes the later records
+	_, records = logBench(t, workoutStore, recordStore, user.ID, 10, 120)
+	assert.Len(t, records, 1)
+	assert.Equal(t, 120.0, heaviestBench(t, recordStore, user.ID))
+	assert.Equal(t, []float64{120}, benchHistory(t, recordStore, user.ID))
+}
@@ -0,0 +1,166 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// templateEntries is a superset of bench press and rows, logged in pounds,
+// followed by a free-text entry.
+func templateEntries() []store.WorkoutEntry {
+	superset := &store.EntryGroup{ID: 1, Type: store.GroupSuperset, Rounds: IntPtr(3), RestSeconds: IntPtr(90)}
+	return []store.WorkoutEntry{
+		{ExerciseName: "bench-press", Sets: 3, Reps: IntPtr(8), Weight: FloatPtr(102.058), WeightUnit: units.Pounds, OrderIndex: 1, Group: superset},
+		{ExerciseName: "Row", Sets: 3, Reps: IntPtr(10), Weight: FloatPtr(60), WeightUnit: units.Kilograms, OrderIndex: 2, Group: superset},
+		{ExerciseName: "Farmer Walk", Sets: 2, DurationSeconds: IntPtr(45), OrderIndex: 3},
+	}
+}
+
+// assertSameEntries checks that got has the exercise, prescription, unit
+// and group of every entry of want, in order. Entries written without a
+// unit are stored in kilograms.
+func assertSameEntries(t *testing.T, want, got []store.WorkoutEntry) {
+	t.Helper()
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].ExerciseID, got[i].ExerciseID, "entry %d", i)
+		assert.Equal(t, want[i].ExerciseName, got[i].ExerciseName, "entry %d", i)
+		assert.Equal(t, want[i].Sets, got[i].Sets, "entry %d", i)
+		assert.Equal(t, want[i].Reps, got[i].Reps, "entry %d", i)
+		assert.Equal(t, want[i].DurationSeconds, got[i].DurationSeconds, "entry %d", i)
+		assert.Equal(t, want[i].Weight, got[i].Weight, "entry %d", i)
+		wantUnit := want[i].WeightUnit
+		if wantUnit == "" {
+			wantUnit = units.Kilograms
+		}
+		assert.Equal(t, wantUnit, got[i].WeightUnit, "entry %d", i)
+		assert.Equal(t, want[i].OrderIndex, got[i].OrderIndex, "entry %d", i)
+		assert.Equal(t, want[i].Group, got[i].Group, "entry %d", i)
+	}
+}
+
+func TestTemplateCRUD(t *testing.T) {
+	DBConn := setupTestDB(t)
+	templateStore := store.NewPostgresTemplateStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	bench := createTestExercise(t, exerciseStore, nil, "Bench Press")
+
+	template := &store.WorkoutTemplate{UserID: alice.ID, Title: "Push", Description: "Upper body", Entries: templateEntries()}
+	require.NoError(t, templateStore.CreateTemplate(template))
+	require.NotZero(t, template.ID)
+	for _, entry := range template.Entries {
+		assert.NotZero(t, entry.ID)
+	}
+
+	stored, err := templateStore.GetTemplateByID(template.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Push", stored.Title)
+	assert.Equal(t, "Upper body", stored.Description)
+	require.Len(t, stored.Entries, 3)
+	// linked to the catalog, the name is kept as written
+	assert.Equal(t, &bench.ID, stored.Entries[0].ExerciseID)
+	assert.Nil(t, stored.Entries[1].ExerciseID)
+	assert.Equal(t, units.Kilograms, stored.Entries[2].WeightUnit)
+	assertSameEntries(t, template.Entries, stored.Entries)
+
+	templates, err := templateStore.ListTemplates(alice.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, template.ID, templates[0].ID)
+	assert.Empty(t, templates[0].Entries)
+
+	stored.Title = "Push B"
+	stored.Entries = stored.Entries[1:]
+	stored.Entries[0].Group = nil
+	stored.Entries[0].OrderIndex = 1
+	stored.Entries[1].OrderIndex = 2
+	require.NoError(t, templateStore.UpdateTemplate(stored))
+
+	updated, err := templateStore.GetTemplateByID(template.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Push B", updated.Title)
+	assertSameEntries(t, stored.Entries, updated.Entries)
+
+	require.NoError(t, templateStore.DeleteTemplate(template.ID))
+	_, err = templateStore.GetTemplateByID(template.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, templateStore.DeleteTemplate(template.ID), sql.ErrNoRows)
+}
+
+func TestWorkoutFromTemplate(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	templateStore := store.NewPostgresTemplateStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	createTestExercise(t, exerciseStore, nil, "Bench Press")
+
+	template := &store.WorkoutTemplate{UserID: alice.ID, Title: "Push", Description: "Upper body", Entries: templateEntries()}
+	require.NoError(t, templateStore.CreateTemplate(template))
+	stored, err := templateStore.GetTemplateByID(template.ID)
+	require.NoError(t, err)
+
+	workout := stored.NewWorkout(alice.ID)
+	workout.PerformedAt = time.Now()
+	workout.Timezone = "UTC"
+	workout, err = workoutStore.CreateWorkout(workout)
+	require.NoError(t, err)
+
+	performed, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Push", performed.Title)
+	assert.Equal(t, "Upper body", performed.Description)
+	assertSameEntries(t, stored.Entries, performed.Entries)
+
+	// the workout got entries of its own
+	for i := range performed.Entries {
+		assert.NotEqual(t, stored.Entries[i].ID, performed.Entries[i].ID)
+	}
+}
+
+func TestSaveWorkoutAsTemplate(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	templateStore := store.NewPostgresTemplateStore(DBConn)
+	exerciseStore := store.NewPostgresExerciseStore(DBConn)
+
+	alice := createTestUser(t, DBConn, "alice")
+	createTestExercise(t, exerciseStore, nil, "Bench Press")
+
+	entries := templateEntries()
+	entries[0].Sets, entries[0].Reps, entries[0].Weight = 0, nil, nil
+	entries[0].SetDetails = []store.WorkoutSet{
+		loggedSet(store.SetWarmUp, 10, 20.412),
+		loggedSet(store.SetWorking, 8, 102.058),
+		loggedSet(store.SetWorking, 6, 106.594),
+	}
+	workout := createTestWorkout(t, workoutStore, alice.ID, entries...)
+	performed, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+
+	template := store.NewTemplateFromWorkout(performed, alice.ID)
+	require.NoError(t, templateStore.CreateTemplate(template))
+
+	stored, err := templateStore.GetTemplateByID(template.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Workout", stored.Title)
+	assertSameEntries(t, performed.Entries, stored.Entries)
+
+	// templates keep the summary of a set log, not the log itself
+	assert.Empty(t, stored.Entries[0].SetDetails)
+	assert.Equal(t, 2, stored.Entries[0].Sets)
+	assert.Equal(t, IntPtr(6), stored.Entries[0].Reps)
+	assert.Equal(t, FloatPtr(106.594), stored.Entries[0].Weight)
+	assert.Equal(t, units.Pounds, stored.Entries[0].WeightUnit)
+	require.NotNil(t, stored.Entries[0].ExerciseID)
+}
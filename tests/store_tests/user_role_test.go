@@ -0,0 +1,16 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidRole(t *testing.T) {
+	assert.True(t, store.IsValidRole(store.RoleUser))
+	assert.True(t, store.IsValidRole(store.RoleAdmin))
+	assert.False(t, store.IsValidRole(store.RoleCoach), "coaches can't be linked to athletes yet")
+	assert.False(t, store.IsValidRole(""))
+	assert.False(t, store.IsValidRole("root"))
+}
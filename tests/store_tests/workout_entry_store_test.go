@@ -0,0 +1,131 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func namedEntry(name string, orderIndex int) store.WorkoutEntry {
+	return store.WorkoutEntry{ExerciseName: name, Sets: 3, Reps: IntPtr(10), OrderIndex: orderIndex}
+}
+
+// entryNames returns the names of the workout's entries in order, checking
+// that order_index is contiguous from 1.
+func entryNames(t *testing.T, workoutStore store.WorkoutStore, workoutID int64) []string {
+	entries, err := workoutStore.ListWorkoutEntries(workoutID)
+	require.NoError(t, err)
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.OrderIndex)
+		names[i] = entry.ExerciseName
+	}
+	return names
+}
+
+func TestWorkoutEntryOrder(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "entry_order")
+	workout := createTestWorkout(t, workoutStore, user.ID, namedEntry("A", 1), namedEntry("B", 2), namedEntry("C", 3))
+	workoutID := int64(workout.ID)
+	version := workout.Version
+
+	inserted := namedEntry("D", 2)
+	newVersion, err := workoutStore.CreateWorkoutEntry(workoutID, version, &inserted, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, version+1, newVersion)
+	assert.Equal(t, 2, inserted.OrderIndex)
+	assert.Equal(t, []string{"A", "D", "B", "C"}, entryNames(t, workoutStore, workoutID))
+	version = newVersion
+
+	appended := namedEntry("E", 0)
+	version, err = workoutStore.CreateWorkoutEntry(workoutID, version, &appended, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, appended.OrderIndex)
+	assert.Equal(t, []string{"A", "D", "B", "C", "E"}, entryNames(t, workoutStore, workoutID))
+
+	moved := namedEntry("A", 4)
+	moved.ID = workout.Entries[0].ID
+	version, err = workoutStore.UpdateWorkoutEntry(workoutID, version, &moved, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"D", "B", "C", "A", "E"}, entryNames(t, workoutStore, workoutID))
+
+	version, err = workoutStore.DeleteWorkoutEntry(workoutID, version, int64(workout.Entries[1].ID), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"D", "C", "A", "E"}, entryNames(t, workoutStore, workoutID))
+
+	entryIDs := []int64{int64(appended.ID), int64(workout.Entries[0].ID), int64(workout.Entries[2].ID), int64(inserted.ID)}
+	version, err = workoutStore.ReorderWorkoutEntries(workoutID, version, entryIDs, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"E", "A", "C", "D"}, entryNames(t, workoutStore, workoutID))
+
+	_, err = workoutStore.ReorderWorkoutEntries(workoutID, version, entryIDs[1:], user.ID)
+	assert.ErrorIs(t, err, store.ErrInvalidEntryOrder)
+
+	current, err := workoutStore.GetWorkoutByID(workoutID)
+	require.NoError(t, err)
+	assert.Equal(t, version, current.Version)
+}
+
+func TestWorkoutEntryVersionConflict(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "entry_conflict")
+	workout := createTestWorkout(t, workoutStore, user.ID, namedEntry("A", 1), namedEntry("B", 2))
+	workoutID := int64(workout.ID)
+	stale := workout.Version
+
+	created := namedEntry("C", 0)
+	_, err := workoutStore.CreateWorkoutEntry(workoutID, stale, &created, user.ID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		write func() error
+	}{
+		{
+			name: "create",
+			write: func() error {
+				entry := namedEntry("D", 0)
+				_, err := workoutStore.CreateWorkoutEntry(workoutID, stale, &entry, user.ID)
+				return err
+			},
+		},
+		{
+			name: "update",
+			write: func() error {
+				entry := namedEntry("Renamed", 0)
+				entry.ID = workout.Entries[0].ID
+				_, err := workoutStore.UpdateWorkoutEntry(workoutID, stale, &entry, user.ID)
+				return err
+			},
+		},
+		{
+			name: "delete",
+			write: func() error {
+				_, err := workoutStore.DeleteWorkoutEntry(workoutID, stale, int64(workout.Entries[1].ID), user.ID)
+				return err
+			},
+		},
+		{
+			name: "reorder",
+			write: func() error {
+				entryIDs := []int64{int64(created.ID), int64(workout.Entries[1].ID), int64(workout.Entries[0].ID)}
+				_, err := workoutStore.ReorderWorkoutEntries(workoutID, stale, entryIDs, user.ID)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.write()
+			assert.ErrorIs(t, err, store.ErrVersionConflict)
+			assert.Equal(t, []string{"A", "B", "C"}, entryNames(t, workoutStore, workoutID))
+		})
+	}
+}
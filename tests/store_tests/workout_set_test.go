@@ -0,0 +1,80 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func BoolPtr(b bool) *bool {
+	return &b
+}
+
+func TestSummarizeSets(t *testing.T) {
+	entry := store.WorkoutEntry{
+		ExerciseName: "Bench Press",
+		SetDetails: []store.WorkoutSet{
+			{Type: store.SetWarmUp, Reps: IntPtr(10), Weight: FloatPtr(40)},
+			{Reps: IntPtr(10), Weight: FloatPtr(60)},
+			{Reps: IntPtr(8), Weight: FloatPtr(70), RPE: FloatPtr(8.5)},
+			{Reps: IntPtr(6), Weight: FloatPtr(80), RIR: IntPtr(1)},
+			{Reps: IntPtr(6), Weight: FloatPtr(85), Completed: BoolPtr(false)},
+		},
+	}
+
+	require.NoError(t, store.SummarizeSets(&entry))
+	assert.Equal(t, 3, entry.Sets)
+	assert.Equal(t, 6, *entry.Reps)
+	assert.Equal(t, 80.0, *entry.Weight)
+	assert.Nil(t, entry.DurationSeconds)
+
+	// omitted types and completed flags are filled in
+	assert.Equal(t, store.SetWorking, entry.SetDetails[1].Type)
+	assert.True(t, *entry.SetDetails[1].Completed)
+}
+
+func TestSummarizeSetsPlanned(t *testing.T) {
+	entry := store.WorkoutEntry{
+		ExerciseName: "Plank",
+		SetDetails: []store.WorkoutSet{
+			{DurationSeconds: IntPtr(45), Completed: BoolPtr(false)},
+			{DurationSeconds: IntPtr(60), Completed: BoolPtr(false)},
+		},
+	}
+
+	require.NoError(t, store.SummarizeSets(&entry))
+	assert.Equal(t, 2, entry.Sets)
+	assert.Equal(t, 60, *entry.DurationSeconds)
+	assert.Nil(t, entry.Reps)
+}
+
+func TestSummarizeSetsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		sets []store.WorkoutSet
+	}{
+		{name: "unknown type", sets: []store.WorkoutSet{{Type: "cluster", Reps: IntPtr(5)}}},
+		{name: "reps and duration", sets: []store.WorkoutSet{{Reps: IntPtr(5), DurationSeconds: IntPtr(30)}}},
+		{name: "neither reps nor duration", sets: []store.WorkoutSet{{Weight: FloatPtr(50)}}},
+		{name: "mixed kinds", sets: []store.WorkoutSet{{Reps: IntPtr(5)}, {DurationSeconds: IntPtr(30)}}},
+		{name: "negative weight", sets: []store.WorkoutSet{{Reps: IntPtr(5), Weight: FloatPtr(-5)}}},
+		{name: "rpe out of range", sets: []store.WorkoutSet{{Reps: IntPtr(5), RPE: FloatPtr(11)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := store.WorkoutEntry{ExerciseName: "Row", SetDetails: tt.sets}
+			assert.ErrorIs(t, store.SummarizeSets(&entry), store.ErrInvalidSet)
+		})
+	}
+}
+
+func TestSummarizeSetsWithoutLog(t *testing.T) {
+	entry := store.WorkoutEntry{ExerciseName: "Row", Sets: 3, Reps: IntPtr(12), Weight: FloatPtr(50)}
+
+	require.NoError(t, store.SummarizeSets(&entry))
+	assert.Equal(t, 3, entry.Sets)
+	assert.Equal(t, 12, *entry.Reps)
+}
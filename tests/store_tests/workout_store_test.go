@@ -2,15 +2,18 @@ package store_test
 
 import (
 	"database/sql"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/gbuenodev/goProject/internal/units"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupTestDB(t *testing.T) *sql.DB {
+func setupTestDB(t testing.TB) *sql.DB {
 	dbConfig := store.DBConfig{
 		Provider: "Postgres",
 		Driver:   "pgx",
@@ -40,6 +43,30 @@ func setupTestDB(t *testing.T) *sql.DB {
 	return DBConn
 }
 
+// createTestUser stores a user the tests can own workouts with.
+func createTestUser(t testing.TB, DBConn *sql.DB, username string) *store.User {
+	user := &store.User{Username: username, Email: username + "@email.com"}
+	err := user.PasswordHash.Set("Sup3rSecr3tPass#!")
+	require.NoError(t, err)
+
+	err = store.NewPostgresUserStore(DBConn).CreateUser(user)
+	require.NoError(t, err)
+	return user
+}
+
+// createTestWorkout stores a workout of userID with entries.
+func createTestWorkout(t testing.TB, workoutStore store.WorkoutStore, userID int, entries ...store.WorkoutEntry) *store.Workout {
+	workout, err := workoutStore.CreateWorkout(&store.Workout{
+		UserID:      userID,
+		Title:       "Test Workout",
+		PerformedAt: time.Now(),
+		Timezone:    "UTC",
+		Entries:     entries,
+	})
+	require.NoError(t, err)
+	return workout
+}
+
 func TestCreateWorkout(t *testing.T) {
 	DBConn := setupTestDB(t)
 
@@ -131,6 +158,7 @@ func TestCreateWorkout(t *testing.T) {
 			assert.Equal(t, tt.workout.CaloriesBurned, createdWorkout.CaloriesBurned)
 			assert.Equal(t, len(tt.workout.Entries), len(createdWorkout.Entries))
 			for i, entry := range tt.workout.Entries {
+				assert.NotZero(t, createdWorkout.Entries[i].ID)
 				assert.Equal(t, entry.ExerciseName, createdWorkout.Entries[i].ExerciseName)
 				assert.Equal(t, entry.Reps, createdWorkout.Entries[i].Reps)
 				assert.Equal(t, entry.Sets, createdWorkout.Entries[i].Sets)
@@ -149,6 +177,7 @@ func TestCreateWorkout(t *testing.T) {
 			assert.Equal(t, createdWorkout.CaloriesBurned, retrievedWorkout.CaloriesBurned)
 			assert.Equal(t, len(createdWorkout.Entries), len(retrievedWorkout.Entries))
 			for i, entry := range createdWorkout.Entries {
+				assert.Equal(t, entry.ID, retrievedWorkout.Entries[i].ID)
 				assert.Equal(t, entry.ExerciseName, retrievedWorkout.Entries[i].ExerciseName)
 				assert.Equal(t, entry.Reps, retrievedWorkout.Entries[i].Reps)
 				assert.Equal(t, entry.Sets, retrievedWorkout.Entries[i].Sets)
@@ -160,6 +189,265 @@ func TestCreateWorkout(t *testing.T) {
 	}
 }
 
+func TestWeightUnits(t *testing.T) {
+	DBConn := setupTestDB(t)
+
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	userStore := store.NewPostgresUserStore(DBConn)
+
+	testUser := &store.User{Username: "Test_User", Email: "test@email.com"}
+	err := testUser.PasswordHash.Set("Sup3rSecr3tPass#!")
+	require.NoError(t, err)
+	err = userStore.CreateUser(testUser)
+	require.NoError(t, err)
+	assert.Equal(t, units.Kilograms, testUser.PreferredUnit)
+
+	createdWorkout, err := workoutStore.CreateWorkout(&store.Workout{
+		UserID: testUser.ID,
+		Title:  "Heavy Day",
+		Entries: []store.WorkoutEntry{
+			// past the 999.99 the column used to hold
+			{ExerciseName: "Leg Press", Sets: 1, Reps: IntPtr(5), Weight: FloatPtr(1200), OrderIndex: 1},
+			{ExerciseName: "Squat", Sets: 3, Reps: IntPtr(5), Weight: FloatPtr(102.058), WeightUnit: units.Pounds, OrderIndex: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	retrievedWorkout, err := workoutStore.GetWorkoutByID(int64(createdWorkout.ID))
+	require.NoError(t, err)
+	require.Len(t, retrievedWorkout.Entries, 2)
+	assert.Equal(t, 1200.0, *retrievedWorkout.Entries[0].Weight)
+	assert.Equal(t, units.Kilograms, retrievedWorkout.Entries[0].WeightUnit)
+	assert.Equal(t, 102.058, *retrievedWorkout.Entries[1].Weight)
+	assert.Equal(t, units.Pounds, retrievedWorkout.Entries[1].WeightUnit)
+}
+
+func TestUpdateWorkoutVersionConflict(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	workout := createTestWorkout(t, workoutStore, user.ID, store.WorkoutEntry{ExerciseName: "Squat", Sets: 3, Reps: IntPtr(5), OrderIndex: 1})
+	assert.Equal(t, 1, workout.Version)
+
+	stale, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+
+	workout.Title = "Edited on the phone"
+	err = workoutStore.UpdateWorkoutByID(workout, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, workout.Version)
+
+	stale.Title = "Edited on the laptop"
+	err = workoutStore.UpdateWorkoutByID(stale, user.ID)
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+
+	err = workoutStore.DeleteWorkoutByID(int64(workout.ID), stale.Version)
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+
+	stored, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Edited on the phone", stored.Title)
+	assert.Equal(t, 2, stored.Version)
+
+	missing := *stored
+	missing.ID = stored.ID + 1000
+	err = workoutStore.UpdateWorkoutByID(&missing, user.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateWorkoutSyncsEntries(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	workout := createTestWorkout(t, workoutStore, user.ID,
+		store.WorkoutEntry{ExerciseName: "Squat", Sets: 3, Reps: IntPtr(5), OrderIndex: 1},
+		store.WorkoutEntry{ExerciseName: "Bench Press", Sets: 3, Reps: IntPtr(5), OrderIndex: 2},
+		store.WorkoutEntry{ExerciseName: "Deadlift", Sets: 1, Reps: IntPtr(5), OrderIndex: 3},
+	)
+	kept, edited, dropped := workout.Entries[0], workout.Entries[1], workout.Entries[2]
+
+	edited.Reps = IntPtr(8)
+	workout.Entries = []store.WorkoutEntry{
+		kept,
+		edited,
+		{ExerciseName: "Pull Up", Sets: 3, Reps: IntPtr(10), OrderIndex: 3},
+	}
+	err := workoutStore.UpdateWorkoutByID(workout, user.ID)
+	require.NoError(t, err)
+	added := workout.Entries[2]
+	require.NotZero(t, added.ID)
+	assert.NotContains(t, []int{kept.ID, edited.ID, dropped.ID}, added.ID)
+
+	stored, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	require.Len(t, stored.Entries, 3)
+	assert.Equal(t, kept.ID, stored.Entries[0].ID)
+	assert.Equal(t, 5, *stored.Entries[0].Reps)
+	assert.Equal(t, edited.ID, stored.Entries[1].ID)
+	assert.Equal(t, 8, *stored.Entries[1].Reps)
+	assert.Equal(t, added.ID, stored.Entries[2].ID)
+	assert.Equal(t, "Pull Up", stored.Entries[2].ExerciseName)
+
+	_, err = workoutStore.GetWorkoutEntry(int64(workout.ID), int64(dropped.ID))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateWorkoutEntryIDs(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	// listed out of order_index order, IDs must still follow the entries
+	workout := createTestWorkout(t, workoutStore, user.ID,
+		store.WorkoutEntry{ExerciseName: "Deadlift", Sets: 1, Reps: IntPtr(5), OrderIndex: 3},
+		store.WorkoutEntry{ExerciseName: "Squat", Sets: 3, Reps: IntPtr(5), OrderIndex: 1},
+		store.WorkoutEntry{ExerciseName: "Bench Press", Sets: 3, Reps: IntPtr(5), OrderIndex: 2},
+	)
+
+	for _, entry := range workout.Entries {
+		stored, err := workoutStore.GetWorkoutEntry(int64(workout.ID), int64(entry.ID))
+		require.NoError(t, err)
+		assert.Equal(t, entry.ExerciseName, stored.ExerciseName)
+		assert.Equal(t, entry.OrderIndex, stored.OrderIndex)
+	}
+}
+
+func TestUpdateWorkoutInvalidEntryID(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	other := createTestWorkout(t, workoutStore, user.ID, store.WorkoutEntry{ExerciseName: "Row", Sets: 3, Reps: IntPtr(10), OrderIndex: 1})
+	workout := createTestWorkout(t, workoutStore, user.ID, store.WorkoutEntry{ExerciseName: "Squat", Sets: 3, Reps: IntPtr(5), OrderIndex: 1})
+	entry := workout.Entries[0]
+
+	tests := []struct {
+		name    string
+		entries []store.WorkoutEntry
+	}{
+		{
+			name:    "entry of another workout",
+			entries: []store.WorkoutEntry{entry, other.Entries[0]},
+		},
+		{
+			name:    "duplicate entry",
+			entries: []store.WorkoutEntry{entry, entry},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := *workout
+			update.Entries = tt.entries
+			err := workoutStore.UpdateWorkoutByID(&update, user.ID)
+			assert.ErrorIs(t, err, store.ErrInvalidEntryID)
+
+			stored, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+			require.NoError(t, err)
+			assert.Equal(t, workout.Version, stored.Version)
+			require.Len(t, stored.Entries, 1)
+			assert.Equal(t, entry.ID, stored.Entries[0].ID)
+		})
+	}
+
+	stored, err := workoutStore.GetWorkoutByID(int64(other.ID))
+	require.NoError(t, err)
+	assert.Equal(t, other.Entries[0].ID, stored.Entries[0].ID)
+}
+
+func TestUpdateWorkoutKeepsSetLog(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	workout := createTestWorkout(t, workoutStore, user.ID, store.WorkoutEntry{
+		ExerciseName: "Squat",
+		OrderIndex:   1,
+		SetDetails: []store.WorkoutSet{
+			{Reps: IntPtr(5), Weight: FloatPtr(100)},
+			{Reps: IntPtr(5), Weight: FloatPtr(110)},
+		},
+	})
+
+	// a client that doesn't read the set log sends the entry without it
+	entry := workout.Entries[0]
+	entry.SetDetails = nil
+	entry.Notes = "felt heavy"
+	workout.Entries = []store.WorkoutEntry{entry}
+	err := workoutStore.UpdateWorkoutByID(workout, user.ID)
+	require.NoError(t, err)
+
+	stored, err := workoutStore.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	require.Len(t, stored.Entries, 1)
+	assert.Equal(t, "felt heavy", stored.Entries[0].Notes)
+	require.Len(t, stored.Entries[0].SetDetails, 2)
+	assert.Equal(t, 110.0, *stored.Entries[0].SetDetails[1].Weight)
+	assert.Equal(t, 2, stored.Entries[0].Sets)
+	assert.Equal(t, 110.0, *stored.Entries[0].Weight)
+
+	// an empty set log removes it
+	entry = stored.Entries[0]
+	entry.SetDetails = []store.WorkoutSet{}
+	entry.Sets = 1
+	entry.Weight = FloatPtr(120)
+	stored.Entries = []store.WorkoutEntry{entry}
+	err = workoutStore.UpdateWorkoutByID(stored, user.ID)
+	require.NoError(t, err)
+
+	cleared, err := workoutStore.GetWorkoutEntry(int64(workout.ID), int64(entry.ID))
+	require.NoError(t, err)
+	assert.Empty(t, cleared.SetDetails)
+	assert.Equal(t, 120.0, *cleared.Weight)
+}
+
+func BenchmarkCreateWorkout(b *testing.B) {
+	DBConn := setupTestDB(b)
+
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	userStore := store.NewPostgresUserStore(DBConn)
+
+	testUser := &store.User{
+		Username: "Bench_User",
+		Email:    "bench@email.com",
+	}
+
+	err := testUser.PasswordHash.Set("Sup3rSecr3tPass#!")
+	require.NoError(b, err)
+
+	err = userStore.CreateUser(testUser)
+	require.NoError(b, err)
+
+	for _, size := range []int{1, 10, 50, 200} {
+		b.Run(fmt.Sprintf("entries=%d", size), func(b *testing.B) {
+			for b.Loop() {
+				workout := &store.Workout{
+					UserID:          testUser.ID,
+					Title:           "Benchmark",
+					DurationMinutes: 60,
+					Entries:         make([]store.WorkoutEntry, size),
+				}
+				for i := range workout.Entries {
+					workout.Entries[i] = store.WorkoutEntry{
+						ExerciseName: "Bench Press",
+						Sets:         3,
+						Reps:         IntPtr(10),
+						Weight:       FloatPtr(80),
+						OrderIndex:   i + 1,
+					}
+				}
+
+				_, err := workoutStore.CreateWorkout(workout)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func IntPtr(i int) *int {
 	return &i
 }
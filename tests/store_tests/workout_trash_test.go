@@ -0,0 +1,152 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gbuenodev/goProject/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trashTestWorkout(t *testing.T, workoutStore store.WorkoutStore, workout *store.Workout) int {
+	err := workoutStore.DeleteWorkoutByID(int64(workout.ID), workout.Version)
+	require.NoError(t, err)
+
+	trashed, err := workoutStore.ListDeletedWorkouts(workout.UserID, 10, 0)
+	require.NoError(t, err)
+	for _, w := range trashed {
+		if w.ID == workout.ID {
+			require.NotNil(t, w.DeletedAt)
+			return w.Version
+		}
+	}
+	t.Fatalf("workout %d is not in the trash", workout.ID)
+	return 0
+}
+
+func TestTrashedWorkoutsAreHidden(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	workout := createTestWorkout(t, workoutStore, user.ID, namedEntry("A", 1))
+	workoutID := int64(workout.ID)
+	version := trashTestWorkout(t, workoutStore, workout)
+	assert.Equal(t, workout.Version+1, version)
+
+	_, err := workoutStore.GetWorkoutByID(workoutID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = workoutStore.GetWorkoutOwner(workoutID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = workoutStore.GetWorkoutEntry(workoutID, int64(workout.Entries[0].ID))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	entries, err := workoutStore.ListWorkoutEntries(workoutID)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	workouts, err := workoutStore.ListWorkouts(user.ID, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, workouts)
+
+	workout.Version = version
+	err = workoutStore.UpdateWorkoutByID(workout, user.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	err = workoutStore.DeleteWorkoutByID(workoutID, version)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	entry := namedEntry("B", 0)
+	_, err = workoutStore.CreateWorkoutEntry(workoutID, version, &entry, user.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	owner, err := workoutStore.GetDeletedWorkoutOwner(workoutID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, owner)
+}
+
+func TestRestoreWorkout(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	workout := createTestWorkout(t, workoutStore, user.ID, namedEntry("A", 1))
+	workoutID := int64(workout.ID)
+	version := trashTestWorkout(t, workoutStore, workout)
+
+	restoredVersion, err := workoutStore.RestoreWorkoutByID(workoutID)
+	require.NoError(t, err)
+	assert.Equal(t, version+1, restoredVersion)
+
+	restored, err := workoutStore.GetWorkoutByID(workoutID)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, restoredVersion, restored.Version)
+	require.Len(t, restored.Entries, 1)
+	assert.Equal(t, workout.Entries[0].ID, restored.Entries[0].ID)
+
+	_, err = workoutStore.RestoreWorkoutByID(workoutID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = workoutStore.GetDeletedWorkoutOwner(workoutID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	trashed, err := workoutStore.ListDeletedWorkouts(user.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestPurgeWorkout(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	workout := createTestWorkout(t, workoutStore, user.ID, namedEntry("A", 1))
+	workoutID := int64(workout.ID)
+	version := trashTestWorkout(t, workoutStore, workout)
+
+	err := workoutStore.PurgeWorkoutByID(workoutID, workout.Version)
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+
+	err = workoutStore.PurgeWorkoutByID(workoutID, version)
+	require.NoError(t, err)
+
+	err = workoutStore.PurgeWorkoutByID(workoutID, version)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = workoutStore.GetDeletedWorkoutOwner(workoutID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	var entries int
+	err = DBConn.QueryRow(`SELECT COUNT(*) FROM workout_entries WHERE workout_id = $1`, workoutID).Scan(&entries)
+	require.NoError(t, err)
+	assert.Zero(t, entries)
+
+	live := createTestWorkout(t, workoutStore, user.ID, namedEntry("A", 1))
+	err = workoutStore.PurgeWorkoutByID(int64(live.ID), live.Version+1)
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+	err = workoutStore.PurgeWorkoutByID(int64(live.ID), live.Version)
+	require.NoError(t, err)
+}
+
+func TestPurgeDeletedWorkouts(t *testing.T) {
+	DBConn := setupTestDB(t)
+	workoutStore := store.NewPostgresWorkoutStore(DBConn)
+	user := createTestUser(t, DBConn, "Test_User")
+
+	trashed := createTestWorkout(t, workoutStore, user.ID)
+	live := createTestWorkout(t, workoutStore, user.ID)
+	trashTestWorkout(t, workoutStore, trashed)
+
+	purged, err := workoutStore.PurgeDeletedWorkouts(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = workoutStore.PurgeDeletedWorkouts(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = workoutStore.GetDeletedWorkoutOwner(int64(trashed.ID))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = workoutStore.GetWorkoutByID(int64(live.ID))
+	assert.NoError(t, err)
+}
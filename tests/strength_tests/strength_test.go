@@ -0,0 +1,102 @@
+package strength_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/strength"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestEstimateOneRepMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula strength.Formula
+		weight  float64
+		reps    int
+		want    float64
+		wantOK  bool
+	}{
+		{name: "single", formula: strength.Epley, weight: 100, reps: 1, want: 100, wantOK: true},
+		{name: "epley", formula: strength.Epley, weight: 100, reps: 6, want: 120, wantOK: true},
+		{name: "brzycki", formula: strength.Brzycki, weight: 100, reps: 10, want: 100 * 36.0 / 27, wantOK: true},
+		{name: "too many reps", formula: strength.Epley, weight: 100, reps: 13},
+		{name: "no weight", formula: strength.Epley, weight: 0, reps: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := strength.EstimateOneRepMax(tt.formula, tt.weight, tt.reps)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.InDelta(t, tt.want, got, 0.001)
+		})
+	}
+}
+
+func TestParseFormula(t *testing.T) {
+	formula, err := strength.ParseFormula("")
+	require.NoError(t, err)
+	assert.Equal(t, strength.Epley, formula)
+
+	formula, err = strength.ParseFormula("Brzycki")
+	require.NoError(t, err)
+	assert.Equal(t, strength.Brzycki, formula)
+
+	_, err = strength.ParseFormula("lombardi")
+	assert.Error(t, err)
+}
+
+func TestWorkoutRecords(t *testing.T) {
+	records := strength.WorkoutRecords([]strength.Performance{
+		{EntryID: 1, ExerciseName: "Bench Press", Sets: 3, Reps: intPtr(10), Weight: floatPtr(80)},
+		{EntryID: 2, ExerciseName: "bench press", Sets: 1, Reps: intPtr(3), Weight: floatPtr(100)},
+		{EntryID: 3, ExerciseName: "Plank", Sets: 1, DurationSeconds: intPtr(90)},
+	})
+
+	byType := map[strength.RecordType][]strength.Record{}
+	for _, record := range records {
+		byType[record.Type] = append(byType[record.Type], record)
+	}
+
+	require.Len(t, byType[strength.RecordHeaviestWeight], 1)
+	assert.Equal(t, 100.0, byType[strength.RecordHeaviestWeight][0].Value)
+	assert.Equal(t, 2, byType[strength.RecordHeaviestWeight][0].EntryID)
+	assert.Equal(t, "name:benchpress", byType[strength.RecordHeaviestWeight][0].ExerciseKey)
+
+	// most reps are kept per weight
+	assert.Len(t, byType[strength.RecordMostReps], 2)
+
+	require.Len(t, byType[strength.RecordHighestVolume], 1)
+	assert.Equal(t, 2400.0, byType[strength.RecordHighestVolume][0].Value)
+
+	require.Len(t, byType[strength.RecordEstimated1RM], 1)
+	assert.Equal(t, 110.0, byType[strength.RecordEstimated1RM][0].Value)
+
+	require.Len(t, byType[strength.RecordLongestDuration], 1)
+	assert.Equal(t, 90.0, byType[strength.RecordLongestDuration][0].Value)
+}
+
+func TestWorkoutRecordsSumsVolumePerEntry(t *testing.T) {
+	// one entry logged set by set: 10 × 60, 8 × 70, 6 × 80
+	records := strength.WorkoutRecords([]strength.Performance{
+		{EntryID: 1, ExerciseName: "Squat", Sets: 1, Reps: intPtr(10), Weight: floatPtr(60)},
+		{EntryID: 1, ExerciseName: "Squat", Sets: 1, Reps: intPtr(8), Weight: floatPtr(70)},
+		{EntryID: 1, ExerciseName: "Squat", Sets: 1, Reps: intPtr(6), Weight: floatPtr(80)},
+		{EntryID: 2, ExerciseName: "Squat", Sets: 2, Reps: intPtr(5), Weight: floatPtr(100)},
+	})
+
+	for _, record := range records {
+		if record.Type == strength.RecordHighestVolume {
+			assert.Equal(t, 1640.0, record.Value)
+			assert.Equal(t, 1, record.EntryID)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package units_test
+
+import (
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	unit, err := units.Parse(" LB ")
+	require.NoError(t, err)
+	assert.Equal(t, units.Pounds, unit)
+
+	_, err = units.Parse("stone")
+	assert.Error(t, err)
+
+	_, err = units.Parse("")
+	assert.Error(t, err)
+}
+
+func TestToKilograms(t *testing.T) {
+	tests := []struct {
+		name   string
+		weight float64
+		unit   units.Unit
+		want   float64
+	}{
+		{name: "kilograms", weight: 102.5, unit: units.Kilograms, want: 102.5},
+		{name: "pounds", weight: 225, unit: units.Pounds, want: 102.058},
+		{name: "one pound", weight: 1, unit: units.Pounds, want: 0.454},
+		{name: "past the old cap", weight: 1200, unit: units.Kilograms, want: 1200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, units.ToKilograms(tt.weight, tt.unit))
+		})
+	}
+}
+
+func TestPoundsRoundTrip(t *testing.T) {
+	for hundredths := 0; hundredths <= 200000; hundredths++ {
+		pounds := float64(hundredths) / 100
+		got := units.FromKilograms(units.ToKilograms(pounds, units.Pounds), units.Pounds)
+		if got != pounds {
+			t.Fatalf("%v lb read back as %v lb", pounds, got)
+		}
+	}
+}
+
+func TestKilogramsRoundTrip(t *testing.T) {
+	for grams := 0; grams <= 500000; grams++ {
+		kilograms := float64(grams) / 1000
+		got := units.ToKilograms(units.FromKilogramsExact(kilograms, units.Pounds), units.Pounds)
+		if got != kilograms {
+			t.Fatalf("%v kg written back as %v kg", kilograms, got)
+		}
+	}
+}
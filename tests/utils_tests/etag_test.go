@@ -0,0 +1,97 @@
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gbuenodev/goProject/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		name    string
+		etag    string
+		want    int
+		wantErr bool
+	}{
+		{name: "strong", etag: `"3"`, want: 3},
+		{name: "weak", etag: `W/"3"`, want: 3},
+		{name: "surrounding spaces", etag: ` "12" `, want: 12},
+		{name: "round trip", etag: utils.ETag(42), want: 42},
+		{name: "unquoted", etag: `3`, wantErr: true},
+		{name: "not a number", etag: `"abc"`, wantErr: true},
+		{name: "zero", etag: `"0"`, wantErr: true},
+		{name: "negative", etag: `"-1"`, wantErr: true},
+		{name: "wildcard", etag: `*`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := utils.ParseETag(tt.etag)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, version)
+		})
+	}
+}
+
+func TestMatchesETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "same", header: `"3"`, want: true},
+		{name: "weak", header: `W/"3"`, want: true},
+		{name: "listed", header: `"1", "2", "3"`, want: true},
+		{name: "wildcard", header: `*`, want: true},
+		{name: "other version", header: `"2"`},
+		{name: "none listed", header: `"1", W/"2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.MatchesETag(tt.header, utils.ETag(3)))
+		})
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifMatch  string
+		want     bool
+		wantCode int
+	}{
+		{name: "missing", wantCode: http.StatusPreconditionRequired},
+		{name: "stale", ifMatch: `"2"`, wantCode: http.StatusPreconditionFailed},
+		{name: "current", ifMatch: `"3"`, want: true},
+		{name: "wildcard", ifMatch: `*`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/workouts/1", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+
+			ok := utils.CheckIfMatch(w, r, utils.ETag(3))
+
+			assert.Equal(t, tt.want, ok)
+			if !tt.want {
+				assert.Equal(t, tt.wantCode, w.Code)
+			}
+			if tt.wantCode == http.StatusPreconditionFailed {
+				assert.Equal(t, utils.ETag(3), w.Header().Get("ETag"))
+			}
+		})
+	}
+}